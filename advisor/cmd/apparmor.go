@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"time"
+
+	log "github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/xentra-ai/advisor/pkg/k8s"
+)
+
+// Additional flags specific to AppArmor profiles
+var (
+	appArmorRecordedWithin time.Duration
+	appArmorPatchFormat    string
+	appArmorAnnotations    bool
+	appArmorComplain       bool
+)
+
+func init() {
+	apparmorCmd.Flags().BoolVarP(&allNamespaces, "all-namespaces", "A", false, "Generate profiles for all pods in all namespaces")
+	apparmorCmd.Flags().BoolVar(&allInNamespace, "all", false, "Generate profiles for all pods in the current namespace")
+	addTargetFlags(apparmorCmd)
+
+	apparmorCmd.Flags().StringVar(&outputDir, "output-dir", "apparmor-profiles", "Directory to store generated AppArmor profiles and workload patches")
+	apparmorCmd.Flags().DurationVar(&appArmorRecordedWithin, "first-recorded-within", 0, "Only use pods the broker first recorded within this window, e.g. 168h (default: all pods). The syscalls of a pod are its cumulative set, they are not filtered by time")
+	apparmorCmd.Flags().StringVar(&appArmorPatchFormat, "patch-format", string(k8s.PatchFormatStrategic), "Format of the workload patches (strategic or kustomize)")
+	apparmorCmd.Flags().BoolVar(&appArmorAnnotations, "annotations", false, "Reference the profiles with container.apparmor.security.beta.kubernetes.io annotations instead of the appArmorProfile securityContext field (Kubernetes before 1.30)")
+	apparmorCmd.Flags().BoolVar(&appArmorComplain, "complain", false, "Generate profiles in complain mode, logging violations instead of denying them")
+}
+
+var apparmorCmd = &cobra.Command{
+	Use:     "apparmor [pod-name | kind/name]",
+	Aliases: []string{"aa"},
+	Short:   "Generate AppArmor profiles",
+	Args:    cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		setupLogger()
+
+		if !cmd.Flags().Changed("output-dir") {
+			outputDir = "apparmor-profiles"
+		}
+
+		config, ok := cmd.Context().Value(k8s.ConfigKey).(*k8s.Config)
+		if !ok {
+			log.Fatal().Msg("Failed to retrieve Kubernetes configuration")
+		}
+		config.OutputDir = outputDir
+
+		namespace, _, err := kubeConfigFlags.ToRawKubeConfigLoader().Namespace()
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to get namespace")
+		}
+
+		opts := k8s.DefaultAppArmorOptions()
+		opts.OutputDir = outputDir
+		opts.FirstRecordedWithin = appArmorRecordedWithin
+		opts.Annotations = appArmorAnnotations
+		opts.Complain = appArmorComplain
+		if opts.PatchFormat, err = k8s.ParsePatchFormat(appArmorPatchFormat); err != nil {
+			log.Error().Err(err).Msg("Invalid AppArmor options")
+			_ = cmd.Usage()
+			return
+		}
+
+		options, err := buildGenerateOptions(args, namespace)
+		if err != nil {
+			log.Error().Err(err).Msg("Invalid pod targeting")
+			_ = cmd.Usage()
+			return
+		}
+
+		// Set up port forwarding
+		stopChan, errChan, done := k8s.PortForward(config)
+		<-done // Block until port-forwarding is set up
+		go func() {
+			for err := range errChan {
+				log.Fatal().Err(err).Msg("Error setting up port-forwarding")
+			}
+		}()
+		log.Debug().Msg("Port forwarding set up successfully.")
+
+		k8s.GenerateAppArmorProfile(options, config, opts)
+		close(stopChan)
+	},
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"os"
+
+	log "github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/xentra-ai/advisor/pkg/k8s"
+)
+
+// Flags of the diff commands
+var (
+	diffProfilesDir string
+)
+
+func init() {
+	diffSeccompCmd.Flags().BoolVarP(&allNamespaces, "all-namespaces", "A", false, "Compare the profiles of all pods in all namespaces")
+	diffSeccompCmd.Flags().BoolVar(&allInNamespace, "all", false, "Compare the profiles of all pods in the current namespace")
+	addTargetFlags(diffSeccompCmd)
+
+	diffSeccompCmd.Flags().StringVar(&diffProfilesDir, "profiles-dir", "seccomp-profiles", "Directory of the existing raw profiles")
+	diffSeccompCmd.Flags().StringVar(&seccompFormat, "format", string(k8s.SeccompFormatJSON), "Existing profiles to compare with (json for raw profiles in --profiles-dir, spo for SeccompProfile resources in the cluster)")
+	diffSeccompCmd.Flags().BoolVar(&podUnion, "pod-union", false, "Also compare the pod-level profile allowing the syscalls of all containers")
+	diffSeccompCmd.Flags().DurationVar(&seccompRecordedWithin, "first-recorded-within", 0, "Only use pods the broker first recorded within this window, e.g. 168h (default: all pods). The syscalls of a pod are its cumulative set, they are not filtered by time")
+	diffSeccompCmd.Flags().StringVar(&seccompBaseline, "baseline", string(k8s.SeccompBaselineNone), "Syscalls added to every profile (none or runtime-default)")
+	diffSeccompCmd.Flags().BoolVar(&argFilters, "arg-filters", false, "Restrict high-risk syscalls by their arguments, as for gen seccomp")
+
+	diffCmd.AddCommand(diffSeccompCmd)
+}
+
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Compare newly generated resources with existing ones",
+}
+
+var diffSeccompCmd = &cobra.Command{
+	Use:     "seccomp [pod-name | kind/name]",
+	Aliases: []string{"secp"},
+	Short:   "Show the syscalls a newly generated seccomp profile adds or removes",
+	Args:    cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		setupLogger()
+
+		config, ok := cmd.Context().Value(k8s.ConfigKey).(*k8s.Config)
+		if !ok {
+			log.Fatal().Msg("Failed to retrieve Kubernetes configuration")
+		}
+
+		namespace, _, err := kubeConfigFlags.ToRawKubeConfigLoader().Namespace()
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to get namespace")
+		}
+
+		profileOpts, err := seccompProfileOptions()
+		if err != nil {
+			log.Error().Err(err).Msg("Invalid seccomp options")
+			_ = cmd.Usage()
+			return
+		}
+
+		options, err := buildGenerateOptions(args, namespace)
+		if err != nil {
+			log.Error().Err(err).Msg("Invalid pod targeting")
+			_ = cmd.Usage()
+			return
+		}
+
+		// Set up port forwarding
+		stopChan, errChan, done := k8s.PortForward(config)
+		<-done // Block until port-forwarding is set up
+		go func() {
+			for err := range errChan {
+				log.Fatal().Err(err).Msg("Error setting up port-forwarding")
+			}
+		}()
+
+		// Exit like diff(1): 1 when a profile would change, 2 on errors. os.Exit skips deferred
+		// calls, so port forwarding is stopped first.
+		changed, err := k8s.DiffSeccomp(options, config, profileOpts, diffProfilesDir, os.Stdout)
+		close(stopChan)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to diff the seccomp profiles")
+			os.Exit(2)
+		}
+		if changed {
+			os.Exit(1)
+		}
+	},
+}
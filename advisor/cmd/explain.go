@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"os"
+	"time"
+
+	log "github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/xentra-ai/advisor/pkg/k8s"
+)
+
+// Flags of the explain commands
+var (
+	explainOutput         string
+	explainRecordedWithin time.Duration
+)
+
+func init() {
+	explainSeccompCmd.Flags().BoolVarP(&allNamespaces, "all-namespaces", "A", false, "Explain the syscalls of all pods in all namespaces")
+	explainSeccompCmd.Flags().BoolVar(&allInNamespace, "all", false, "Explain the syscalls of all pods in the current namespace")
+	addTargetFlags(explainSeccompCmd)
+
+	explainSeccompCmd.Flags().StringVar(&explainOutput, "output", string(k8s.ReportFormatText), "Report format (text or json)")
+	explainSeccompCmd.Flags().DurationVar(&explainRecordedWithin, "first-recorded-within", 0, "Only use pods the broker first recorded within this window, e.g. 168h (default: all pods). The syscalls of a pod are its cumulative set, they are not filtered by time")
+
+	explainCmd.AddCommand(explainSeccompCmd)
+}
+
+var explainCmd = &cobra.Command{
+	Use:   "explain",
+	Short: "Explain recorded runtime behavior for security reviews",
+}
+
+var explainSeccompCmd = &cobra.Command{
+	Use:     "seccomp [pod-name | kind/name]",
+	Aliases: []string{"secp"},
+	Short:   "Classify the observed syscalls against dangerous syscalls and RuntimeDefault",
+	Args:    cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		setupLogger()
+
+		config, ok := cmd.Context().Value(k8s.ConfigKey).(*k8s.Config)
+		if !ok {
+			log.Fatal().Msg("Failed to retrieve Kubernetes configuration")
+		}
+
+		namespace, _, err := kubeConfigFlags.ToRawKubeConfigLoader().Namespace()
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to get namespace")
+		}
+
+		format, err := k8s.ParseReportFormat(explainOutput)
+		if err != nil {
+			log.Error().Err(err).Msg("Invalid explain options")
+			_ = cmd.Usage()
+			return
+		}
+		profileOpts := k8s.DefaultProfileOptions()
+		profileOpts.FirstRecordedWithin = explainRecordedWithin
+
+		options, err := buildGenerateOptions(args, namespace)
+		if err != nil {
+			log.Error().Err(err).Msg("Invalid pod targeting")
+			_ = cmd.Usage()
+			return
+		}
+
+		// Set up port forwarding
+		stopChan, errChan, done := k8s.PortForward(config)
+		<-done // Block until port-forwarding is set up
+		go func() {
+			for err := range errChan {
+				log.Fatal().Err(err).Msg("Error setting up port-forwarding")
+			}
+		}()
+		defer close(stopChan)
+
+		if err := k8s.ExplainSeccomp(options, config, profileOpts, format, os.Stdout); err != nil {
+			log.Error().Err(err).Msg("Failed to explain the seccomp profile")
+			os.Exit(1)
+		}
+	},
+}
@@ -25,14 +25,42 @@ var (
 	policyType     string
 	dryRun         bool
 	outputDir      string
+
+	baseline                   bool
+	baselineAllowSameNamespace bool
+	baselineClusterwide        bool
+
+	adminPriority             int32
+	adminMonitoringNamespaces []string
+	adminDenyEgressCIDRs      []string
+
+	istioTrustDomain string
+
+	antreaTier     string
+	antreaPriority float64
+
+	portRanges   bool
+	portRangeGap int
+
+	dropEphemeralPorts bool
+	ephemeralPortRange string
+
+	ciliumEntities   bool
+	ciliumAllowWorld bool
 )
 
 var networkPolicyCmd = &cobra.Command{
-	Use:     "networkpolicy [pod-name]",
+	Use:     "networkpolicy [pod-name | kind/name]",
 	Aliases: []string{"netpol"},
 	Short:   "Generate Kubernetes NetworkPolicies to secure your cluster",
-	Long:    `Generate Kubernetes NetworkPolicies for pods in your Kubernetes cluster, based on network traffic collected from the controller(s).`,
-	Args:    cobra.MaximumNArgs(1),
+	Long: `Generate Kubernetes NetworkPolicies for pods in your Kubernetes cluster, based on network traffic collected from the controller(s).
+
+Pods can be targeted by name, by workload reference (deployment/api, statefulset/db, daemonset/agent),
+by label or field selector, or with --all / --all-namespaces.
+
+With --baseline a namespace-wide default-deny-all policy and an allow-DNS egress policy are
+generated for every targeted namespace, the per-workload allow policies are layered on top.`,
+	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		// Set up the logger first, so we get useful debug output
 		setupLogger()
@@ -59,6 +87,14 @@ var networkPolicyCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
+		// Validate the flags before port forwarding is set up
+		ephemeralOptions, err := ephemeralPortOptions()
+		if err != nil {
+			log.Error().Err(err).Msg("Invalid ephemeral port options")
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
 		// Set output directory in config
 		config.OutputDir = outputDir
 		log.Debug().Msgf("Using output directory: %s", outputDir)
@@ -103,15 +139,10 @@ var networkPolicyCmd = &cobra.Command{
 		config.DryRun = dryRun
 
 		// Create policy service with appropriate configuration
-		var policyServiceType network.PolicyType
-		if policyType == "cilium" {
-			policyServiceType = network.CiliumPolicy
-		} else {
-			policyServiceType = network.StandardPolicy
-		}
+		policyServiceType := parsePolicyType(policyType)
 
 		// Create the policy service
-		policyService := createPolicyService(config, policyServiceType)
+		policyService := createPolicyService(config, policyServiceType, ephemeralOptions)
 
 		// Initialize output directory
 		if err := policyService.InitOutputDirectory(); err != nil {
@@ -119,41 +150,40 @@ var networkPolicyCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		// Check for --all or --all-namespaces flags
-		if allNamespaces {
-			log.Info().Msg("Generating policies for all pods in all namespaces")
-			// Get all running pods across all namespaces
-			pods, err := k8s.GetAllPodsInAllNamespaces(ctx, config)
-			if err != nil {
-				log.Error().Err(err).Msg("Error getting pods in all namespaces")
-				os.Exit(1)
-			}
-			processPods(pods, policyService, policyServiceType)
-		} else if allInNamespace {
-			// Determine namespace (use targetNamespace which was resolved earlier)
-			log.Info().Msgf("Generating policies for all pods in namespace: %s", targetNamespace)
-			// Get all running pods in the specified namespace
-			pods, err := k8s.GetPodsInNamespace(ctx, config, targetNamespace)
-			if err != nil {
-				log.Error().Err(err).Msgf("Error getting pods in namespace %s", targetNamespace)
-				os.Exit(1)
+		// Resolve the target pods from the arguments and targeting flags
+		options, err := buildGenerateOptions(args, targetNamespace)
+		if err != nil {
+			log.Error().Err(err).Msg("Invalid pod targeting")
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if options.Mode == k8s.SinglePod {
+			if baseline {
+				generateBaseline(policyService, policyServiceType, []string{targetNamespace})
 			}
-			processPods(pods, policyService, policyServiceType)
-		} else {
-			// Check if a pod name was provided
-			if len(args) != 1 {
-				log.Error().Msg("Pod name is required when not using --all or --all-namespaces flags")
-				fmt.Fprintf(os.Stderr, "Error: pod name argument is required. Use --all to generate for all pods in a namespace.\n")
+			log.Info().Msgf("Generating policy for pod %s in namespace %s", options.PodName, targetNamespace)
+			if err := policyService.GenerateAndHandlePolicy(options.PodName, policyServiceType); err != nil {
+				log.Error().Err(err).Msgf("Error generating policy for pod %s", options.PodName)
 				os.Exit(1)
 			}
+			return
+		}
 
-			podName := args[0]
-			log.Info().Msgf("Generating policy for pod %s in namespace %s", podName, targetNamespace)
-			if err := policyService.GenerateAndHandlePolicy(podName, policyServiceType); err != nil {
-				log.Error().Err(err).Msgf("Error generating policy for pod %s", podName)
-				os.Exit(1)
-			}
+		pods, err := k8s.GetResource(options, config)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to resolve the target pods")
+			os.Exit(1)
+		}
+		if len(pods) == 0 {
+			log.Warn().Msg("No running pods matched the given target")
+			return
 		}
+		if baseline {
+			generateBaseline(policyService, policyServiceType, podNamespaces(pods))
+		}
+		log.Info().Msgf("Generating policies for %d pods", len(pods))
+		processPods(pods, policyService, policyServiceType)
 	},
 }
 
@@ -168,21 +198,173 @@ func processPods(pods []corev1.Pod, policyService *network.PolicyService, policy
 	}
 }
 
+// parsePolicyType maps the --type flag to a policy type, defaulting to standard NetworkPolicies
+func parsePolicyType(value string) network.PolicyType {
+	switch value {
+	case "cilium":
+		return network.CiliumPolicy
+	case "cilium-clusterwide":
+		return network.CiliumClusterwidePolicy
+	case "admin":
+		return network.AdminPolicy
+	case "calico":
+		return network.CalicoPolicy
+	case "istio":
+		return network.IstioPolicy
+	case "antrea":
+		return network.AntreaPolicy
+	case "antrea-clusterwide":
+		return network.AntreaClusterPolicy
+	default:
+		return network.StandardPolicy
+	}
+}
+
+// generateBaseline generates the namespace baseline policies requested by the baseline flags
+func generateBaseline(policyService *network.PolicyService, policyType network.PolicyType, namespaces []string) {
+	opts := network.DefaultBaselineOptions()
+	opts.AllowSameNamespace = baselineAllowSameNamespace
+	opts.Clusterwide = baselineClusterwide
+
+	log.Info().Msgf("Generating baseline policies for namespaces %v", namespaces)
+	if err := policyService.GenerateAndHandleBaseline(namespaces, policyType, opts); err != nil {
+		log.Error().Err(err).Msg("Error generating baseline policies")
+		os.Exit(1)
+	}
+}
+
+// podNamespaces returns the distinct namespaces of the pods in order of appearance
+func podNamespaces(pods []corev1.Pod) []string {
+	seen := make(map[string]bool)
+	var namespaces []string
+	for _, pod := range pods {
+		if !seen[pod.Namespace] {
+			seen[pod.Namespace] = true
+			namespaces = append(namespaces, pod.Namespace)
+		}
+	}
+	return namespaces
+}
+
 // createPolicyService creates and initializes a policy service
-func createPolicyService(config *k8s.Config, defaultType network.PolicyType) *network.PolicyService {
+func createPolicyService(config *k8s.Config, defaultType network.PolicyType, ephemeralOptions network.EphemeralPortOptions) *network.PolicyService {
 	// Create a config adapter to implement the ConfigProvider interface
 	configAdapter := &k8sConfigAdapter{config: config}
 
 	// Create the policy service
 	policyService := network.NewPolicyService(configAdapter, defaultType)
+	policyService.SetEphemeralPortOptions(ephemeralOptions)
 
 	// Register generators
-	policyService.RegisterGenerator(network.NewStandardPolicyGenerator())
-	policyService.RegisterGenerator(network.NewCiliumPolicyGenerator())
+	portRangeOptions := detectPortRangeOptions(config)
+	policyService.RegisterGenerator(network.NewStandardPolicyGeneratorWithOptions(network.StandardPolicyOptions{
+		PortRanges: portRangeOptions,
+		Endpoints:  &k8sEndpointResolver{config: config},
+	}))
+	ciliumOptions := ciliumPolicyOptions(config, defaultType, portRangeOptions)
+	policyService.RegisterGenerator(network.NewCiliumPolicyGeneratorWithOptions(ciliumOptions))
+	policyService.RegisterGenerator(network.NewCiliumClusterwidePolicyGeneratorWithOptions(ciliumOptions))
+	policyService.RegisterGenerator(network.NewAdminPolicyGeneratorWithOptions(adminPolicyOptions()))
+	policyService.RegisterGenerator(network.NewCalicoPolicyGenerator())
+	policyService.RegisterGenerator(network.NewIstioPolicyGeneratorWithOptions(network.IstioPolicyOptions{TrustDomain: istioTrustDomain}))
+	policyService.RegisterGenerator(network.NewAntreaPolicyGeneratorWithOptions(antreaPolicyOptions()))
+	policyService.RegisterGenerator(network.NewAntreaClusterPolicyGeneratorWithOptions(antreaPolicyOptions()))
 
 	return policyService
 }
 
+// detectPortRangeOptions enables the merging of adjacent ports into endPort ranges when requested
+// and supported by the cluster
+func detectPortRangeOptions(config *k8s.Config) network.PortRangeOptions {
+	options := network.DefaultPortRangeOptions()
+	options.GapTolerance = portRangeGap
+	if !portRanges {
+		return options
+	}
+
+	options.Enabled = k8s.SupportsEndPort(config)
+	if !options.Enabled {
+		log.Info().Msg("The cluster does not support endPort, every observed port gets its own rule")
+	}
+	return options
+}
+
+// ciliumPolicyOptions builds the Cilium policy options. ExternalName Services are only resolved
+// when generating Cilium policies, and the API server and node addresses only looked up when
+// entities are enabled too.
+func ciliumPolicyOptions(config *k8s.Config, policyType network.PolicyType, portRangeOptions network.PortRangeOptions) network.CiliumPolicyOptions {
+	options := network.CiliumPolicyOptions{
+		PortRanges: portRangeOptions,
+		AllowWorld: ciliumAllowWorld,
+		Endpoints:  &k8sEndpointResolver{config: config},
+	}
+	if policyType != network.CiliumPolicy && policyType != network.CiliumClusterwidePolicy {
+		return options
+	}
+
+	options.ExternalNames = externalNameServices(config)
+	if !ciliumEntities {
+		return options
+	}
+
+	addresses, err := k8s.GetClusterAddresses(context.Background(), config)
+	if err != nil {
+		log.Warn().Err(err).Msg("Unable to look up the API server and node addresses, they will be matched by CIDR")
+		return options
+	}
+	options.Cluster = network.ClusterInfo{
+		APIServerIPs: addresses.APIServerIPs,
+		NodeIPs:      addresses.NodeIPs,
+	}
+	return options
+}
+
+// externalNameServices resolves the cluster's ExternalName Services for the Cilium generators
+func externalNameServices(config *k8s.Config) map[string]network.ExternalNameService {
+	services, err := k8s.GetExternalNameServices(context.Background(), config)
+	if err != nil {
+		log.Warn().Err(err).Msg("Unable to look up the ExternalName services, their peers will be matched by CIDR")
+		return nil
+	}
+
+	externalNames := make(map[string]network.ExternalNameService, len(services))
+	for address, svc := range services {
+		externalNames[address] = network.ExternalNameService{Namespace: svc.Namespace, Name: svc.Name, ExternalName: svc.Spec.ExternalName}
+	}
+	return externalNames
+}
+
+// ephemeralPortOptions builds the ephemeral port filter options from the ephemeral port flags
+func ephemeralPortOptions() (network.EphemeralPortOptions, error) {
+	options := network.DefaultEphemeralPortOptions()
+	options.Enabled = dropEphemeralPorts
+
+	minPort, maxPort, err := network.ParseEphemeralPortRange(ephemeralPortRange)
+	if err != nil {
+		return options, fmt.Errorf("invalid --ephemeral-port-range: %w", err)
+	}
+	options.Min = minPort
+	options.Max = maxPort
+	return options, nil
+}
+
+// adminPolicyOptions builds the AdminNetworkPolicy options from the admin flags
+func adminPolicyOptions() network.AdminPolicyOptions {
+	options := network.DefaultAdminPolicyOptions()
+	options.Priority = adminPriority
+	options.MonitoringNamespaces = adminMonitoringNamespaces
+	options.DeniedEgressCIDRs = adminDenyEgressCIDRs
+	return options
+}
+
+// antreaPolicyOptions builds the Antrea policy options from the antrea flags
+func antreaPolicyOptions() network.AntreaPolicyOptions {
+	options := network.DefaultAntreaPolicyOptions()
+	options.Tier = antreaTier
+	options.Priority = antreaPriority
+	return options
+}
+
 // k8sConfigAdapter adapts the k8s.Config to the network.ConfigProvider interface
 type k8sConfigAdapter struct {
 	config *k8s.Config
@@ -200,18 +382,62 @@ func (a *k8sConfigAdapter) GetOutputDir() string {
 	return a.config.OutputDir
 }
 
+// k8sEndpointResolver adapts k8s.GetServiceEndpoints to the network.EndpointResolver interface
+type k8sEndpointResolver struct {
+	config *k8s.Config
+}
+
+func (r *k8sEndpointResolver) GetServiceEndpoints(namespace, name string) (*network.ServiceEndpoints, error) {
+	endpoints, err := k8s.GetServiceEndpoints(context.Background(), r.config, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &network.ServiceEndpoints{Addresses: endpoints.Addresses}
+	for _, port := range endpoints.Ports {
+		if port.Port == nil {
+			continue
+		}
+		endpointPort := network.EndpointPort{Port: *port.Port}
+		if port.Name != nil {
+			endpointPort.Name = *port.Name
+		}
+		if port.Protocol != nil {
+			endpointPort.Protocol = *port.Protocol
+		}
+		result.Ports = append(result.Ports, endpointPort)
+	}
+	return result, nil
+}
+
 func init() {
 	// Add flags
 	networkPolicyCmd.Flags().StringP("namespace", "n", "", "Namespace (defaults to current context namespace)")
 	networkPolicyCmd.Flags().BoolVarP(&allInNamespace, "all", "a", false, "Generate policies for all pods in the specified or current namespace")
 	networkPolicyCmd.Flags().BoolVarP(&allNamespaces, "all-namespaces", "A", false, "Generate policies for all pods in all namespaces")
-	networkPolicyCmd.Flags().StringVarP(&policyType, "type", "t", "kubernetes", "Type of network policy to generate (kubernetes or cilium)")
+	networkPolicyCmd.Flags().StringVarP(&policyType, "type", "t", "kubernetes", "Type of network policy to generate (kubernetes, cilium, cilium-clusterwide, admin, calico, istio, antrea or antrea-clusterwide)")
 	networkPolicyCmd.Flags().BoolVar(&dryRun, "dry-run", true, "Only generate policies and save to files without applying them to the cluster")
 	networkPolicyCmd.Flags().StringVar(&outputDir, "output-dir", "network-policies", "Directory to store generated network policies")
+	networkPolicyCmd.Flags().BoolVar(&baseline, "baseline", false, "Also generate a default-deny-all and allow-DNS baseline policy for every targeted namespace")
+	networkPolicyCmd.Flags().BoolVar(&baselineAllowSameNamespace, "baseline-allow-same-namespace", false, "Add a baseline policy allowing all traffic within each namespace (requires --baseline)")
+	networkPolicyCmd.Flags().BoolVar(&baselineClusterwide, "baseline-clusterwide", false, "Emit the Cilium baseline as CiliumClusterwideNetworkPolicies (requires --baseline and --type cilium)")
+	networkPolicyCmd.Flags().Int32Var(&adminPriority, "admin-priority", network.DefaultAdminPolicyOptions().Priority, "Priority of the per-workload AdminNetworkPolicies (--type admin)")
+	networkPolicyCmd.Flags().StringSliceVar(&adminMonitoringNamespaces, "admin-monitoring-namespace", network.DefaultAdminPolicyOptions().MonitoringNamespaces, "Namespaces always allowed to reach every pod by the admin guardrails (--type admin --baseline)")
+	networkPolicyCmd.Flags().StringSliceVar(&adminDenyEgressCIDRs, "admin-deny-egress-cidr", network.DefaultAdminPolicyOptions().DeniedEgressCIDRs, "CIDRs always denied as egress destination by the admin guardrails (--type admin --baseline)")
+	networkPolicyCmd.Flags().StringVar(&istioTrustDomain, "istio-trust-domain", network.DefaultIstioPolicyOptions().TrustDomain, "Mesh trust domain used to build the SPIFFE principals of peers (--type istio)")
+	networkPolicyCmd.Flags().BoolVar(&portRanges, "port-ranges", false, "Merge adjacent observed ports into endPort ranges when the cluster supports endPort (Kubernetes 1.25+)")
+	networkPolicyCmd.Flags().IntVar(&portRangeGap, "port-range-gap", network.DefaultPortRangeOptions().GapTolerance, "Number of unobserved ports allowed between two ports merged into a range, the ports in between are allowed too")
+	networkPolicyCmd.Flags().BoolVar(&dropEphemeralPorts, "drop-ephemeral-ports", false, "Drop flows on ephemeral client ports that are not declared as container ports, and list them in a report")
+	networkPolicyCmd.Flags().StringVar(&ephemeralPortRange, "ephemeral-port-range", fmt.Sprintf("%d-%d", network.DefaultEphemeralPortMin, network.DefaultEphemeralPortMax), "Local port range the nodes assign client ports from (net.ipv4.ip_local_port_range)")
+	networkPolicyCmd.Flags().BoolVar(&ciliumEntities, "cilium-entities", true, "Match the API server and node addresses with the kube-apiserver, host and remote-node entities (--type cilium or cilium-clusterwide)")
+	networkPolicyCmd.Flags().BoolVar(&ciliumAllowWorld, "cilium-allow-world", false, "Match public peers with the world entity instead of their /32 CIDR, allowing the observed ports to any internet address (--type cilium or cilium-clusterwide)")
+	networkPolicyCmd.Flags().StringVar(&antreaTier, "antrea-tier", network.DefaultAntreaPolicyOptions().Tier, "Tier of the per-workload Antrea policies (--type antrea or antrea-clusterwide)")
+	networkPolicyCmd.Flags().Float64Var(&antreaPriority, "antrea-priority", network.DefaultAntreaPolicyOptions().Priority, "Priority of the Antrea policies within their tier, lower values take precedence (--type antrea or antrea-clusterwide)")
+	addTargetFlags(networkPolicyCmd)
 
 	// Add completion for the policy type flag
 	networkPolicyCmd.RegisterFlagCompletionFunc("type", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		return []string{"kubernetes", "cilium"}, cobra.ShellCompDirectiveNoFileComp
+		return []string{"kubernetes", "cilium", "cilium-clusterwide", "admin", "calico", "istio", "antrea", "antrea-clusterwide"}, cobra.ShellCompDirectiveNoFileComp
 	})
 }
 
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xentra-ai/advisor/pkg/k8s"
+	"github.com/xentra-ai/advisor/pkg/network"
+)
+
+// parseNetworkPolicyFlags parses the arguments into the networkpolicy flags and restores the ephemeral
+// port flags after the test
+func parseNetworkPolicyFlags(t *testing.T, args ...string) {
+	origDrop, origRange := dropEphemeralPorts, ephemeralPortRange
+	t.Cleanup(func() {
+		dropEphemeralPorts, ephemeralPortRange = origDrop, origRange
+		for _, name := range []string{"drop-ephemeral-ports", "ephemeral-port-range"} {
+			networkPolicyCmd.Flags().Lookup(name).Changed = false
+		}
+	})
+	require.NoError(t, networkPolicyCmd.Flags().Parse(args))
+}
+
+func TestEphemeralPortFlags(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		parseNetworkPolicyFlags(t)
+		options, err := ephemeralPortOptions()
+		require.NoError(t, err)
+		assert.Equal(t, network.DefaultEphemeralPortOptions(), options)
+		assert.False(t, options.Enabled, "dropping observed flows is opt-in")
+	})
+
+	t.Run("enabled with a custom range", func(t *testing.T) {
+		parseNetworkPolicyFlags(t, "--drop-ephemeral-ports", "--ephemeral-port-range", "49152-65535")
+		options, err := ephemeralPortOptions()
+		require.NoError(t, err)
+
+		policyService := createPolicyService(&k8s.Config{DryRun: true}, network.StandardPolicy, options)
+		assert.Equal(t, network.EphemeralPortOptions{Enabled: true, Min: 49152, Max: 65535}, policyService.EphemeralPortOptions())
+	})
+
+	t.Run("invalid range", func(t *testing.T) {
+		parseNetworkPolicyFlags(t, "--ephemeral-port-range", "60999-32768")
+		_, err := ephemeralPortOptions()
+		assert.ErrorContains(t, err, "--ephemeral-port-range")
+	})
+}
+
+func TestPortRangeFlags(t *testing.T) {
+	// Ranges also allow the unobserved ports in between, so they are opt-in
+	parseNetworkPolicyFlags(t)
+	assert.False(t, detectPortRangeOptions(&k8s.Config{}).Enabled)
+}
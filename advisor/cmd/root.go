@@ -25,6 +25,7 @@ func init() {
 	// Add your sub-commands
 	genCmd.AddCommand(networkPolicyCmd)
 	genCmd.AddCommand(seccompCmd)
+	genCmd.AddCommand(apparmorCmd)
 
 	// Initialize kubeConfigFlags
 	kubeConfigFlags = genericclioptions.NewConfigFlags(true)
@@ -40,8 +41,8 @@ func init() {
 
 	// Add PersistentPreRun for handling Kubernetes setup
 	rootCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
-		// Skip version command to avoid unnecessary Kubernetes setup
-		if cmd.Name() == "version" {
+		// Skip version and validate commands to avoid unnecessary Kubernetes setup
+		if cmd.Name() == "version" || cmd.Parent() == validateCmd {
 			return
 		}
 
@@ -75,6 +76,9 @@ func init() {
 	}
 
 	rootCmd.AddCommand(genCmd)
+	rootCmd.AddCommand(explainCmd)
+	rootCmd.AddCommand(diffCmd)
+	rootCmd.AddCommand(validateCmd)
 
 	// Set up colored output with consistent RFC3339 timestamp format
 	consoleWriter := zerolog.ConsoleWriter{
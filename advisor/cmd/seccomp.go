@@ -1,6 +1,9 @@
 package cmd
 
 import (
+	"fmt"
+	"time"
+
 	log "github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 	"github.com/xentra-ai/advisor/pkg/k8s"
@@ -8,21 +11,49 @@ import (
 
 // Additional flags specific to seccomp profiles
 var (
-	defaultAction string
+	defaultAction         string
+	podUnion              bool
+	seccompFormat         string
+	patchWorkloads        bool
+	patchFormat           string
+	profileDir            string
+	profilesInstalled     bool
+	seccompRecordedWithin time.Duration
+	seccompBaseline       string
+	seccompReport         bool
+	argFilters            bool
+	stageProfiles         bool
+	promoteProfiles       bool
+	promoteAfter          int
 )
 
 func init() {
 	// Add existing flags
 	seccompCmd.Flags().BoolVarP(&allNamespaces, "all-namespaces", "A", false, "Generate profiles for all pods in all namespaces")
 	seccompCmd.Flags().BoolVar(&allInNamespace, "all", false, "Generate profiles for all pods in the current namespace")
+	addTargetFlags(seccompCmd)
 
 	// Add seccomp-specific flags
 	seccompCmd.Flags().StringVar(&outputDir, "output-dir", "seccomp-profiles", "Directory to store generated seccomp profiles")
 	seccompCmd.Flags().StringVar(&defaultAction, "default-action", "SCMP_ACT_ERRNO", "Default action for seccomp profile (SCMP_ACT_ERRNO|SCMP_ACT_KILL|SCMP_ACT_LOG)")
+	seccompCmd.Flags().BoolVar(&podUnion, "pod-union", false, "Also write a pod-level profile allowing the syscalls of all containers")
+	seccompCmd.Flags().StringVar(&seccompFormat, "format", string(k8s.SeccompFormatJSON), "Output format (json for raw profiles, spo for Security Profiles Operator SeccompProfile resources)")
+	seccompCmd.Flags().BoolVar(&dryRun, "dry-run", true, "Only save SeccompProfile resources and workload patches to files without applying them to the cluster")
+	seccompCmd.Flags().BoolVar(&patchWorkloads, "patch-workloads", false, "Write patches setting the generated localhost profiles on the pods' owning workloads")
+	seccompCmd.Flags().StringVar(&patchFormat, "patch-format", string(k8s.PatchFormatStrategic), "Format of the workload patches (strategic or kustomize)")
+	seccompCmd.Flags().BoolVar(&profilesInstalled, "profiles-installed", false, "With --format json, confirm the raw profiles are installed on the nodes so --dry-run=false applies the workload patches")
+	seccompCmd.Flags().StringVar(&profileDir, "profile-dir", "", "Directory below the kubelet seccomp root the raw profiles are installed in, used in the patches' localhostProfile")
+	seccompCmd.Flags().DurationVar(&seccompRecordedWithin, "first-recorded-within", 0, "Only use pods the broker first recorded within this window, e.g. 168h (default: all pods). The syscalls of a pod are its cumulative set, they are not filtered by time")
+	seccompCmd.Flags().StringVar(&seccompBaseline, "baseline", string(k8s.SeccompBaselineNone), "Syscalls added to every profile (none, or runtime-default for the syscalls containerd's RuntimeDefault profile allows)")
+	seccompCmd.Flags().BoolVar(&seccompReport, "report", false, "Also write a report classifying the observed syscalls against dangerous syscalls and RuntimeDefault")
+	seccompCmd.Flags().BoolVar(&argFilters, "arg-filters", false, "Restrict clone, clone3, personality, socket and ioctl by their arguments with conservative built-in filters")
+	seccompCmd.Flags().BoolVar(&stageProfiles, "stage", false, "Write complete profiles logging unlisted syscalls (SCMP_ACT_LOG) and track them in the rollout state file")
+	seccompCmd.Flags().BoolVar(&promoteProfiles, "promote", false, "Enforce staged profiles (SCMP_ACT_ERRNO) once no new syscalls were observed for --promote-after days")
+	seccompCmd.Flags().IntVar(&promoteAfter, "promote-after", 7, "Days without new syscalls before --promote enforces a staged profile")
 }
 
 var seccompCmd = &cobra.Command{
-	Use:     "seccomp [pod-name]",
+	Use:     "seccomp [pod-name | kind/name]",
 	Aliases: []string{"secp"},
 	Short:   "Generate seccomp profile",
 	Args:    cobra.MaximumNArgs(1),
@@ -41,8 +72,9 @@ var seccompCmd = &cobra.Command{
 			log.Fatal().Msg("Failed to retrieve Kubernetes configuration")
 		}
 
-		// Set output directory in config
+		// Set output directory and dry run mode in config
 		config.OutputDir = outputDir
+		config.DryRun = dryRun
 		log.Debug().Msgf("Using output directory: %s", outputDir)
 
 		// Get the namespace from kubeConfigFlags
@@ -51,22 +83,18 @@ var seccompCmd = &cobra.Command{
 			log.Fatal().Err(err).Msg("Failed to get namespace")
 		}
 
-		options := k8s.GenerateOptions{}
-
-		if allNamespaces {
-			options.Mode = k8s.AllPodsInAllNamespaces
-		} else if allInNamespace {
-			options.Mode = k8s.AllPodsInNamespace
-			options.Namespace = namespace
-		} else {
-			// Validate that a pod name is provided
-			if len(args) != 1 {
-				_ = cmd.Usage()
-				return
-			}
-			options.Mode = k8s.SinglePod
-			options.PodName = args[0]
-			options.Namespace = namespace
+		profileOpts, err := seccompProfileOptions()
+		if err != nil {
+			log.Error().Err(err).Msg("Invalid seccomp options")
+			_ = cmd.Usage()
+			return
+		}
+
+		options, err := buildGenerateOptions(args, namespace)
+		if err != nil {
+			log.Error().Err(err).Msg("Invalid pod targeting")
+			_ = cmd.Usage()
+			return
 		}
 
 		// Set up port forwarding
@@ -80,7 +108,53 @@ var seccompCmd = &cobra.Command{
 		log.Debug().Msg("Port forwarding set up successfully.")
 
 		// Generate seccomp profiles
-		k8s.GenerateSeccompProfile(options, config)
+		k8s.GenerateSeccompProfile(options, config, profileOpts)
 		close(stopChan)
 	},
 }
+
+// seccompProfileOptions builds the profile options from the seccomp flags
+func seccompProfileOptions() (k8s.ProfileOptions, error) {
+	profileOpts := k8s.DefaultProfileOptions()
+	profileOpts.OutputDir = outputDir
+	profileOpts.DefaultAction = defaultAction
+	profileOpts.PodUnion = podUnion
+
+	format, err := k8s.ParseSeccompFormat(seccompFormat)
+	if err != nil {
+		return profileOpts, err
+	}
+	profileOpts.Format = format
+
+	profileOpts.PatchWorkloads = patchWorkloads
+	profileOpts.ProfileDir = profileDir
+	profileOpts.ProfilesInstalled = profilesInstalled
+	profilePatchFormat, err := k8s.ParsePatchFormat(patchFormat)
+	if err != nil {
+		return profileOpts, err
+	}
+	profileOpts.PatchFormat = profilePatchFormat
+
+	profileOpts.FirstRecordedWithin = seccompRecordedWithin
+	baseline, err := k8s.ParseSeccompBaseline(seccompBaseline)
+	if err != nil {
+		return profileOpts, err
+	}
+	profileOpts.Baseline = baseline
+	profileOpts.Report = seccompReport
+	profileOpts.ArgFilters = argFilters
+
+	switch {
+	case stageProfiles && promoteProfiles:
+		return profileOpts, fmt.Errorf("%w: --stage and --promote cannot be combined", k8s.ErrInvalidInput)
+	case stageProfiles:
+		profileOpts.Rollout = k8s.RolloutStage
+	case promoteProfiles:
+		profileOpts.Rollout = k8s.RolloutPromote
+	}
+	if promoteAfter < 0 {
+		return profileOpts, fmt.Errorf("%w: --promote-after must not be negative", k8s.ErrInvalidInput)
+	}
+	profileOpts.PromoteAfter = time.Duration(promoteAfter) * 24 * time.Hour
+	return profileOpts, nil
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/xentra-ai/advisor/pkg/k8s"
+)
+
+// Pod targeting flags shared by the gen commands
+var (
+	labelSelector     string
+	fieldSelector     string
+	excludeNamespaces []string
+	includeInactive   bool
+)
+
+// addTargetFlags registers the pod targeting flags on a gen command
+func addTargetFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&labelSelector, "selector", "l", "", "Label selector to filter target pods (e.g. app=api,tier!=cache)")
+	cmd.Flags().StringVar(&fieldSelector, "field-selector", "", "Field selector to filter target pods (e.g. spec.nodeName=node-1)")
+	cmd.Flags().StringSliceVar(&excludeNamespaces, "exclude-namespace", nil, "Namespace glob patterns to skip (e.g. kube-system,platform-*), can be repeated")
+	cmd.Flags().BoolVar(&includeInactive, "include-inactive", false, "Also target pods recorded by the broker that are no longer running (completed Jobs, crashed or scaled-down pods)")
+}
+
+// buildGenerateOptions resolves the targeting flags and the optional positional argument
+// (a pod name or a workload reference such as deployment/api) into GenerateOptions
+func buildGenerateOptions(args []string, namespace string) (k8s.GenerateOptions, error) {
+	options := k8s.GenerateOptions{
+		Namespace:         namespace,
+		ExcludeNamespaces: excludeNamespaces,
+		IncludeInactive:   includeInactive,
+	}
+
+	// Selectors and workload references scan all namespaces when -A is set
+	if allNamespaces {
+		options.Namespace = ""
+	}
+
+	switch {
+	case len(args) == 1 && strings.Contains(args[0], "/"):
+		workload, err := k8s.ParseWorkloadRef(args[0])
+		if err != nil {
+			return options, err
+		}
+		if allNamespaces {
+			return options, fmt.Errorf("workload reference %s cannot be combined with --all-namespaces", args[0])
+		}
+		options.Mode = k8s.PodsForWorkload
+		options.Namespace = namespace
+		options.Workload = workload
+	case len(args) == 1:
+		if labelSelector != "" || fieldSelector != "" || allNamespaces || allInNamespace {
+			return options, fmt.Errorf("pod name %s cannot be combined with selectors, --all or --all-namespaces", args[0])
+		}
+		options.Mode = k8s.SinglePod
+		options.PodName = args[0]
+	case labelSelector != "" || fieldSelector != "":
+		options.Mode = k8s.PodsBySelector
+		options.LabelSelector = labelSelector
+		options.FieldSelector = fieldSelector
+	case allNamespaces:
+		options.Mode = k8s.AllPodsInAllNamespaces
+	case allInNamespace:
+		options.Mode = k8s.AllPodsInNamespace
+	default:
+		return options, fmt.Errorf("a pod name or workload reference (e.g. deployment/api) is required unless --all, --all-namespaces or a selector is used")
+	}
+
+	return options, nil
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"os"
+
+	log "github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/xentra-ai/advisor/pkg/k8s"
+)
+
+// Flags of the validate commands
+var fixProfile bool
+
+func init() {
+	validateSeccompCmd.Flags().BoolVar(&fixProfile, "fix", false, "Remove duplicate syscall names from the profile file")
+
+	validateCmd.AddCommand(validateSeccompCmd)
+}
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate security profiles without a cluster",
+}
+
+var validateSeccompCmd = &cobra.Command{
+	Use:     "seccomp <file>",
+	Aliases: []string{"secp"},
+	Short:   "Validate a seccomp profile or SeccompProfile resource against the embedded syscall tables",
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		setupLogger()
+
+		valid, err := k8s.ValidateSeccompFile(args[0], fixProfile, os.Stdout)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to validate the seccomp profile")
+		}
+		if !valid {
+			os.Exit(1)
+		}
+	},
+}
@@ -13,6 +13,7 @@ require (
 	k8s.io/apimachinery v0.33.3
 	k8s.io/cli-runtime v0.33.3
 	k8s.io/client-go v0.33.3
+	sigs.k8s.io/network-policy-api v0.1.7
 	sigs.k8s.io/yaml v1.5.0
 )
 
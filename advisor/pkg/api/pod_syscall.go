@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
@@ -14,16 +15,38 @@ import (
 type PodSysCall struct {
 	Syscalls []string `json:"syscalls"`
 	Arch     string   `json:"arch"`
+	// Containers holds the syscalls observed per container, keyed by container name
+	Containers map[string][]string `json:"containers,omitempty"`
 }
 
 type PodSysCallResponse struct {
-	PodName      string `json:"pod_name"`
-	PodNamespace string `json:"pod_namespace"`
-	Syscalls     string `json:"syscalls"`
-	Arch         string `json:"arch"`
+	PodName       string `json:"pod_name"`
+	PodNamespace  string `json:"pod_namespace"`
+	ContainerName string `json:"container_name"`
+	Syscalls      string `json:"syscalls"`
+	Arch          string `json:"arch"`
+	// TimeStamp is when the broker first recorded the container, in UTC without a zone. Later
+	// updates of the syscalls keep it.
+	TimeStamp string `json:"time_stamp"`
 }
 
-func GetPodSysCall(podName string) (PodSysCall, error) {
+// brokerTimeLayout is the layout of the broker's naive UTC timestamps
+const brokerTimeLayout = "2006-01-02T15:04:05.999999999"
+
+// Function variables for easier mocking in tests
+var (
+	GetPodSysCallFunc = getRealPodSysCall
+)
+
+// GetPodSysCall gets the syscalls observed for a pod, in total and per container. When since is
+// set, pods the broker first recorded before it are skipped. The broker keeps one row per container
+// holding its cumulative syscalls and only sets the timestamp when the row is created, so the
+// syscalls of a pod cannot be narrowed to a time window.
+func GetPodSysCall(podName string, since time.Time) (PodSysCall, error) {
+	return GetPodSysCallFunc(podName, since)
+}
+
+func getRealPodSysCall(podName string, since time.Time) (PodSysCall, error) {
 	time.Sleep(3 * time.Second)
 	apiURL := "http://127.0.0.1:9090/pod/syscalls/" + podName
 
@@ -50,14 +73,73 @@ func GetPodSysCall(podName string) (PodSysCall, error) {
 		return PodSysCall{}, err
 	}
 
+	podSysCallsResponse = firstRecordedSince(podSysCallsResponse, since)
 	if len(podSysCallsResponse) == 0 {
 		return PodSysCall{}, fmt.Errorf("GetPodSysCall: No pod syscall found in database")
 	}
 
-	var podSysCalls PodSysCall
+	return podSysCallFromResponses(podSysCallsResponse), nil
+}
+
+// firstRecordedSince drops the records of pods first recorded before since. Records without a
+// readable timestamp are kept, so older brokers still work.
+func firstRecordedSince(responses []PodSysCallResponse, since time.Time) []PodSysCallResponse {
+	if since.IsZero() {
+		return responses
+	}
 
-	podSysCalls.Syscalls = strings.Split(podSysCallsResponse[0].Syscalls, ",")
-	podSysCalls.Arch = podSysCallsResponse[0].Arch
+	var recent []PodSysCallResponse
+	for _, response := range responses {
+		timeStamp, err := time.Parse(brokerTimeLayout, response.TimeStamp)
+		if err != nil {
+			log.Debug().Err(err).Msgf("GetPodSysCall: Keeping record of pod %s without a valid timestamp", response.PodName)
+			recent = append(recent, response)
+			continue
+		}
+		if !timeStamp.Before(since) {
+			recent = append(recent, response)
+		}
+	}
+	return recent
+}
 
-	return podSysCalls, nil
+// podSysCallFromResponses combines the broker records of a pod. Records that name a container are
+// also collected per container, records without one only count towards the pod.
+func podSysCallFromResponses(responses []PodSysCallResponse) PodSysCall {
+	podSysCalls := PodSysCall{Arch: responses[0].Arch}
+
+	for _, response := range responses {
+		syscalls := splitSyscalls(response.Syscalls)
+		podSysCalls.Syscalls = appendSyscalls(podSysCalls.Syscalls, syscalls)
+		if response.ContainerName == "" {
+			continue
+		}
+		if podSysCalls.Containers == nil {
+			podSysCalls.Containers = make(map[string][]string)
+		}
+		podSysCalls.Containers[response.ContainerName] = appendSyscalls(podSysCalls.Containers[response.ContainerName], syscalls)
+	}
+
+	return podSysCalls
+}
+
+// splitSyscalls splits the comma separated syscall list of a broker record
+func splitSyscalls(syscalls string) []string {
+	var names []string
+	for _, name := range strings.Split(syscalls, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// appendSyscalls appends the syscalls that are not in the list yet
+func appendSyscalls(syscalls []string, newSyscalls []string) []string {
+	for _, syscall := range newSyscalls {
+		if !slices.Contains(syscalls, syscall) {
+			syscalls = append(syscalls, syscall)
+		}
+	}
+	return syscalls
 }
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// brokerSysCallRows are pod_syscalls rows as the broker returns them: one row per container holding
+// its cumulative syscalls, with the time_stamp of the first insert kept by later updates. Rows
+// written before the broker recorded containers have an empty container name.
+const brokerSysCallRows = `[
+	{"pod_name": "api-7d9f-old", "pod_namespace": "shop", "container_name": "app", "syscalls": "read,write,openat,futex", "arch": "x86_64", "time_stamp": "2026-10-01T08:00:00.123456"},
+	{"pod_name": "api-7d9f-old", "pod_namespace": "shop", "container_name": "envoy", "syscalls": "read,write,epoll_wait", "arch": "x86_64", "time_stamp": "2026-10-01T08:00:00.123456"},
+	{"pod_name": "api-7d9f-new", "pod_namespace": "shop", "container_name": "app", "syscalls": "read,write,futex", "arch": "x86_64", "time_stamp": "2026-10-16T09:30:00"},
+	{"pod_name": "api-legacy", "pod_namespace": "shop", "container_name": "", "syscalls": "read", "arch": "x86_64", "time_stamp": ""}
+]`
+
+func TestFirstRecordedSince(t *testing.T) {
+	var rows []PodSysCallResponse
+	require.NoError(t, json.Unmarshal([]byte(brokerSysCallRows), &rows))
+
+	assert.Len(t, firstRecordedSince(rows, time.Time{}), 4, "a zero time keeps all rows")
+
+	// A pod first recorded before the window is dropped, even if the controller kept updating its
+	// syscalls since. Rows without a timestamp are kept.
+	since := time.Date(2026, 10, 11, 0, 0, 0, 0, time.UTC)
+	recent := firstRecordedSince(rows, since)
+	require.Len(t, recent, 2)
+	assert.Equal(t, "api-7d9f-new", recent[0].PodName)
+	assert.Equal(t, "api-legacy", recent[1].PodName)
+
+	assert.Empty(t, firstRecordedSince(rows[:2], since))
+}
+
+func TestPodSysCallFromResponses(t *testing.T) {
+	var rows []PodSysCallResponse
+	require.NoError(t, json.Unmarshal([]byte(brokerSysCallRows), &rows))
+
+	podSysCall := podSysCallFromResponses(rows[:2])
+	assert.Equal(t, "x86_64", podSysCall.Arch)
+	assert.Equal(t, []string{"read", "write", "openat", "futex", "epoll_wait"}, podSysCall.Syscalls)
+	assert.Equal(t, map[string][]string{
+		"app":   {"read", "write", "openat", "futex"},
+		"envoy": {"read", "write", "epoll_wait"},
+	}, podSysCall.Containers)
+
+	// Rows without a container only count towards the pod
+	legacy := podSysCallFromResponses(rows[3:])
+	assert.Equal(t, []string{"read"}, legacy.Syscalls)
+	assert.Nil(t, legacy.Containers)
+}
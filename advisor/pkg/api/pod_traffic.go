@@ -50,6 +50,7 @@ var (
 	GetPodTrafficFunc = getRealPodTraffic
 	GetPodSpecFunc    = getRealPodSpec
 	GetSvcSpecFunc    = getRealSvcSpec
+	GetPodDetailsFunc = getRealPodDetails
 )
 
 // GetPodTraffic gets pod traffic information
@@ -67,6 +68,11 @@ func GetSvcSpec(svcIP string) (*SvcDetail, error) {
 	return GetSvcSpecFunc(svcIP)
 }
 
+// GetPodDetails gets every pod recorded by the broker, including pods that no longer run
+func GetPodDetails() ([]PodDetail, error) {
+	return GetPodDetailsFunc()
+}
+
 // Real implementations
 func getRealPodTraffic(podName string) ([]PodTraffic, error) {
 	time.Sleep(3 * time.Second)
@@ -169,3 +175,34 @@ func getRealSvcSpec(svcIp string) (*SvcDetail, error) {
 
 	return &details, nil
 }
+
+func getRealPodDetails() ([]PodDetail, error) {
+	// Specify the URL of the REST API endpoint you want to invoke.
+	apiURL := "http://127.0.0.1:9090/pod/info"
+
+	// Send an HTTP GET request to the API endpoint.
+	resp, err := http.Get(apiURL)
+	if err != nil {
+		log.Error().Err(err).Msg("GetPodDetails: Error making GET request")
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// The broker answers 404 when it has not recorded any pods yet
+	if resp.StatusCode == http.StatusNotFound {
+		return []PodDetail{}, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GetPodDetails: received non-OK HTTP status code: %v", resp.StatusCode)
+	}
+
+	var details []PodDetail
+
+	// Parse the JSON response and unmarshal it into the Go struct.
+	if err := json.NewDecoder(resp.Body).Decode(&details); err != nil {
+		log.Error().Err(err).Msg("GetPodDetails: Error decoding JSON")
+		return nil, err
+	}
+
+	return details, nil
+}
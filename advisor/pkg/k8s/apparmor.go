@@ -0,0 +1,470 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/rs/zerolog/log"
+	"github.com/xentra-ai/advisor/pkg/api"
+	"github.com/xentra-ai/advisor/pkg/common"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// appArmorAnnotationPrefix is the deprecated per-container AppArmor annotation, used by clusters
+// before Kubernetes 1.30 introduced the appArmorProfile securityContext field
+const appArmorAnnotationPrefix = "container.apparmor.security.beta.kubernetes.io/"
+
+// privilegedPortLimit is the lowest port that can be bound without CAP_NET_BIND_SERVICE
+const privilegedPortLimit = 1024
+
+// syscallCapabilities maps syscalls to the capabilities they may need. Syscalls that only need a
+// capability in some cases, such as kill for processes of other users, are included so the
+// profile does not break those cases.
+var syscallCapabilities = map[string][]string{
+	"chown": {"chown"}, "fchown": {"chown"}, "lchown": {"chown"}, "fchownat": {"chown"},
+	"chown32": {"chown"}, "fchown32": {"chown"}, "lchown32": {"chown"},
+	"setuid": {"setuid"}, "setreuid": {"setuid"}, "setresuid": {"setuid"}, "setfsuid": {"setuid"},
+	"setuid32": {"setuid"}, "setreuid32": {"setuid"}, "setresuid32": {"setuid"}, "setfsuid32": {"setuid"},
+	"setgid": {"setgid"}, "setregid": {"setgid"}, "setresgid": {"setgid"}, "setfsgid": {"setgid"}, "setgroups": {"setgid"},
+	"setgid32": {"setgid"}, "setregid32": {"setgid"}, "setresgid32": {"setgid"}, "setfsgid32": {"setgid"}, "setgroups32": {"setgid"},
+	"capset":             {"setpcap"},
+	"kill":               {"kill"},
+	"tkill":              {"kill"},
+	"tgkill":             {"kill"},
+	"rt_sigqueueinfo":    {"kill"},
+	"chroot":             {"sys_chroot"},
+	"mknod":              {"mknod"},
+	"mknodat":            {"mknod"},
+	"setpriority":        {"sys_nice"},
+	"sched_setscheduler": {"sys_nice"},
+	"sched_setattr":      {"sys_nice"},
+	"mount":              {"sys_admin"},
+	"umount2":            {"sys_admin"},
+	"pivot_root":         {"sys_admin"},
+	"fsmount":            {"sys_admin"},
+	"move_mount":         {"sys_admin"},
+	"setns":              {"sys_admin"},
+	"unshare":            {"sys_admin"},
+	"sethostname":        {"sys_admin"},
+	"setdomainname":      {"sys_admin"},
+	"ptrace":             {"sys_ptrace"},
+	"process_vm_readv":   {"sys_ptrace"},
+	"process_vm_writev":  {"sys_ptrace"},
+	"settimeofday":       {"sys_time"},
+	"clock_settime":      {"sys_time"},
+	"adjtimex":           {"sys_time"},
+	"reboot":             {"sys_boot"},
+	"init_module":        {"sys_module"},
+	"finit_module":       {"sys_module"},
+	"delete_module":      {"sys_module"},
+	"iopl":               {"sys_rawio"},
+	"ioperm":             {"sys_rawio"},
+	"acct":               {"sys_pacct"},
+	"mlock":              {"ipc_lock"},
+	"mlock2":             {"ipc_lock"},
+	"mlockall":           {"ipc_lock"},
+	"syslog":             {"syslog"},
+}
+
+// fileCapabilities are always allowed, as file permission checks needing them cannot be told
+// apart from other file accesses by their syscalls
+var fileCapabilities = []string{"dac_override", "dac_read_search", "fowner", "fsetid"}
+
+// Syscalls enabling the mount, ptrace and unix socket rules
+var (
+	mountSyscalls  = []string{"mount", "umount2", "pivot_root", "fsmount", "move_mount"}
+	ptraceSyscalls = []string{"ptrace", "process_vm_readv", "process_vm_writev"}
+	socketSyscalls = []string{"socket", "socketpair", "socketcall"}
+)
+
+// protocolSocketTypes maps traffic protocols to the socket types carrying them
+var protocolSocketTypes = map[corev1.Protocol][]string{
+	corev1.ProtocolTCP:  {"stream"},
+	corev1.ProtocolUDP:  {"dgram"},
+	corev1.ProtocolSCTP: {"seqpacket", "stream"},
+}
+
+// appArmorFileRules are the file rules of every profile, modelled on the docker-default profile:
+// files are accessible apart from writes to kernel settings below /proc and /sys
+var appArmorFileRules = []string{
+	"file,",
+	"deny @{PROC}/* w,",
+	"deny @{PROC}/{[^1-9],[^1-9][^0-9],[^1-9s][^0-9y][^0-9s],[^1-9][^0-9][^0-9][^0-9/]*}/** w,",
+	"deny @{PROC}/sys/[^k]** w,",
+	"deny @{PROC}/sys/kernel/{?,??,[^s][^h][^m]**} w,",
+	"deny @{PROC}/sysrq-trigger rwklx,",
+	"deny @{PROC}/kcore rwklx,",
+	"deny /sys/[^f]*/** wklx,",
+	"deny /sys/f[^s]*/** wklx,",
+	"deny /sys/fs/[^c]*/** wklx,",
+	"deny /sys/fs/c[^g]*/** wklx,",
+	"deny /sys/fs/cg[^r]*/** wklx,",
+	"deny /sys/firmware/** rwklx,",
+	"deny /sys/kernel/security/** rwklx,",
+}
+
+// AppArmorOptions contains configuration for AppArmor profile generation
+type AppArmorOptions struct {
+	OutputDir string
+	// FirstRecordedWithin only uses the pods the broker first recorded within this window, zero uses
+	// all pods
+	FirstRecordedWithin time.Duration
+	// PatchFormat selects strategic-merge or kustomize patch files
+	PatchFormat PatchFormat
+	// Annotations references the profiles with the annotations of clusters before Kubernetes 1.30
+	Annotations bool
+	// Complain only logs violations instead of denying them
+	Complain bool
+}
+
+// DefaultAppArmorOptions returns the AppArmor options used when no flags are set
+func DefaultAppArmorOptions() AppArmorOptions {
+	return AppArmorOptions{
+		OutputDir:   "apparmor-profiles",
+		PatchFormat: PatchFormatStrategic,
+	}
+}
+
+// AppArmorProfile is an AppArmor profile derived from the recorded behavior of a container
+type AppArmorProfile struct {
+	Name     string
+	Complain bool
+	// Capabilities are the capabilities the observed syscalls may need
+	Capabilities []string
+	// Network lists the allowed address families and socket types, e.g. "inet stream"
+	Network []string
+	// Mount allows mounting, for containers that were observed mounting
+	Mount bool
+	// Ptrace allows tracing processes of the same profile
+	Ptrace bool
+}
+
+// containerAppArmorProfile is a generated profile for a container, or for all containers of the
+// pod when the container is empty
+type containerAppArmorProfile struct {
+	Container string
+	Profile   AppArmorProfile
+}
+
+// GenerateAppArmorProfile writes an AppArmor profile for every container of the targeted workloads
+// and a patch referencing the profiles for every workload. Profiles must be loaded on the nodes
+// before the patches are applied, so neither is applied to the cluster.
+func GenerateAppArmorProfile(options GenerateOptions, config *Config, opts AppArmorOptions) {
+	pods, err := GetResource(options, config)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to resolve the target pods")
+	}
+
+	if err := os.MkdirAll(opts.OutputDir, 0755); err != nil {
+		log.Fatal().Err(err).Msgf("failed to create output directory")
+	}
+
+	for _, subject := range collectSeccompSubjects(context.TODO(), config, pods, opts.FirstRecordedWithin) {
+		profiles := buildAppArmorProfiles(subject, appArmorTraffic(subject), opts)
+		for _, containerProfile := range profiles {
+			filename, err := writeAppArmorProfile(opts.OutputDir, subject, containerProfile)
+			if err != nil {
+				log.Error().Err(err).Msgf("Failed to write the AppArmor profile of %s", seccompSubjectContainer(subject, containerProfile.Container))
+				continue
+			}
+			log.Info().Msgf("Generated AppArmor profile for %s: %s", seccompSubjectContainer(subject, containerProfile.Container), filename)
+		}
+
+		if err := handleAppArmorPatch(subject, profiles, opts); err != nil {
+			log.Error().Err(err).Msgf("Failed to write the AppArmor patch of %s/%s", subject.Namespace, subject.Name)
+		}
+	}
+}
+
+// appArmorTraffic fetches the traffic recorded for the pods of the subject
+func appArmorTraffic(subject seccompSubject) []api.PodTraffic {
+	var traffic []api.PodTraffic
+	for _, podName := range subject.PodNames {
+		podTraffic, err := api.GetPodTraffic(podName)
+		if err != nil {
+			log.Debug().Err(err).Msgf("Error retrieving %s pod traffic", podName)
+			continue
+		}
+		traffic = append(traffic, podTraffic...)
+	}
+	return traffic
+}
+
+// buildAppArmorProfiles creates a profile per container with recorded syscalls, or a profile for
+// all containers when the broker did not record containers. Containers share the pod's network
+// namespace, so the network rules derived from the pod's traffic apply to every container.
+func buildAppArmorProfiles(subject seccompSubject, traffic []api.PodTraffic, opts AppArmorOptions) []containerAppArmorProfile {
+	var podSyscalls []string
+	containerSyscalls := make(map[string][]string)
+	for _, observation := range subject.Observations {
+		podSyscalls = MergeSyscalls(podSyscalls, observation.Syscalls)
+		for container, syscalls := range observation.Containers {
+			containerSyscalls[container] = MergeSyscalls(containerSyscalls[container], syscalls)
+		}
+	}
+
+	network, bindsPrivileged := appArmorNetwork(traffic)
+	if len(network) == 0 {
+		log.Debug().Msgf("No traffic recorded for %s/%s, its profiles deny inet sockets", subject.Namespace, subject.Name)
+	}
+
+	containers := make([]string, 0, len(containerSyscalls))
+	for container := range containerSyscalls {
+		containers = append(containers, container)
+	}
+	sort.Strings(containers)
+
+	var profiles []containerAppArmorProfile
+	for _, container := range containers {
+		if len(containerSyscalls[container]) == 0 {
+			log.Warn().Msgf("No syscalls recorded for %s, skipping", seccompSubjectContainer(subject, container))
+			continue
+		}
+		profiles = append(profiles, containerAppArmorProfile{
+			Container: container,
+			Profile:   newAppArmorProfile(appArmorProfileName(subject.Namespace, subject.Name, container), containerSyscalls[container], network, bindsPrivileged, opts),
+		})
+	}
+	if len(profiles) > 0 {
+		return profiles
+	}
+
+	if len(podSyscalls) == 0 {
+		log.Warn().Msgf("No syscalls recorded for %s/%s, skipping", subject.Namespace, subject.Name)
+		return nil
+	}
+	log.Debug().Msgf("No per-container syscalls recorded for %s/%s, generating a profile for all containers", subject.Namespace, subject.Name)
+	return []containerAppArmorProfile{{
+		Profile: newAppArmorProfile(appArmorProfileName(subject.Namespace, subject.Name, ""), podSyscalls, network, bindsPrivileged, opts),
+	}}
+}
+
+// appArmorNetwork derives the network rules from the protocols of the recorded traffic, allowing
+// both IP families as dual-stack sockets carry IPv4 traffic too. It also reports whether the pod
+// received traffic on a privileged port.
+func appArmorNetwork(traffic []api.PodTraffic) ([]string, bool) {
+	var network []string
+	bindsPrivileged := false
+	for _, t := range traffic {
+		for _, socketType := range protocolSocketTypes[corev1.Protocol(strings.ToUpper(string(t.Protocol)))] {
+			network = append(network, "inet "+socketType, "inet6 "+socketType)
+		}
+		if strings.EqualFold(t.TrafficType, "INGRESS") {
+			if port, err := strconv.Atoi(t.SrcPodPort); err == nil && port > 0 && port < privilegedPortLimit {
+				bindsPrivileged = true
+			}
+		}
+	}
+	if len(network) > 0 {
+		// Name resolution queries the interfaces over netlink
+		network = append(network, "netlink raw")
+	}
+	sort.Strings(network)
+	return slices.Compact(network), bindsPrivileged
+}
+
+// newAppArmorProfile creates the profile of a container from its syscalls and the pod's network
+// rules
+func newAppArmorProfile(name string, syscalls []string, network []string, bindsPrivileged bool, opts AppArmorOptions) AppArmorProfile {
+	capabilities := slices.Clone(fileCapabilities)
+	for _, syscall := range syscalls {
+		capabilities = append(capabilities, syscallCapabilities[syscall]...)
+	}
+	if bindsPrivileged {
+		capabilities = append(capabilities, "net_bind_service")
+	}
+	sort.Strings(capabilities)
+
+	profile := AppArmorProfile{
+		Name:         name,
+		Complain:     opts.Complain,
+		Capabilities: slices.Compact(capabilities),
+		Network:      slices.Clone(network),
+		Mount:        slices.ContainsFunc(syscalls, func(s string) bool { return slices.Contains(mountSyscalls, s) }),
+		Ptrace:       slices.ContainsFunc(syscalls, func(s string) bool { return slices.Contains(ptraceSyscalls, s) }),
+	}
+	if slices.ContainsFunc(syscalls, func(s string) bool { return slices.Contains(socketSyscalls, s) }) {
+		profile.Network = append([]string{"unix"}, profile.Network...)
+	}
+	return profile
+}
+
+// String renders the profile in the AppArmor policy language
+func (p AppArmorProfile) String() string {
+	var b strings.Builder
+	flags := "attach_disconnected,mediate_deleted"
+	if p.Complain {
+		flags += ",complain"
+	}
+
+	b.WriteString("#include <tunables/global>\n\n")
+	fmt.Fprintf(&b, "profile %s flags=(%s) {\n", p.Name, flags)
+	b.WriteString("  #include <abstractions/base>\n\n")
+
+	b.WriteString("  # Capabilities the observed syscalls may need\n")
+	for _, capability := range p.Capabilities {
+		fmt.Fprintf(&b, "  capability %s,\n", capability)
+	}
+
+	b.WriteString("\n  # Address families of the recorded traffic\n")
+	if len(p.Network) == 0 {
+		b.WriteString("  # No traffic was recorded, so no sockets are allowed\n")
+	}
+	for _, network := range p.Network {
+		fmt.Fprintf(&b, "  network %s,\n", network)
+	}
+
+	b.WriteString("\n")
+	for _, rule := range appArmorFileRules {
+		fmt.Fprintf(&b, "  %s\n", rule)
+	}
+
+	b.WriteString("\n")
+	if p.Mount {
+		b.WriteString("  mount,\n  umount,\n")
+	} else {
+		b.WriteString("  deny mount,\n  deny umount,\n  deny pivot_root,\n")
+	}
+
+	fmt.Fprintf(&b, "\n  signal (send,receive) peer=%s,\n", p.Name)
+	b.WriteString("  signal (receive) peer=unconfined,\n")
+	if p.Ptrace {
+		fmt.Fprintf(&b, "  ptrace (trace,read,tracedby,readby) peer=%s,\n", p.Name)
+	} else {
+		fmt.Fprintf(&b, "  ptrace (read,readby) peer=%s,\n", p.Name)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// appArmorProfileName returns the profile name, <ns>-<name>-<container> for a container and
+// <ns>-<name> for all containers of the pod, named after its workload or the bare pod
+func appArmorProfileName(namespace, name, container string) string {
+	if container == "" {
+		return fmt.Sprintf("%s-%s", namespace, name)
+	}
+	return fmt.Sprintf("%s-%s-%s", namespace, name, container)
+}
+
+// writeAppArmorProfile writes the profile text to <profile>-apparmor in the output directory
+func writeAppArmorProfile(outputDir string, subject seccompSubject, containerProfile containerAppArmorProfile) (string, error) {
+	filename := filepath.Join(outputDir, containerProfile.Profile.Name+"-apparmor")
+	comment := fmt.Sprintf("# AppArmor profile generated from the recorded behavior of %s\n", seccompSubjectContainer(subject, containerProfile.Container))
+	if err := os.WriteFile(filename, []byte(comment+containerProfile.Profile.String()), 0644); err != nil {
+		return "", err
+	}
+	return filename, nil
+}
+
+// handleAppArmorPatch writes a patch referencing the profiles in the workload of the subject
+func handleAppArmorPatch(subject seccompSubject, profiles []containerAppArmorProfile, opts AppArmorOptions) error {
+	if len(profiles) == 0 {
+		return nil
+	}
+
+	owner := subject.Owner
+	if owner == nil {
+		log.Warn().Msgf("Pod %s/%s is not owned by a workload, set its AppArmor profile in the pod manifest", subject.Namespace, subject.Name)
+		return nil
+	}
+
+	patch, err := appArmorWorkloadPatch(subject, profiles, opts)
+	if err != nil {
+		return err
+	}
+	patchYAML, err := yaml.Marshal(patch)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the patch for %s %s: %w", owner.Kind, owner.Name, err)
+	}
+	resourceType := "apparmor-patch"
+	if opts.PatchFormat == PatchFormatKustomize {
+		resourceType = "apparmor-kustomize-patch"
+	}
+	filename, err := common.SaveToFile(opts.OutputDir, resourceType, owner.Namespace, fmt.Sprintf("%s-%s", strings.ToLower(owner.Kind), owner.Name), patchYAML)
+	if err != nil {
+		return err
+	}
+	log.Info().Msgf("Generated AppArmor patch for %s %s/%s: %s", owner.Kind, owner.Namespace, owner.Name, filename)
+	return nil
+}
+
+// appArmorWorkloadPatch builds the patch referencing the localhost profile of each container in
+// the workload's pod template, as appArmorProfile securityContext fields or as annotations. A
+// profile for all containers is set on the pod, or annotated for every container.
+func appArmorWorkloadPatch(subject seccompSubject, profiles []containerAppArmorProfile, opts AppArmorOptions) (map[string]interface{}, error) {
+	owner := subject.Owner
+	specPath := podTemplateSpecPath(owner.Kind)
+	patch := map[string]interface{}{}
+
+	if opts.Annotations {
+		annotations := map[string]interface{}{}
+		for _, profile := range profiles {
+			containers := []string{profile.Container}
+			if profile.Container == "" {
+				containers = podContainerNames(subject.Pod)
+			}
+			for _, container := range containers {
+				annotations[appArmorAnnotationPrefix+container] = "localhost/" + profile.Profile.Name
+			}
+		}
+		metadataPath := append(slices.Clone(specPath[:len(specPath)-1]), "metadata", "annotations")
+		if err := unstructured.SetNestedField(patch, annotations, metadataPath...); err != nil {
+			return nil, fmt.Errorf("failed to build the patch for %s %s: %w", owner.Kind, owner.Name, err)
+		}
+	} else {
+		podSpec := map[string]interface{}{}
+		var containers, initContainers []interface{}
+		for _, profile := range profiles {
+			securityContext := map[string]interface{}{
+				"appArmorProfile": map[string]interface{}{
+					"type":             string(corev1.AppArmorProfileTypeLocalhost),
+					"localhostProfile": profile.Profile.Name,
+				},
+			}
+
+			switch {
+			case profile.Container == "":
+				podSpec["securityContext"] = securityContext
+			case isInitContainer(subject.Pod, profile.Container):
+				initContainers = append(initContainers, map[string]interface{}{"name": profile.Container, "securityContext": securityContext})
+			default:
+				containers = append(containers, map[string]interface{}{"name": profile.Container, "securityContext": securityContext})
+			}
+		}
+		if len(containers) > 0 {
+			podSpec["containers"] = containers
+		}
+		if len(initContainers) > 0 {
+			podSpec["initContainers"] = initContainers
+		}
+		if err := unstructured.SetNestedField(patch, podSpec, specPath...); err != nil {
+			return nil, fmt.Errorf("failed to build the patch for %s %s: %w", owner.Kind, owner.Name, err)
+		}
+	}
+
+	if opts.PatchFormat == PatchFormatKustomize {
+		setKustomizeTarget(patch, owner)
+	}
+	return patch, nil
+}
+
+// podContainerNames lists the init and regular containers of the pod
+func podContainerNames(pod corev1.Pod) []string {
+	var names []string
+	for _, container := range pod.Spec.InitContainers {
+		names = append(names, container.Name)
+	}
+	for _, container := range pod.Spec.Containers {
+		names = append(names, container.Name)
+	}
+	return names
+}
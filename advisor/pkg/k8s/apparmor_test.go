@@ -0,0 +1,187 @@
+package k8s
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xentra-ai/advisor/pkg/api"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+func TestAppArmorNetwork(t *testing.T) {
+	network, bindsPrivileged := appArmorNetwork([]api.PodTraffic{
+		{TrafficType: "INGRESS", SrcPodPort: "8080", Protocol: corev1.ProtocolTCP},
+		{TrafficType: "EGRESS", DstPort: "53", Protocol: "udp"},
+		{TrafficType: "EGRESS", DstPort: "443", Protocol: corev1.ProtocolTCP},
+	})
+	assert.Equal(t, []string{"inet dgram", "inet stream", "inet6 dgram", "inet6 stream", "netlink raw"}, network)
+	assert.False(t, bindsPrivileged, "egress to privileged ports needs no capability")
+
+	_, bindsPrivileged = appArmorNetwork([]api.PodTraffic{{TrafficType: "INGRESS", SrcPodPort: "443", Protocol: corev1.ProtocolTCP}})
+	assert.True(t, bindsPrivileged)
+
+	network, _ = appArmorNetwork(nil)
+	assert.Empty(t, network)
+}
+
+func TestNewAppArmorProfile(t *testing.T) {
+	profile := newAppArmorProfile("shop-web-app", []string{"read", "setuid", "chown", "socket", "kill"}, []string{"inet stream"}, true, DefaultAppArmorOptions())
+	assert.Equal(t, []string{"chown", "dac_override", "dac_read_search", "fowner", "fsetid", "kill", "net_bind_service", "setuid"}, profile.Capabilities)
+	assert.Equal(t, []string{"unix", "inet stream"}, profile.Network)
+	assert.False(t, profile.Mount)
+	assert.False(t, profile.Ptrace)
+
+	text := profile.String()
+	assert.Contains(t, text, "profile shop-web-app flags=(attach_disconnected,mediate_deleted) {")
+	assert.Contains(t, text, "  capability net_bind_service,\n")
+	assert.Contains(t, text, "  network inet stream,\n")
+	assert.Contains(t, text, "  deny mount,\n")
+	assert.Contains(t, text, "  ptrace (read,readby) peer=shop-web-app,\n")
+	assert.Contains(t, text, "  deny @{PROC}/sysrq-trigger rwklx,\n")
+
+	opts := DefaultAppArmorOptions()
+	opts.Complain = true
+	profile = newAppArmorProfile("shop-web-app", []string{"mount", "ptrace"}, nil, false, opts)
+	assert.Equal(t, []string{"dac_override", "dac_read_search", "fowner", "fsetid", "sys_admin", "sys_ptrace"}, profile.Capabilities)
+	text = profile.String()
+	assert.Contains(t, text, "flags=(attach_disconnected,mediate_deleted,complain)")
+	assert.Contains(t, text, "No traffic was recorded")
+	assert.Contains(t, text, "  mount,\n")
+	assert.Contains(t, text, "  ptrace (trace,read,tracedby,readby) peer=shop-web-app,\n")
+}
+
+func TestBuildAppArmorProfiles(t *testing.T) {
+	subject := seccompSubject{Name: "web", Namespace: "shop", Observations: []api.PodSysCall{
+		{Syscalls: []string{"read", "chown"}, Containers: map[string][]string{"app": {"read"}, "sidecar": {"chown"}}},
+		{Syscalls: []string{"read"}, Containers: map[string][]string{"app": {"setgid"}, "idle": {}}},
+	}}
+	traffic := []api.PodTraffic{{TrafficType: "EGRESS", Protocol: corev1.ProtocolUDP}}
+
+	profiles := buildAppArmorProfiles(subject, traffic, DefaultAppArmorOptions())
+	require.Len(t, profiles, 2)
+	assert.Equal(t, "app", profiles[0].Container)
+	assert.Equal(t, "shop-web-app", profiles[0].Profile.Name)
+	assert.Contains(t, profiles[0].Profile.Capabilities, "setgid")
+	assert.NotContains(t, profiles[0].Profile.Capabilities, "chown")
+	assert.Equal(t, "sidecar", profiles[1].Container)
+	assert.Equal(t, profiles[0].Profile.Network, profiles[1].Profile.Network, "containers share the pod's network rules")
+
+	t.Run("without containers", func(t *testing.T) {
+		subject := seccompSubject{Name: "web", Namespace: "shop", Observations: []api.PodSysCall{{Syscalls: []string{"read"}}}}
+		profiles := buildAppArmorProfiles(subject, nil, DefaultAppArmorOptions())
+		require.Len(t, profiles, 1)
+		assert.Empty(t, profiles[0].Container)
+		assert.Equal(t, "shop-web", profiles[0].Profile.Name)
+	})
+}
+
+func TestAppArmorWorkloadPatch(t *testing.T) {
+	pod := *mockOwnedPod(mockOwnerRef("apps/v1", "ReplicaSet", "api-abc123"))
+	pod.Spec.InitContainers = []corev1.Container{{Name: "migrate"}}
+	owner := &Owner{APIVersion: "apps/v1", Kind: "Deployment", Name: "api", Namespace: "default"}
+	subject := seccompSubject{Name: "api", Namespace: "default", Owner: owner, Pod: pod}
+	profiles := []containerAppArmorProfile{
+		{Container: "app", Profile: AppArmorProfile{Name: "default-api-app"}},
+		{Container: "migrate", Profile: AppArmorProfile{Name: "default-api-migrate"}},
+	}
+
+	patch, err := appArmorWorkloadPatch(subject, profiles, DefaultAppArmorOptions())
+	require.NoError(t, err)
+	containers, _, _ := unstructured.NestedSlice(patch, "spec", "template", "spec", "containers")
+	assert.Equal(t, []interface{}{map[string]interface{}{
+		"name": "app",
+		"securityContext": map[string]interface{}{"appArmorProfile": map[string]interface{}{
+			"type":             "Localhost",
+			"localhostProfile": "default-api-app",
+		}},
+	}}, containers)
+	initContainers, _, _ := unstructured.NestedSlice(patch, "spec", "template", "spec", "initContainers")
+	assert.Len(t, initContainers, 1)
+
+	t.Run("annotations", func(t *testing.T) {
+		opts := DefaultAppArmorOptions()
+		opts.Annotations = true
+		opts.PatchFormat = PatchFormatKustomize
+		patch, err := appArmorWorkloadPatch(subject, profiles[:1], opts)
+		require.NoError(t, err)
+
+		annotations, _, _ := unstructured.NestedStringMap(patch, "spec", "template", "metadata", "annotations")
+		assert.Equal(t, map[string]string{"container.apparmor.security.beta.kubernetes.io/app": "localhost/default-api-app"}, annotations)
+		assert.Equal(t, "Deployment", patch["kind"])
+		_, found, _ := unstructured.NestedMap(patch, "spec", "template", "spec")
+		assert.False(t, found)
+	})
+
+	t.Run("pod profile annotated for every container", func(t *testing.T) {
+		opts := DefaultAppArmorOptions()
+		opts.Annotations = true
+		patch, err := appArmorWorkloadPatch(subject, []containerAppArmorProfile{{Profile: AppArmorProfile{Name: "default-api"}}}, opts)
+		require.NoError(t, err)
+
+		annotations, _, _ := unstructured.NestedStringMap(patch, "spec", "template", "metadata", "annotations")
+		assert.Len(t, annotations, len(pod.Spec.Containers)+1)
+		assert.Equal(t, "localhost/default-api", annotations["container.apparmor.security.beta.kubernetes.io/migrate"])
+	})
+}
+
+func TestGenerateAppArmorProfile(t *testing.T) {
+	origGetPodFunc := getPodFunc
+	origGetPodSysCallFunc := api.GetPodSysCallFunc
+	origGetPodTrafficFunc := api.GetPodTrafficFunc
+	defer func() {
+		getPodFunc = origGetPodFunc
+		api.GetPodSysCallFunc = origGetPodSysCallFunc
+		api.GetPodTrafficFunc = origGetPodTrafficFunc
+	}()
+
+	getPodFunc = func(ctx context.Context, cfg *Config, ns, name string) (*corev1.Pod, error) {
+		return createMockPodForTest(name, ns), nil
+	}
+	api.GetPodSysCallFunc = func(podName string, since time.Time) (api.PodSysCall, error) {
+		return api.PodSysCall{Arch: "x86_64", Syscalls: []string{"read"}, Containers: map[string][]string{"app": {"read", "socket"}}}, nil
+	}
+	api.GetPodTrafficFunc = func(podName string) ([]api.PodTraffic, error) {
+		return []api.PodTraffic{{SrcPodName: podName, TrafficType: "INGRESS", SrcPodPort: "8080", Protocol: corev1.ProtocolTCP}}, nil
+	}
+
+	opts := DefaultAppArmorOptions()
+	opts.OutputDir = t.TempDir()
+	GenerateAppArmorProfile(GenerateOptions{Mode: SinglePod, PodName: "web-0", Namespace: "shop"}, &Config{}, opts)
+
+	data, err := os.ReadFile(filepath.Join(opts.OutputDir, "shop-web-0-app-apparmor"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "# AppArmor profile generated from the recorded behavior of container app of shop/web-0\n")
+	assert.Contains(t, string(data), "profile shop-web-0-app flags=")
+	assert.Contains(t, string(data), "  network unix,\n  network inet stream,\n")
+
+	// Bare pods get no workload patch
+	entries, err := os.ReadDir(opts.OutputDir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestHandleAppArmorPatch(t *testing.T) {
+	opts := DefaultAppArmorOptions()
+	opts.OutputDir = t.TempDir()
+	subject := seccompSubject{
+		Name:      "api",
+		Namespace: "default",
+		Owner:     &Owner{APIVersion: "apps/v1", Kind: "Deployment", Name: "api", Namespace: "default"},
+		Pod:       *mockOwnedPod(mockOwnerRef("apps/v1", "ReplicaSet", "api-abc123")),
+	}
+
+	require.NoError(t, handleAppArmorPatch(subject, []containerAppArmorProfile{{Container: "app", Profile: AppArmorProfile{Name: "default-api-app"}}}, opts))
+	data, err := os.ReadFile(filepath.Join(opts.OutputDir, "default-deployment-api-apparmor-patch.yaml"))
+	require.NoError(t, err)
+	var patch map[string]interface{}
+	require.NoError(t, yaml.Unmarshal(data, &patch))
+	containers, _, _ := unstructured.NestedSlice(patch, "spec", "template", "spec", "containers")
+	assert.Len(t, containers, 1)
+}
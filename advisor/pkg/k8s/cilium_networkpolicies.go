@@ -41,7 +41,11 @@ func GenerateCiliumNetworkPolicy(options GenerateOptions, config *Config) {
 	}
 
 	// Fetch pods based on options
-	pods := GetResource(options, config)
+	pods, err := GetResource(options, config)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to resolve the target pods")
+		return
+	}
 
 	if len(pods) == 0 {
 		log.Info().Msg("No pods found with the specified criteria")
@@ -212,7 +216,7 @@ func transformToCiliumNetworkPolicy(podTraffic []apiapi.PodTraffic, podDetail *a
 		return nil, err
 	}
 
-	podSelectorLabels, err := detectSelectorLabels(config.Clientset, &podDetail.Pod)
+	podSelectorLabels, err := detectSelectorLabels(config, &podDetail.Pod)
 	if err != nil {
 		return nil, err
 	}
@@ -438,7 +442,7 @@ func determineCiliumEndpointForTraffic(traffic apiapi.PodTraffic, config *Config
 		return emptySelectors, nil
 	}
 
-	peerSelectorLabels, err := detectSelectorLabels(config.Clientset, origin)
+	peerSelectorLabels, err := detectSelectorLabels(config, origin)
 	if err != nil {
 		log.Debug().Err(err).Msg("Error detecting selector labels, using empty selectors")
 		return emptySelectors, nil
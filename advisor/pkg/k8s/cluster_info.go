@@ -0,0 +1,90 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	log "github.com/rs/zerolog/log"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ClusterAddresses holds the addresses of the API server and the nodes of a cluster
+type ClusterAddresses struct {
+	// APIServerIPs are the ClusterIP of the default/kubernetes Service and its endpoint addresses
+	APIServerIPs []string
+	// NodeIPs are the InternalIP and ExternalIP addresses of all nodes
+	NodeIPs []string
+}
+
+// Function variables for mocking in tests
+var (
+	getClusterAddressesFunc = getClusterAddresses // Internal function
+)
+
+// GetClusterAddresses collects the API server and node addresses from the kubernetes Service, its
+// EndpointSlices and the Node objects
+func GetClusterAddresses(ctx context.Context, config *Config) (*ClusterAddresses, error) {
+	return getClusterAddressesFunc(ctx, config)
+}
+
+func getClusterAddresses(ctx context.Context, config *Config) (*ClusterAddresses, error) {
+	if config == nil || config.Clientset == nil {
+		return nil, fmt.Errorf("not connected to a Kubernetes server")
+	}
+	return clusterAddresses(ctx, config.Clientset)
+}
+
+// clusterAddresses collects the cluster addresses using any clientset implementation
+func clusterAddresses(ctx context.Context, clientset kubernetes.Interface) (*ClusterAddresses, error) {
+	addresses := &ClusterAddresses{}
+
+	service, err := clientset.CoreV1().Services(metav1.NamespaceDefault).Get(ctx, "kubernetes", metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the kubernetes service: %w", err)
+	}
+	addresses.APIServerIPs = appendUnique(addresses.APIServerIPs, service.Spec.ClusterIPs...)
+
+	endpointSlices, err := clientset.DiscoveryV1().EndpointSlices(metav1.NamespaceDefault).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=kubernetes", discoveryv1.LabelServiceName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the kubernetes service endpoint slices: %w", err)
+	}
+	for _, slice := range endpointSlices.Items {
+		for _, endpoint := range slice.Endpoints {
+			addresses.APIServerIPs = appendUnique(addresses.APIServerIPs, endpoint.Addresses...)
+		}
+	}
+
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	for _, node := range nodes.Items {
+		for _, address := range node.Status.Addresses {
+			if address.Type == corev1.NodeInternalIP || address.Type == corev1.NodeExternalIP {
+				addresses.NodeIPs = appendUnique(addresses.NodeIPs, address.Address)
+			}
+		}
+	}
+
+	log.Debug().Msgf("Found API server addresses %v and %d node addresses", addresses.APIServerIPs, len(addresses.NodeIPs))
+	return addresses, nil
+}
+
+// appendUnique appends the non-empty values that are not in the slice yet
+func appendUnique(values []string, newValues ...string) []string {
+	for _, value := range newValues {
+		if value == "" || value == corev1.ClusterIPNone {
+			continue
+		}
+		if !slices.Contains(values, value) {
+			values = append(values, value)
+		}
+	}
+	return values
+}
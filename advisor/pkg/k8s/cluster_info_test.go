@@ -0,0 +1,54 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestClusterAddresses(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "kubernetes", Namespace: metav1.NamespaceDefault},
+			Spec:       corev1.ServiceSpec{ClusterIP: "10.96.0.1", ClusterIPs: []string{"10.96.0.1"}},
+		},
+		&discoveryv1.EndpointSlice{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "kubernetes",
+				Namespace: metav1.NamespaceDefault,
+				Labels:    map[string]string{discoveryv1.LabelServiceName: "kubernetes"},
+			},
+			Endpoints: []discoveryv1.Endpoint{{Addresses: []string{"172.18.0.2"}}, {Addresses: []string{"172.18.0.3"}}},
+		},
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "control-plane"},
+			Status: corev1.NodeStatus{Addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeInternalIP, Address: "172.18.0.2"},
+				{Type: corev1.NodeHostName, Address: "control-plane"},
+			}},
+		},
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker"},
+			Status: corev1.NodeStatus{Addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeInternalIP, Address: "172.18.0.4"},
+				{Type: corev1.NodeExternalIP, Address: "203.0.113.4"},
+			}},
+		},
+	)
+
+	addresses, err := clusterAddresses(context.Background(), clientset)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.96.0.1", "172.18.0.2", "172.18.0.3"}, addresses.APIServerIPs)
+	assert.Equal(t, []string{"172.18.0.2", "172.18.0.4", "203.0.113.4"}, addresses.NodeIPs)
+}
+
+func TestClusterAddresses_MissingService(t *testing.T) {
+	_, err := clusterAddresses(context.Background(), fake.NewSimpleClientset())
+	assert.Error(t, err)
+}
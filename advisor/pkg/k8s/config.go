@@ -8,10 +8,14 @@ import (
 
 	log "github.com/rs/zerolog/log"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
@@ -22,11 +26,13 @@ const ConfigKey contextKey = "k8sConfig"
 
 // Config holds the Kubernetes configuration
 type Config struct {
-	Clientset   *kubernetes.Clientset
-	ConfigFlags *genericclioptions.ConfigFlags
-	Config      *rest.Config
-	DryRun      bool
-	OutputDir   string
+	Clientset     *kubernetes.Clientset
+	DynamicClient dynamic.Interface
+	RESTMapper    meta.RESTMapper
+	ConfigFlags   *genericclioptions.ConfigFlags
+	Config        *rest.Config
+	DryRun        bool
+	OutputDir     string
 }
 
 // Function variables for testing
@@ -49,6 +55,15 @@ var (
 		return kubernetes.NewForConfig(c)
 	}
 
+	dynamicNewForConfigFunc = func(c *rest.Config) (dynamic.Interface, error) {
+		return dynamic.NewForConfig(c)
+	}
+
+	// newRESTMapperFunc builds a lazily populated, discovery backed RESTMapper
+	newRESTMapperFunc = func(clientset *kubernetes.Clientset) meta.RESTMapper {
+		return restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(clientset.Discovery()))
+	}
+
 	buildConfigFromFlagsFunc = func(masterUrl, kubeconfigPath string) (*rest.Config, error) {
 		return clientcmd.BuildConfigFromFlags(masterUrl, kubeconfigPath)
 	}
@@ -79,10 +94,17 @@ func NewConfig(configFlags *genericclioptions.ConfigFlags) (*Config, error) {
 		return nil, err
 	}
 
+	dynamicClient, err := dynamicNewForConfigFunc(config)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Config{
-		Clientset:   clientset,
-		ConfigFlags: configFlags,
-		Config:      config,
+		Clientset:     clientset,
+		DynamicClient: dynamicClient,
+		RESTMapper:    newRESTMapperFunc(clientset),
+		ConfigFlags:   configFlags,
+		Config:        config,
 	}, nil
 }
 
@@ -145,6 +167,11 @@ func GetConfig(dryRun bool) (*Config, error) {
 		return nil, fmt.Errorf("failed to create Kubernetes clientset: %w", err)
 	}
 
+	dynamicClient, err := dynamicNewForConfigFunc(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes dynamic client: %w", err)
+	}
+
 	// Verify the connection
 	_, err = listNodesFunc(clientset)
 	if err != nil {
@@ -167,10 +194,12 @@ func GetConfig(dryRun bool) (*Config, error) {
 	}
 
 	return &Config{
-		Config:      config,
-		Clientset:   clientset,
-		ConfigFlags: nil, // We're not using the genericclioptions.ConfigFlags here
-		DryRun:      dryRun,
+		Config:        config,
+		Clientset:     clientset,
+		DynamicClient: dynamicClient,
+		RESTMapper:    newRESTMapperFunc(clientset),
+		ConfigFlags:   nil, // We're not using the genericclioptions.ConfigFlags here
+		DryRun:        dryRun,
 	}, nil
 }
 
@@ -6,7 +6,8 @@ import (
 
 // Common error definitions for the k8s package
 var (
-	ErrNoClientset  = errors.New("no Kubernetes clientset available")
-	ErrInvalidInput = errors.New("invalid input parameters")
-	ErrNoConfig     = errors.New("no Kubernetes configuration available")
+	ErrNoClientset   = errors.New("no Kubernetes clientset available")
+	ErrInvalidInput  = errors.New("invalid input parameters")
+	ErrNoConfig      = errors.New("no Kubernetes configuration available")
+	ErrPodNotRunning = errors.New("pod is not running")
 )
@@ -2,9 +2,14 @@ package k8s
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	log "github.com/rs/zerolog/log"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 // Version is set at build time using -ldflags
@@ -17,18 +22,27 @@ const (
 	SinglePod ModeType = iota
 	AllPodsInNamespace
 	AllPodsInAllNamespaces
+	PodsBySelector
+	PodsForWorkload
 )
 
 // GenerateOptions holds options for the GenerateNetworkPolicy function
 type GenerateOptions struct {
-	Mode      ModeType
-	PodName   string // Used if Mode is SinglePod
-	Namespace string // Used if Mode is AllPodsInNamespace or SinglePod
+	Mode              ModeType
+	PodName           string      // Used if Mode is SinglePod
+	Namespace         string      // Used if Mode is AllPodsInNamespace, SinglePod, PodsForWorkload or PodsBySelector (empty for all namespaces)
+	LabelSelector     string      // Used if Mode is PodsBySelector
+	FieldSelector     string      // Used if Mode is PodsBySelector
+	Workload          WorkloadRef // Used if Mode is PodsForWorkload
+	ExcludeNamespaces []string    // Namespace glob patterns to skip, applied to every mode
+	IncludeInactive   bool        // Also target pods the broker recorded that are no longer running
 }
 
 // Exportable function variables for testing - REMOVED
 
-func GetResource(options GenerateOptions, config *Config) []corev1.Pod {
+// GetResource returns the running pods the options target. Failing to list them, e.g. for missing
+// RBAC permissions, is returned as an error, so it is not mistaken for a target matching no pods.
+func GetResource(options GenerateOptions, config *Config) ([]corev1.Pod, error) {
 	var pods []corev1.Pod
 	ctx := context.TODO() // Or pass a context if available
 
@@ -37,9 +51,12 @@ func GetResource(options GenerateOptions, config *Config) []corev1.Pod {
 		// Fetch the specified pod
 		fetchedPod, err := GetPod(ctx, config, options.Namespace, options.PodName)
 		if err != nil {
-			// Log the error and return an empty slice instead of fatally exiting.
-			log.Error().Err(err).Msgf("failed to get running pod %s in namespace %s", options.PodName, options.Namespace)
-			return []corev1.Pod{}
+			if options.IncludeInactive && (apierrors.IsNotFound(err) || errors.Is(err, ErrPodNotRunning)) {
+				// The pod completed or was deleted, look it up in the broker instead
+				log.Debug().Err(err).Msgf("pod %s in namespace %s is not running, checking broker records", options.PodName, options.Namespace)
+				break
+			}
+			return nil, fmt.Errorf("failed to get running pod %s in namespace %s: %w", options.PodName, options.Namespace, err)
 		}
 		pods = append(pods, *fetchedPod)
 
@@ -47,9 +64,7 @@ func GetResource(options GenerateOptions, config *Config) []corev1.Pod {
 		// Fetch all running pods in the given namespace
 		fetchedPods, err := GetPodsInNamespace(ctx, config, options.Namespace)
 		if err != nil {
-			log.Error().Err(err).Msgf("failed to fetch running pods in namespace %s", options.Namespace)
-			// Return empty list on error, or handle differently as needed
-			return []corev1.Pod{}
+			return nil, fmt.Errorf("failed to fetch running pods in namespace %s: %w", options.Namespace, err)
 		}
 		pods = append(pods, fetchedPods...)
 
@@ -57,14 +72,120 @@ func GetResource(options GenerateOptions, config *Config) []corev1.Pod {
 		// Fetch all running pods in all namespaces
 		fetchedPods, err := GetAllPodsInAllNamespaces(ctx, config)
 		if err != nil {
-			log.Error().Err(err).Msgf("failed to fetch all running pods in all namespaces")
-			// Return empty list on error, or handle differently as needed
-			return []corev1.Pod{}
+			return nil, fmt.Errorf("failed to fetch running pods in all namespaces: %w", err)
 		}
 		pods = append(pods, fetchedPods...)
+
+	case PodsBySelector:
+		// Fetch all running pods matching the label and field selectors
+		fetchedPods, err := GetPodsBySelector(ctx, config, options.Namespace, options.LabelSelector, options.FieldSelector)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch running pods matching selector %q (field selector %q): %w", options.LabelSelector, options.FieldSelector, err)
+		}
+		pods = append(pods, fetchedPods...)
+
+	case PodsForWorkload:
+		// Fetch all running pods managed by the referenced workload
+		fetchedPods, err := GetPodsForWorkload(ctx, config, options.Namespace, options.Workload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch running pods for %s in namespace %s: %w", options.Workload, options.Namespace, err)
+		}
+		pods = append(pods, fetchedPods...)
+
 	default:
-		log.Error().Msgf("Unknown mode type: %v", options.Mode)
-		return []corev1.Pod{}
+		return nil, fmt.Errorf("%w: unknown mode type %v", ErrInvalidInput, options.Mode)
+	}
+
+	if options.IncludeInactive {
+		pods = mergeBrokerPods(ctx, options, config, pods)
+	}
+
+	return ExcludeNamespaces(pods, options.ExcludeNamespaces), nil
+}
+
+// mergeBrokerPods adds the pods recorded by the broker that match the target options but are
+// not running anymore, such as completed Jobs, crashed pods or scaled-to-zero workloads
+func mergeBrokerPods(ctx context.Context, options GenerateOptions, config *Config, pods []corev1.Pod) []corev1.Pod {
+	matches, err := brokerPodMatcher(ctx, options, config)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to build matcher for broker pods, only running pods will be targeted")
+		return pods
+	}
+
+	brokerPods, err := GetBrokerPods(ctx, options.Namespace)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to fetch pods recorded by the broker, only running pods will be targeted")
+		return pods
+	}
+
+	seen := make(map[string]bool, len(pods))
+	for _, pod := range pods {
+		seen[pod.Namespace+"/"+pod.Name] = true
+	}
+
+	added := 0
+	for _, pod := range brokerPods {
+		key := pod.Namespace + "/" + pod.Name
+		if seen[key] || !matches(pod) {
+			continue
+		}
+		seen[key] = true
+		pods = append(pods, pod)
+		added++
+		log.Debug().Msgf("Found inactive pod in broker records: %s", key)
 	}
+
+	log.Info().Msgf("Added %d inactive pods from broker records", added)
 	return pods
 }
+
+// brokerPodMatcher returns a predicate that selects broker pods within the scope of the target options
+func brokerPodMatcher(ctx context.Context, options GenerateOptions, config *Config) (func(corev1.Pod) bool, error) {
+	switch options.Mode {
+	case SinglePod:
+		return func(pod corev1.Pod) bool {
+			return pod.Name == options.PodName && pod.Namespace == options.Namespace
+		}, nil
+
+	case AllPodsInNamespace, AllPodsInAllNamespaces:
+		// Broker pods are already fetched for the namespace in scope
+		return func(pod corev1.Pod) bool { return true }, nil
+
+	case PodsBySelector:
+		labelSelector, err := labels.Parse(options.LabelSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid label selector %q: %w", options.LabelSelector, err)
+		}
+		fieldSelector, err := fields.ParseSelector(options.FieldSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid field selector %q: %w", options.FieldSelector, err)
+		}
+		return func(pod corev1.Pod) bool {
+			return labelSelector.Matches(labels.Set(pod.Labels)) && fieldSelector.Matches(podFieldSet(pod))
+		}, nil
+
+	case PodsForWorkload:
+		selector, err := getWorkloadSelector(ctx, config, options.Namespace, options.Workload)
+		if err != nil {
+			return nil, err
+		}
+		return func(pod corev1.Pod) bool {
+			return selector.Matches(labels.Set(pod.Labels))
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown mode type: %v", options.Mode)
+	}
+}
+
+// podFieldSet returns the pod fields that field selectors are commonly evaluated against
+func podFieldSet(pod corev1.Pod) fields.Set {
+	return fields.Set{
+		"metadata.name":           pod.Name,
+		"metadata.namespace":      pod.Namespace,
+		"spec.nodeName":           pod.Spec.NodeName,
+		"spec.serviceAccountName": pod.Spec.ServiceAccountName,
+		"status.phase":            string(pod.Status.Phase),
+		"status.podIP":            pod.Status.PodIP,
+	}
+}
@@ -2,10 +2,13 @@ package k8s
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -28,10 +31,14 @@ func TestGetResource(t *testing.T) {
 	origGetPodFunc := getPodFunc
 	origGetPodsInNamespaceFunc := getPodsInNamespaceFunc
 	origGetAllPodsInAllNamespacesFunc := getAllPodsInAllNamespacesFunc
+	origGetPodsBySelectorFunc := getPodsBySelectorFunc
+	origGetPodsForWorkloadFunc := getPodsForWorkloadFunc
 	defer func() {
 		getPodFunc = origGetPodFunc
 		getPodsInNamespaceFunc = origGetPodsInNamespaceFunc
 		getAllPodsInAllNamespacesFunc = origGetAllPodsInAllNamespacesFunc
+		getPodsBySelectorFunc = origGetPodsBySelectorFunc
+		getPodsForWorkloadFunc = origGetPodsForWorkloadFunc
 	}()
 	// --- End save/restore ---
 
@@ -47,7 +54,8 @@ func TestGetResource(t *testing.T) {
 		assert.Equal(t, "test-pod", name)
 		return createMockPodForTest(name, ns), nil
 	}
-	podsSingle := GetResource(optionsSingle, config)
+	podsSingle, err := GetResource(optionsSingle, config)
+	require.NoError(t, err)
 	assert.Len(t, podsSingle, 1)
 	assert.Equal(t, "test-pod", podsSingle[0].Name)
 	assert.Equal(t, "test-namespace", podsSingle[0].Namespace)
@@ -66,7 +74,8 @@ func TestGetResource(t *testing.T) {
 			*createMockPodForTest("test-pod-2", ns),
 		}, nil
 	}
-	podsNamespace := GetResource(optionsNamespace, config)
+	podsNamespace, err := GetResource(optionsNamespace, config)
+	require.NoError(t, err)
 	assert.Len(t, podsNamespace, 2)
 	assert.Equal(t, "test-pod-1", podsNamespace[0].Name)
 	assert.Equal(t, "test-pod-2", podsNamespace[1].Name)
@@ -84,7 +93,8 @@ func TestGetResource(t *testing.T) {
 			*createMockPodForTest("test-pod-b", "ns-b"),
 		}, nil
 	}
-	podsAll := GetResource(optionsAll, config)
+	podsAll, err := GetResource(optionsAll, config)
+	require.NoError(t, err)
 	assert.Len(t, podsAll, 2)
 	assert.Equal(t, "test-pod-a", podsAll[0].Name)
 	assert.Equal(t, "ns-a", podsAll[0].Namespace)
@@ -92,14 +102,154 @@ func TestGetResource(t *testing.T) {
 	assert.Equal(t, "ns-b", podsAll[1].Namespace)
 	// --- End Test AllPodsInAllNamespaces mode ---
 
+	// --- Test ExcludeNamespaces with AllPodsInAllNamespaces mode ---
+	optionsExclude := GenerateOptions{
+		Mode:              AllPodsInAllNamespaces,
+		ExcludeNamespaces: []string{"ns-b"},
+	}
+	podsExclude, err := GetResource(optionsExclude, config)
+	require.NoError(t, err)
+	assert.Len(t, podsExclude, 1)
+	assert.Equal(t, "test-pod-a", podsExclude[0].Name)
+	// --- End Test ExcludeNamespaces ---
+
+	// --- Test PodsBySelector mode ---
+	optionsSelector := GenerateOptions{
+		Mode:          PodsBySelector,
+		Namespace:     "test-namespace",
+		LabelSelector: "app=api",
+		FieldSelector: "spec.nodeName=node-1",
+	}
+	getPodsBySelectorFunc = func(ctx context.Context, cfg *Config, ns, labels, fields string) ([]corev1.Pod, error) {
+		assert.Equal(t, "test-namespace", ns)
+		assert.Equal(t, "app=api", labels)
+		assert.Equal(t, "spec.nodeName=node-1", fields)
+		return []corev1.Pod{*createMockPodForTest("api-1", ns)}, nil
+	}
+	podsSelector, err := GetResource(optionsSelector, config)
+	require.NoError(t, err)
+	assert.Len(t, podsSelector, 1)
+	assert.Equal(t, "api-1", podsSelector[0].Name)
+	// --- End Test PodsBySelector mode ---
+
+	// --- Test PodsForWorkload mode ---
+	optionsWorkload := GenerateOptions{
+		Mode:      PodsForWorkload,
+		Namespace: "test-namespace",
+		Workload:  WorkloadRef{Kind: "statefulset", Name: "db"},
+	}
+	getPodsForWorkloadFunc = func(ctx context.Context, cfg *Config, ns string, workload WorkloadRef) ([]corev1.Pod, error) {
+		assert.Equal(t, "test-namespace", ns)
+		assert.Equal(t, "statefulset/db", workload.String())
+		return []corev1.Pod{
+			*createMockPodForTest("db-0", ns),
+			*createMockPodForTest("db-1", ns),
+		}, nil
+	}
+	podsWorkload, err := GetResource(optionsWorkload, config)
+	require.NoError(t, err)
+	assert.Len(t, podsWorkload, 2)
+	assert.Equal(t, "db-0", podsWorkload[0].Name)
+	// --- End Test PodsForWorkload mode ---
+
 	// --- Test Error Handling (Example for SinglePod) ---
 	getPodFunc = func(ctx context.Context, cfg *Config, ns, name string) (*corev1.Pod, error) {
 		return nil, assert.AnError // Simulate an error
 	}
-	// Assert that an empty slice is returned on error now
-	podsError := GetResource(optionsSingle, config)
-	assert.Empty(t, podsError, "Expected empty slice on GetPod error")
+	// The error is returned, so callers do not mistake it for a target matching no pods
+	podsError, err := GetResource(optionsSingle, config)
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.Empty(t, podsError)
+
+	// --- Test IncludeInactive falls back to broker records ---
+	origGetBrokerPodsFunc := getBrokerPodsFunc
+	defer func() { getBrokerPodsFunc = origGetBrokerPodsFunc }()
+	getBrokerPodsFunc = func(ctx context.Context, ns string) ([]corev1.Pod, error) {
+		return []corev1.Pod{
+			*createMockPodForTest("test-pod", "test-namespace"),
+			*createMockPodForTest("other-pod", "test-namespace"),
+		}, nil
+	}
+	optionsInactive := optionsSingle
+	optionsInactive.IncludeInactive = true
+	// Other errors, e.g. missing RBAC permissions, are not hidden by the broker records
+	_, err = GetResource(optionsInactive, config)
+	assert.ErrorIs(t, err, assert.AnError)
+
+	for _, podErr := range []error{
+		apierrors.NewNotFound(corev1.Resource("pods"), "test-pod"),
+		fmt.Errorf("%w: pod test-pod in namespace test-namespace is in state Succeeded", ErrPodNotRunning),
+	} {
+		getPodFunc = func(ctx context.Context, cfg *Config, ns, name string) (*corev1.Pod, error) {
+			return nil, podErr
+		}
+		podsInactive, err := GetResource(optionsInactive, config)
+		require.NoError(t, err)
+		assert.Len(t, podsInactive, 1, "Expected the completed pod to be found in broker records")
+		assert.Equal(t, "test-pod", podsInactive[0].Name)
+	}
+	// --- End Test IncludeInactive ---
 
-	// Add similar error tests for other modes if needed (they currently return empty slices, not panic)
+	getPodsInNamespaceFunc = func(ctx context.Context, cfg *Config, ns string) ([]corev1.Pod, error) {
+		return nil, assert.AnError // Simulate a list or RBAC error
+	}
+	_, err = GetResource(optionsNamespace, config)
+	assert.ErrorIs(t, err, assert.AnError)
+
+	_, err = GetResource(GenerateOptions{Mode: ModeType(99)}, config)
+	assert.ErrorIs(t, err, ErrInvalidInput)
 	// --- End Test Error Handling ---
 }
+
+func TestGetResource_IncludeInactive(t *testing.T) {
+	config := &Config{}
+
+	origGetPodsBySelectorFunc := getPodsBySelectorFunc
+	origGetBrokerPodsFunc := getBrokerPodsFunc
+	defer func() {
+		getPodsBySelectorFunc = origGetPodsBySelectorFunc
+		getBrokerPodsFunc = origGetBrokerPodsFunc
+	}()
+
+	running := createMockPodForTest("api-1", "prod")
+	running.Labels = map[string]string{"app": "api"}
+	getPodsBySelectorFunc = func(ctx context.Context, cfg *Config, ns, labels, fields string) ([]corev1.Pod, error) {
+		return []corev1.Pod{*running}, nil
+	}
+
+	completed := createMockPodForTest("api-migrate", "prod")
+	completed.Labels = map[string]string{"app": "api"}
+	completed.Spec.NodeName = "node-1"
+	unrelated := createMockPodForTest("web-1", "prod")
+	unrelated.Labels = map[string]string{"app": "web"}
+	getBrokerPodsFunc = func(ctx context.Context, ns string) ([]corev1.Pod, error) {
+		// The running pod is recorded by the broker too and must not be duplicated
+		return []corev1.Pod{*running, *completed, *unrelated}, nil
+	}
+
+	options := GenerateOptions{
+		Mode:            PodsBySelector,
+		Namespace:       "prod",
+		LabelSelector:   "app=api",
+		IncludeInactive: true,
+	}
+	pods, err := GetResource(options, config)
+	require.NoError(t, err)
+	assert.Len(t, pods, 2)
+	assert.Equal(t, "api-1", pods[0].Name)
+	assert.Equal(t, "api-migrate", pods[1].Name)
+
+	// Field selectors are evaluated against broker records as well
+	options.FieldSelector = "spec.nodeName=node-2"
+	pods, err = GetResource(options, config)
+	require.NoError(t, err)
+	assert.Len(t, pods, 1)
+	assert.Equal(t, "api-1", pods[0].Name)
+
+	// Without the flag the broker is not consulted
+	options.FieldSelector = ""
+	options.IncludeInactive = false
+	pods, err = GetResource(options, config)
+	require.NoError(t, err)
+	assert.Len(t, pods, 1)
+}
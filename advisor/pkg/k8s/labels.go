@@ -3,21 +3,53 @@ package k8s
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strings"
 
+	log "github.com/rs/zerolog/log"
 	api "github.com/xentra-ai/advisor/pkg/api"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
+// maxOwnerDepth bounds the ownerReference walk to guard against reference cycles
+const maxOwnerDepth = 10
+
+// Owner describes the top-level controller of a pod
+type Owner struct {
+	APIVersion string
+	Kind       string
+	Name       string
+	Namespace  string
+	// Selector holds the labels selecting the controller's pods. It is nil when the controller
+	// selects its pods with matchExpressions, which a label map cannot express.
+	Selector map[string]string
+}
+
+// wellKnownOwnerResources maps common controller kinds to their resources, so owners can be
+// resolved when no RESTMapper is available or discovery does not know the kind
+var wellKnownOwnerResources = map[schema.GroupKind]string{
+	{Group: "apps", Kind: "ReplicaSet"}:        "replicasets",
+	{Group: "apps", Kind: "Deployment"}:        "deployments",
+	{Group: "apps", Kind: "StatefulSet"}:       "statefulsets",
+	{Group: "apps", Kind: "DaemonSet"}:         "daemonsets",
+	{Group: "batch", Kind: "Job"}:              "jobs",
+	{Group: "batch", Kind: "CronJob"}:          "cronjobs",
+	{Group: "", Kind: "ReplicationController"}: "replicationcontrollers",
+	{Group: "argoproj.io", Kind: "Rollout"}:    "rollouts",
+}
+
 // DetectLabels detects the labels of a pod.
-func detectSelectorLabels(clientset *kubernetes.Clientset, origin interface{}) (map[string]string, error) {
+func detectSelectorLabels(config *Config, origin interface{}) (map[string]string, error) {
 	// Use type assertion to check the specific type
 	switch o := origin.(type) {
 	case *v1.Pod:
-		return GetOwnerRef(clientset, o)
+		return GetOwnerRef(config, o)
 	case *api.PodDetail:
-		return GetOwnerRef(clientset, &o.Pod)
+		return GetOwnerRef(config, &o.Pod)
 	case *api.SvcDetail:
 		var svc v1.Service
 		svc = o.Service
@@ -27,54 +59,216 @@ func detectSelectorLabels(clientset *kubernetes.Clientset, origin interface{}) (
 	}
 }
 
-func GetOwnerRef(clientset *kubernetes.Clientset, pod *v1.Pod) (map[string]string, error) {
-	ctx := context.TODO()
-
-	// Check if the Pod has an owner
-	if len(pod.OwnerReferences) > 0 {
-		owner := pod.OwnerReferences[0]
-
-		// TODO: If the resource no longer exists but the database has the log/entry this will cause it to break for this netpol
-
-		// Based on the owner, get the controller object to check its labels
-		switch owner.Kind {
-		case "ReplicaSet":
-			replicaSet, err := clientset.AppsV1().ReplicaSets(pod.Namespace).Get(ctx, owner.Name, metav1.GetOptions{})
-			if err != nil {
-				return nil, err
-			}
-			deployment, err := clientset.AppsV1().Deployments(pod.Namespace).Get(ctx, replicaSet.OwnerReferences[0].Name, metav1.GetOptions{})
-			if err != nil {
-				return nil, err
-			}
-			return deployment.Spec.Selector.MatchLabels, nil
-
-		case "StatefulSet":
-			statefulSet, err := clientset.AppsV1().StatefulSets(pod.Namespace).Get(ctx, owner.Name, metav1.GetOptions{})
-			if err != nil {
-				return nil, err
-			}
-			return statefulSet.Spec.Selector.MatchLabels, nil
-
-		case "DaemonSet":
-			daemonSet, err := clientset.AppsV1().DaemonSets(pod.Namespace).Get(ctx, owner.Name, metav1.GetOptions{})
-			if err != nil {
-				return nil, err
-			}
-			return daemonSet.Spec.Selector.MatchLabels, nil
-
-		case "Job":
-			job, err := clientset.BatchV1().Jobs(pod.Namespace).Get(ctx, owner.Name, metav1.GetOptions{})
-			if err != nil {
-				return nil, err
-			}
-			return job.Spec.Selector.MatchLabels, nil
-
-		// Add more controller kinds here if needed
-
-		default:
-			return nil, fmt.Errorf("unknown or unsupported ownerReference: %s", owner.String())
+// jobRunLabels are set by the Job controller on the pods of a single run, so a selector holding
+// them never matches the pods of the next CronJob run
+var jobRunLabels = []string{
+	"batch.kubernetes.io/job-name",
+	"batch.kubernetes.io/controller-uid",
+	"job-name",
+	"controller-uid",
+}
+
+// GetOwnerRef returns the selector labels of the pod's top-level controller.
+// It falls back to the pod's own labels when the pod has no owner or the owner chain
+// cannot be resolved, e.g. because an owner no longer exists.
+func GetOwnerRef(config *Config, pod *v1.Pod) (map[string]string, error) {
+	if len(pod.OwnerReferences) == 0 {
+		return pod.Labels, nil
+	}
+
+	if config == nil || config.DynamicClient == nil {
+		log.Debug().Msgf("No dynamic client available to resolve owner of pod %s/%s, using pod labels", pod.Namespace, pod.Name)
+		return podSelectorLabels(pod), nil
+	}
+
+	owner, err := ResolveOwner(context.TODO(), config, pod)
+	if err != nil {
+		log.Debug().Err(err).Msgf("Could not resolve owner of pod %s/%s, using pod labels", pod.Namespace, pod.Name)
+		return podSelectorLabels(pod), nil
+	}
+
+	if owner == nil || len(owner.Selector) == 0 {
+		log.Debug().Msgf("Owner of pod %s/%s has no usable selector, using pod labels", pod.Namespace, pod.Name)
+		return podSelectorLabels(pod), nil
+	}
+
+	return owner.Selector, nil
+}
+
+// podInstanceLabels are set by controllers on individual pods or revisions, so a selector holding
+// them only matches this pod or the pods of the current rollout
+var podInstanceLabels = []string{
+	"pod-template-hash",
+	"controller-revision-hash",
+	"pod-template-generation",
+	"statefulset.kubernetes.io/pod-name",
+	"apps.kubernetes.io/pod-index",
+}
+
+// podSelectorLabels returns the pod labels used when the owner's selector is unavailable. The
+// per-pod and per-revision labels set by controllers, and the per-run labels of Job pods, are
+// dropped so the selector also matches other replicas, later rollouts and later CronJob runs; when
+// nothing else is left the pod labels are kept and only this pod is matched.
+func podSelectorLabels(pod *v1.Pod) map[string]string {
+	dropped := podInstanceLabels
+	ref := controllerRef(pod.OwnerReferences)
+	isJob := ref != nil && ref.Kind == "Job" && strings.HasPrefix(ref.APIVersion, "batch/")
+	if isJob {
+		dropped = append(slices.Clone(podInstanceLabels), jobRunLabels...)
+	}
+
+	selector := make(map[string]string, len(pod.Labels))
+	for key, value := range pod.Labels {
+		if !slices.Contains(dropped, key) {
+			selector[key] = value
+		}
+	}
+	if len(selector) == len(pod.Labels) {
+		return pod.Labels
+	}
+	if len(selector) == 0 {
+		if isJob {
+			log.Warn().Msgf("Pod %s/%s of Job %s only has per-run labels, the selector will not match other runs; add labels to the job template", pod.Namespace, pod.Name, ref.Name)
+		} else {
+			log.Warn().Msgf("Pod %s/%s only has per-pod labels, the selector will not match other replicas; add labels to the pod template", pod.Namespace, pod.Name)
+		}
+		return pod.Labels
+	}
+
+	if isJob {
+		log.Warn().Msgf("Owner of pod %s/%s has no usable selector, using the pod labels without the per-run labels of Job %s", pod.Namespace, pod.Name, ref.Name)
+	} else {
+		log.Debug().Msgf("Using the labels of pod %s/%s without its per-pod and per-revision labels", pod.Namespace, pod.Name)
+	}
+	return selector
+}
+
+// ResolveOwner walks the pod's controller ownerReferences through the dynamic client up to the
+// top-level controller, e.g. Pod -> ReplicaSet -> Deployment or Pod -> Job -> CronJob.
+// It returns nil when the pod is not owned by a controller.
+func ResolveOwner(ctx context.Context, config *Config, pod *v1.Pod) (*Owner, error) {
+	if config == nil || config.DynamicClient == nil {
+		return nil, ErrNoClientset
+	}
+
+	ref := controllerRef(pod.OwnerReferences)
+	if ref == nil {
+		return nil, nil
+	}
+
+	var top *unstructured.Unstructured
+	for depth := 0; ref != nil; depth++ {
+		if depth >= maxOwnerDepth {
+			return nil, fmt.Errorf("owner chain of pod %s/%s exceeds %d levels", pod.Namespace, pod.Name, maxOwnerDepth)
+		}
+
+		gvr, namespaced, err := ownerResource(config.RESTMapper, *ref)
+		if err != nil {
+			return nil, err
+		}
+		resource := config.DynamicClient.Resource(gvr)
+
+		var obj *unstructured.Unstructured
+		if namespaced {
+			obj, err = resource.Namespace(pod.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		} else {
+			obj, err = resource.Get(ctx, ref.Name, metav1.GetOptions{})
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get owner %s %s of pod %s/%s: %w", ref.Kind, ref.Name, pod.Namespace, pod.Name, err)
+		}
+
+		log.Debug().Msgf("Resolved owner %s %s of pod %s/%s", obj.GetKind(), obj.GetName(), pod.Namespace, pod.Name)
+		top = obj
+		ref = controllerRef(obj.GetOwnerReferences())
+	}
+
+	selector, err := selectorFromUnstructured(top)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Owner{
+		APIVersion: top.GetAPIVersion(),
+		Kind:       top.GetKind(),
+		Name:       top.GetName(),
+		Namespace:  top.GetNamespace(),
+		Selector:   selector,
+	}, nil
+}
+
+// controllerRef returns the managing controller reference, or the first reference if none is
+// marked as controller
+func controllerRef(refs []metav1.OwnerReference) *metav1.OwnerReference {
+	for i := range refs {
+		if refs[i].Controller != nil && *refs[i].Controller {
+			return &refs[i]
 		}
 	}
-	return pod.Labels, nil
+	if len(refs) > 0 {
+		return &refs[0]
+	}
+	return nil
+}
+
+// ownerResource maps an ownerReference to its resource and reports whether it is namespaced.
+// The RESTMapper is preferred; well-known kinds are the fallback when it does not know the kind,
+// and a lowercase plural guess is only made when there is no RESTMapper to ask.
+func ownerResource(mapper meta.RESTMapper, ref metav1.OwnerReference) (schema.GroupVersionResource, bool, error) {
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil {
+		return schema.GroupVersionResource{}, false, fmt.Errorf("invalid apiVersion %q on ownerReference %s: %w", ref.APIVersion, ref.Name, err)
+	}
+	gk := schema.GroupKind{Group: gv.Group, Kind: ref.Kind}
+
+	if mapper != nil {
+		mapping, err := mapper.RESTMapping(gk, gv.Version)
+		if err == nil {
+			return mapping.Resource, mapping.Scope.Name() != meta.RESTScopeNameRoot, nil
+		}
+		if resource, ok := wellKnownOwnerResources[gk]; ok && meta.IsNoMatchError(err) {
+			log.Debug().Err(err).Msgf("RESTMapper does not know %s, falling back to known resources", gk)
+			return gv.WithResource(resource), true, nil
+		}
+		return schema.GroupVersionResource{}, false, fmt.Errorf("failed to map %s to a resource: %w", gk, err)
+	}
+
+	if resource, ok := wellKnownOwnerResources[gk]; ok {
+		return gv.WithResource(resource), true, nil
+	}
+
+	return gv.WithResource(strings.ToLower(ref.Kind) + "s"), true, nil
+}
+
+// selectorFromUnstructured reads the pod selector labels from a controller object.
+// It understands LabelSelector style selectors (spec.selector.matchLabels), map style selectors
+// (ReplicationController spec.selector) and falls back to pod template labels for controllers
+// without a selector such as CronJob. Selectors with matchExpressions give a nil selector, as
+// their matchLabels alone would select more pods than the controller does.
+func selectorFromUnstructured(obj *unstructured.Unstructured) (map[string]string, error) {
+	if expressions, found, err := unstructured.NestedSlice(obj.Object, "spec", "selector", "matchExpressions"); err == nil && found && len(expressions) > 0 {
+		log.Debug().Msgf("%s %s selects its pods with matchExpressions, which cannot be expressed as labels", obj.GetKind(), obj.GetName())
+		return nil, nil
+	}
+
+	if matchLabels, found, err := unstructured.NestedStringMap(obj.Object, "spec", "selector", "matchLabels"); err == nil && found && len(matchLabels) > 0 {
+		return matchLabels, nil
+	}
+
+	// ReplicationController selectors are plain label maps
+	if selector, found, err := unstructured.NestedStringMap(obj.Object, "spec", "selector"); err == nil && found && len(selector) > 0 {
+		return selector, nil
+	}
+
+	templatePaths := [][]string{
+		{"spec", "template", "metadata", "labels"},
+		{"spec", "jobTemplate", "spec", "template", "metadata", "labels"},
+	}
+	for _, path := range templatePaths {
+		if templateLabels, found, err := unstructured.NestedStringMap(obj.Object, path...); err == nil && found && len(templateLabels) > 0 {
+			return templateLabels, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no selector or pod template labels found on %s %s", obj.GetKind(), obj.GetName())
 }
@@ -1,17 +1,23 @@
 package k8s
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	api "github.com/xentra-ai/advisor/pkg/api"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
 )
 
 func TestDetectSelectorLabels(t *testing.T) {
-	clientset := &kubernetes.Clientset{}
+	config := &Config{}
 	pod := &v1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
 			Labels: map[string]string{
@@ -38,18 +44,241 @@ func TestDetectSelectorLabels(t *testing.T) {
 		},
 	}
 
-	labels1, err1 := detectSelectorLabels(clientset, pod)
+	labels1, err1 := detectSelectorLabels(config, pod)
 	assert.NoError(t, err1)
 	assert.Equal(t, map[string]string{"app": "test-app"}, labels1)
 
-	labels2, err2 := detectSelectorLabels(clientset, podDetail)
+	labels2, err2 := detectSelectorLabels(config, podDetail)
 	assert.NoError(t, err2)
 	assert.Equal(t, map[string]string{"app": "test-app"}, labels2)
 
-	labels3, err3 := detectSelectorLabels(clientset, serviceDetail)
+	labels3, err3 := detectSelectorLabels(config, serviceDetail)
 	assert.NoError(t, err3)
 	assert.Equal(t, map[string]string{"app": "test-app"}, labels3)
 
-	_, err4 := detectSelectorLabels(clientset, "unknown type")
+	_, err4 := detectSelectorLabels(config, "unknown type")
 	assert.Error(t, err4)
 }
+
+// Helper to create an unstructured owner object for the fake dynamic client
+func mockOwnerObject(apiVersion, kind, name string, spec map[string]interface{}, owner *metav1.OwnerReference) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       kind,
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": "default",
+		},
+		"spec": spec,
+	}}
+	if owner != nil {
+		obj.SetOwnerReferences([]metav1.OwnerReference{*owner})
+	}
+	return obj
+}
+
+// Helper to create a controller ownerReference
+func mockOwnerRef(apiVersion, kind, name string) *metav1.OwnerReference {
+	isController := true
+	return &metav1.OwnerReference{APIVersion: apiVersion, Kind: kind, Name: name, Controller: &isController}
+}
+
+// Helper to create a pod owned by the given reference
+func mockOwnedPod(owner *metav1.OwnerReference) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "owned-pod",
+			Namespace:       "default",
+			Labels:          map[string]string{"app": "pod-label", "pod-template-hash": "abc123"},
+			OwnerReferences: []metav1.OwnerReference{*owner},
+		},
+	}
+}
+
+func TestGetOwnerRef_OwnerChains(t *testing.T) {
+	objects := []runtime.Object{
+		// Deployment -> ReplicaSet
+		mockOwnerObject("apps/v1", "Deployment", "api", map[string]interface{}{
+			"selector": map[string]interface{}{"matchLabels": map[string]interface{}{"app": "api"}},
+		}, nil),
+		mockOwnerObject("apps/v1", "ReplicaSet", "api-abc123", map[string]interface{}{
+			"selector": map[string]interface{}{"matchLabels": map[string]interface{}{"app": "api", "pod-template-hash": "abc123"}},
+		}, mockOwnerRef("apps/v1", "Deployment", "api")),
+		// ReplicaSet without an owner must not panic
+		mockOwnerObject("apps/v1", "ReplicaSet", "orphan", map[string]interface{}{
+			"selector": map[string]interface{}{"matchLabels": map[string]interface{}{"app": "orphan"}},
+		}, nil),
+		// CronJob -> Job, CronJob has no selector so the job template labels are used
+		mockOwnerObject("batch/v1", "CronJob", "backup", map[string]interface{}{
+			"jobTemplate": map[string]interface{}{"spec": map[string]interface{}{"template": map[string]interface{}{
+				"metadata": map[string]interface{}{"labels": map[string]interface{}{"app": "backup"}},
+			}}},
+		}, nil),
+		mockOwnerObject("batch/v1", "Job", "backup-28391", map[string]interface{}{
+			"selector": map[string]interface{}{"matchLabels": map[string]interface{}{"controller-uid": "1234"}},
+		}, mockOwnerRef("batch/v1", "CronJob", "backup")),
+		// ReplicationController uses a plain map selector
+		mockOwnerObject("v1", "ReplicationController", "legacy", map[string]interface{}{
+			"selector": map[string]interface{}{"app": "legacy"},
+		}, nil),
+		// Deployment selecting its pods with matchExpressions
+		mockOwnerObject("apps/v1", "Deployment", "worker", map[string]interface{}{
+			"selector": map[string]interface{}{
+				"matchLabels": map[string]interface{}{"app": "worker"},
+				"matchExpressions": []interface{}{
+					map[string]interface{}{"key": "track", "operator": "NotIn", "values": []interface{}{"canary"}},
+				},
+			},
+		}, nil),
+		mockOwnerObject("apps/v1", "ReplicaSet", "worker-7c9d", map[string]interface{}{
+			"selector": map[string]interface{}{"matchLabels": map[string]interface{}{"app": "worker", "pod-template-hash": "7c9d"}},
+		}, mockOwnerRef("apps/v1", "Deployment", "worker")),
+		// Argo Rollout -> ReplicaSet
+		mockOwnerObject("argoproj.io/v1alpha1", "Rollout", "canary", map[string]interface{}{
+			"selector": map[string]interface{}{"matchLabels": map[string]interface{}{"app": "canary"}},
+		}, nil),
+		mockOwnerObject("apps/v1", "ReplicaSet", "canary-5f6d", map[string]interface{}{
+			"selector": map[string]interface{}{"matchLabels": map[string]interface{}{"app": "canary", "rollouts-pod-template-hash": "5f6d"}},
+		}, mockOwnerRef("argoproj.io/v1alpha1", "Rollout", "canary")),
+	}
+
+	config := &Config{
+		DynamicClient: dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), objects...),
+	}
+
+	tests := []struct {
+		name     string
+		owner    *metav1.OwnerReference
+		expected map[string]string
+	}{
+		{"ReplicaSet to Deployment", mockOwnerRef("apps/v1", "ReplicaSet", "api-abc123"), map[string]string{"app": "api"}},
+		{"ReplicaSet without owner", mockOwnerRef("apps/v1", "ReplicaSet", "orphan"), map[string]string{"app": "orphan"}},
+		{"Job to CronJob", mockOwnerRef("batch/v1", "Job", "backup-28391"), map[string]string{"app": "backup"}},
+		{"ReplicationController", mockOwnerRef("v1", "ReplicationController", "legacy"), map[string]string{"app": "legacy"}},
+		{"ReplicaSet to Argo Rollout", mockOwnerRef("apps/v1", "ReplicaSet", "canary-5f6d"), map[string]string{"app": "canary"}},
+		{"matchExpressions fall back to pod labels", mockOwnerRef("apps/v1", "ReplicaSet", "worker-7c9d"), map[string]string{"app": "pod-label"}},
+		{"Missing owner falls back to pod labels", mockOwnerRef("apps/v1", "ReplicaSet", "deleted"), map[string]string{"app": "pod-label"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			labels, err := GetOwnerRef(config, mockOwnedPod(tt.owner))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, labels)
+		})
+	}
+}
+
+func TestGetOwnerRef_CronJobWithoutTemplateLabels(t *testing.T) {
+	config := &Config{
+		DynamicClient: dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(),
+			mockOwnerObject("batch/v1", "CronJob", "report", map[string]interface{}{
+				"jobTemplate": map[string]interface{}{"spec": map[string]interface{}{"template": map[string]interface{}{
+					"spec": map[string]interface{}{},
+				}}},
+			}, nil),
+			mockOwnerObject("batch/v1", "Job", "report-28391", map[string]interface{}{
+				"selector": map[string]interface{}{"matchLabels": map[string]interface{}{"batch.kubernetes.io/controller-uid": "1234"}},
+			}, mockOwnerRef("batch/v1", "CronJob", "report")),
+		),
+	}
+
+	// The Job controller adds the per-run labels, which would select only this run
+	pod := mockOwnedPod(mockOwnerRef("batch/v1", "Job", "report-28391"))
+	pod.Labels = map[string]string{
+		"app":                                "report",
+		"batch.kubernetes.io/job-name":       "report-28391",
+		"job-name":                           "report-28391",
+		"batch.kubernetes.io/controller-uid": "1234",
+		"controller-uid":                     "1234",
+	}
+
+	labels, err := GetOwnerRef(config, pod)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"app": "report"}, labels)
+
+	// Without other labels the pod labels are kept, rather than selecting every pod
+	pod.Labels = map[string]string{"job-name": "report-28391", "controller-uid": "1234"}
+	labels, err = GetOwnerRef(config, pod)
+	require.NoError(t, err)
+	assert.Equal(t, pod.Labels, labels)
+}
+
+func TestPodSelectorLabels(t *testing.T) {
+	// A StatefulSet pod whose owner could not be resolved keeps only the labels shared by its replicas
+	pod := mockOwnedPod(mockOwnerRef("apps/v1", "StatefulSet", "db"))
+	pod.Labels = map[string]string{
+		"app":                                "db",
+		"controller-revision-hash":           "db-6d4c8f",
+		"statefulset.kubernetes.io/pod-name": "db-0",
+		"apps.kubernetes.io/pod-index":       "0",
+	}
+	assert.Equal(t, map[string]string{"app": "db"}, podSelectorLabels(pod))
+
+	// Without other labels the pod labels are kept, rather than selecting every pod
+	pod.Labels = map[string]string{"statefulset.kubernetes.io/pod-name": "db-0"}
+	assert.Equal(t, pod.Labels, podSelectorLabels(pod))
+
+	pod.Labels = map[string]string{"app": "db", "tier": "data"}
+	assert.Equal(t, pod.Labels, podSelectorLabels(pod))
+}
+
+func TestResolveOwner_CustomController(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "WebApp"}
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{gvk.GroupVersion()})
+	mapper.AddSpecific(gvk, gvk.GroupVersion().WithResource("webapps"), gvk.GroupVersion().WithResource("webapp"), meta.RESTScopeNamespace)
+
+	webApp := mockOwnerObject("example.com/v1", "WebApp", "shop", map[string]interface{}{
+		"selector": map[string]interface{}{"matchLabels": map[string]interface{}{"webapp": "shop"}},
+	}, nil)
+
+	scheme := runtime.NewScheme()
+	config := &Config{
+		DynamicClient: dynamicfake.NewSimpleDynamicClientWithCustomListKinds(scheme,
+			map[schema.GroupVersionResource]string{gvk.GroupVersion().WithResource("webapps"): "WebAppList"}, webApp),
+		RESTMapper: mapper,
+	}
+
+	owner, err := ResolveOwner(context.TODO(), config, mockOwnedPod(mockOwnerRef("example.com/v1", "WebApp", "shop")))
+	assert.NoError(t, err)
+	assert.Equal(t, "WebApp", owner.Kind)
+	assert.Equal(t, "shop", owner.Name)
+	assert.Equal(t, map[string]string{"webapp": "shop"}, owner.Selector)
+
+	// Pods without owners have no owner to resolve
+	owner, err = ResolveOwner(context.TODO(), config, &v1.Pod{})
+	assert.NoError(t, err)
+	assert.Nil(t, owner)
+}
+
+// failingRESTMapper fails every mapping, as a RESTMapper does when discovery is unavailable
+type failingRESTMapper struct {
+	meta.RESTMapper
+	err error
+}
+
+func (m failingRESTMapper) RESTMapping(gk schema.GroupKind, versions ...string) (*meta.RESTMapping, error) {
+	return nil, m.err
+}
+
+func TestOwnerResource(t *testing.T) {
+	gvr, namespaced, err := ownerResource(nil, *mockOwnerRef("example.com/v1", "WebApp", "shop"))
+	require.NoError(t, err)
+	assert.Equal(t, "webapps", gvr.Resource, "the plural is guessed without a RESTMapper")
+	assert.True(t, namespaced)
+
+	// Well-known kinds the RESTMapper does not know fall back to their resources
+	mapper := meta.NewDefaultRESTMapper(nil)
+	gvr, _, err = ownerResource(mapper, *mockOwnerRef("apps/v1", "Deployment", "api"))
+	require.NoError(t, err)
+	assert.Equal(t, "deployments", gvr.Resource)
+
+	_, _, err = ownerResource(mapper, *mockOwnerRef("example.com/v1", "WebApp", "shop"))
+	assert.True(t, meta.IsNoMatchError(err), "unknown kinds are not guessed when a RESTMapper is available")
+
+	_, _, err = ownerResource(failingRESTMapper{err: assert.AnError}, *mockOwnerRef("apps/v1", "Deployment", "api"))
+	assert.ErrorIs(t, err, assert.AnError)
+
+	_, _, err = ownerResource(nil, *mockOwnerRef("apps/v1/beta", "Deployment", "api"))
+	assert.Error(t, err)
+}
@@ -10,7 +10,6 @@ import (
 	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
-	"k8s.io/client-go/kubernetes"
 )
 
 // API function variables are now defined in the api package
@@ -19,8 +18,8 @@ import (
 var (
 	processIngressRulesFunc  = processIngressRules
 	processEgressRulesFunc   = processEgressRules
-	detectSelectorLabelsFunc = func(clientset *kubernetes.Clientset, origin interface{}) (map[string]string, error) {
-		return detectSelectorLabels(clientset, origin)
+	detectSelectorLabelsFunc = func(config *Config, origin interface{}) (map[string]string, error) {
+		return detectSelectorLabels(config, origin)
 	}
 	determinePeerForTrafficFunc = determinePeerForTraffic
 
@@ -3,17 +3,48 @@ package k8s
 import (
 	"context"
 	"fmt"
+	"path"
+	"strings"
 
 	log "github.com/rs/zerolog/log"
+	api "github.com/xentra-ai/advisor/pkg/api"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
+// WorkloadRef identifies a workload by kind and name, e.g. deployment/api
+type WorkloadRef struct {
+	Kind string
+	Name string
+}
+
+// String returns the workload reference in kind/name form
+func (w WorkloadRef) String() string {
+	return fmt.Sprintf("%s/%s", w.Kind, w.Name)
+}
+
+// Supported workload kinds, keyed by the aliases accepted on the command line
+var workloadKindAliases = map[string]string{
+	"deployment":   "deployment",
+	"deployments":  "deployment",
+	"deploy":       "deployment",
+	"statefulset":  "statefulset",
+	"statefulsets": "statefulset",
+	"sts":          "statefulset",
+	"daemonset":    "daemonset",
+	"daemonsets":   "daemonset",
+	"ds":           "daemonset",
+}
+
 // Function variables for mocking in tests
 var (
 	getPodFunc                    = getPod                    // Internal function
 	getPodsInNamespaceFunc        = getPodsInNamespace        // Internal function
 	getAllPodsInAllNamespacesFunc = getAllPodsInAllNamespaces // Internal function
+	getPodsBySelectorFunc         = getPodsBySelector         // Internal function
+	getPodsForWorkloadFunc        = getPodsForWorkload        // Internal function
+	getBrokerPodsFunc             = getBrokerPods             // Internal function
 )
 
 // GetPod fetches a single running pod by name and namespace.
@@ -31,6 +62,71 @@ func GetAllPodsInAllNamespaces(ctx context.Context, config *Config) ([]corev1.Po
 	return getAllPodsInAllNamespacesFunc(ctx, config)
 }
 
+// GetPodsBySelector fetches all running pods matching the given label and field selectors.
+// An empty namespace searches all namespaces.
+func GetPodsBySelector(ctx context.Context, config *Config, namespace, labelSelector, fieldSelector string) ([]corev1.Pod, error) {
+	return getPodsBySelectorFunc(ctx, config, namespace, labelSelector, fieldSelector)
+}
+
+// GetPodsForWorkload fetches all running pods managed by the referenced workload.
+func GetPodsForWorkload(ctx context.Context, config *Config, namespace string, workload WorkloadRef) ([]corev1.Pod, error) {
+	return getPodsForWorkloadFunc(ctx, config, namespace, workload)
+}
+
+// GetBrokerPods fetches every pod the broker has recorded, regardless of whether it still runs.
+// An empty namespace returns pods from all namespaces.
+func GetBrokerPods(ctx context.Context, namespace string) ([]corev1.Pod, error) {
+	return getBrokerPodsFunc(ctx, namespace)
+}
+
+// ParseWorkloadRef parses a kind/name reference such as deployment/api or sts/db.
+func ParseWorkloadRef(ref string) (WorkloadRef, error) {
+	kind, name, found := strings.Cut(ref, "/")
+	if !found || kind == "" || name == "" {
+		return WorkloadRef{}, fmt.Errorf("%w: workload reference %q must be in the form kind/name", ErrInvalidInput, ref)
+	}
+
+	canonicalKind, ok := workloadKindAliases[strings.ToLower(kind)]
+	if !ok {
+		return WorkloadRef{}, fmt.Errorf("%w: unsupported workload kind %q (supported: deployment, statefulset, daemonset)", ErrInvalidInput, kind)
+	}
+
+	return WorkloadRef{Kind: canonicalKind, Name: name}, nil
+}
+
+// ExcludeNamespaces removes pods whose namespace matches any of the given glob patterns.
+func ExcludeNamespaces(pods []corev1.Pod, patterns []string) []corev1.Pod {
+	if len(patterns) == 0 {
+		return pods
+	}
+
+	filtered := []corev1.Pod{}
+	for _, pod := range pods {
+		if namespaceExcluded(pod.Namespace, patterns) {
+			log.Debug().Msgf("Skipping pod %s/%s: namespace is excluded", pod.Namespace, pod.Name)
+			continue
+		}
+		filtered = append(filtered, pod)
+	}
+
+	return filtered
+}
+
+// namespaceExcluded reports whether a namespace matches any of the exclusion patterns
+func namespaceExcluded(namespace string, patterns []string) bool {
+	for _, pattern := range patterns {
+		matched, err := path.Match(pattern, namespace)
+		if err != nil {
+			log.Warn().Err(err).Msgf("Invalid namespace exclusion pattern %q", pattern)
+			continue
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
 // --- Internal implementations ---
 
 // getPod is the internal implementation for GetPod
@@ -48,7 +144,7 @@ func getPod(ctx context.Context, config *Config, namespace, podName string) (*co
 	}
 
 	if pod.Status.Phase != corev1.PodRunning {
-		return nil, fmt.Errorf("pod %s in namespace %s is not in Running state (current state: %s)", podName, namespace, pod.Status.Phase)
+		return nil, fmt.Errorf("%w: pod %s in namespace %s is in state %s", ErrPodNotRunning, podName, namespace, pod.Status.Phase)
 	}
 
 	log.Debug().Msgf("Found running pod: %s", pod.Name)
@@ -106,3 +202,132 @@ func getAllPodsInAllNamespaces(ctx context.Context, config *Config) ([]corev1.Po
 	log.Info().Msgf("Found %d running pods across all namespaces", len(runningPods))
 	return runningPods, nil
 }
+
+// getPodsBySelector is the internal implementation for GetPodsBySelector
+func getPodsBySelector(ctx context.Context, config *Config, namespace, labelSelector, fieldSelector string) ([]corev1.Pod, error) {
+	if config == nil || config.Clientset == nil {
+		return nil, ErrNoClientset
+	}
+
+	log.Debug().Msgf("Getting running pods in namespace %q with label selector %q and field selector %q", namespace, labelSelector, fieldSelector)
+
+	podList, err := config.Clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labelSelector,
+		FieldSelector: fieldSelector,
+	})
+	if err != nil {
+		log.Error().Err(err).Msgf("Error listing pods with label selector %q and field selector %q", labelSelector, fieldSelector)
+		return nil, err
+	}
+
+	runningPods := filterRunningPods(podList.Items)
+	log.Info().Msgf("Found %d running pods matching the given selectors", len(runningPods))
+	return runningPods, nil
+}
+
+// getPodsForWorkload is the internal implementation for GetPodsForWorkload
+func getPodsForWorkload(ctx context.Context, config *Config, namespace string, workload WorkloadRef) ([]corev1.Pod, error) {
+	if config == nil || config.Clientset == nil {
+		return nil, ErrNoClientset
+	}
+
+	log.Debug().Msgf("Getting running pods for %s in namespace %s", workload, namespace)
+
+	labelSelector, err := getWorkloadSelector(ctx, config, namespace, workload)
+	if err != nil {
+		return nil, err
+	}
+
+	podList, err := config.Clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: labelSelector.String()})
+	if err != nil {
+		log.Error().Err(err).Msgf("Error listing pods for %s in namespace %s", workload, namespace)
+		return nil, err
+	}
+
+	runningPods := filterRunningPods(podList.Items)
+	log.Info().Msgf("Found %d running pods for %s in namespace %s", len(runningPods), workload, namespace)
+	return runningPods, nil
+}
+
+// getWorkloadSelector returns the pod selector of the referenced workload
+func getWorkloadSelector(ctx context.Context, config *Config, namespace string, workload WorkloadRef) (labels.Selector, error) {
+	if config == nil || config.Clientset == nil {
+		return nil, ErrNoClientset
+	}
+
+	var selector *metav1.LabelSelector
+	switch workload.Kind {
+	case "deployment":
+		deployment, err := config.Clientset.AppsV1().Deployments(namespace).Get(ctx, workload.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		selector = deployment.Spec.Selector
+	case "statefulset":
+		statefulSet, err := config.Clientset.AppsV1().StatefulSets(namespace).Get(ctx, workload.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		selector = statefulSet.Spec.Selector
+	case "daemonset":
+		daemonSet, err := config.Clientset.AppsV1().DaemonSets(namespace).Get(ctx, workload.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		selector = daemonSet.Spec.Selector
+	default:
+		return nil, fmt.Errorf("%w: unsupported workload kind %q", ErrInvalidInput, workload.Kind)
+	}
+
+	labelSelector, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector on %s: %w", workload, err)
+	}
+	if labelSelector.Empty() {
+		return nil, fmt.Errorf("%s has an empty selector", workload)
+	}
+
+	return labelSelector, nil
+}
+
+// getBrokerPods is the internal implementation for GetBrokerPods
+func getBrokerPods(ctx context.Context, namespace string) ([]corev1.Pod, error) {
+	log.Debug().Msgf("Getting pods recorded by the broker in namespace %q", namespace)
+
+	details, err := api.GetPodDetails()
+	if err != nil {
+		log.Error().Err(err).Msg("Error listing pods recorded by the broker")
+		return nil, err
+	}
+
+	pods := []corev1.Pod{}
+	for _, detail := range details {
+		pod := detail.Pod
+		// Older broker records may carry an incomplete pod object
+		if pod.Name == "" {
+			pod.Name = detail.Name
+		}
+		if pod.Namespace == "" {
+			pod.Namespace = detail.Namespace
+		}
+		if pod.Name == "" || (namespace != "" && pod.Namespace != namespace) {
+			continue
+		}
+		pods = append(pods, pod)
+	}
+
+	log.Info().Msgf("Found %d pods recorded by the broker", len(pods))
+	return pods, nil
+}
+
+// filterRunningPods returns only the pods in the Running phase
+func filterRunningPods(pods []corev1.Pod) []corev1.Pod {
+	runningPods := []corev1.Pod{}
+	for _, pod := range pods {
+		if pod.Status.Phase == corev1.PodRunning {
+			runningPods = append(runningPods, pod)
+			log.Debug().Msgf("Found running pod: %s/%s", pod.Namespace, pod.Name)
+		}
+	}
+	return runningPods
+}
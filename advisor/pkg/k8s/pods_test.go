@@ -0,0 +1,101 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	api "github.com/xentra-ai/advisor/pkg/api"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestParseWorkloadRef(t *testing.T) {
+	tests := []struct {
+		ref      string
+		expected WorkloadRef
+		wantErr  bool
+	}{
+		{ref: "deployment/api", expected: WorkloadRef{Kind: "deployment", Name: "api"}},
+		{ref: "deploy/api", expected: WorkloadRef{Kind: "deployment", Name: "api"}},
+		{ref: "StatefulSet/db", expected: WorkloadRef{Kind: "statefulset", Name: "db"}},
+		{ref: "sts/db", expected: WorkloadRef{Kind: "statefulset", Name: "db"}},
+		{ref: "daemonset/agent", expected: WorkloadRef{Kind: "daemonset", Name: "agent"}},
+		{ref: "ds/agent", expected: WorkloadRef{Kind: "daemonset", Name: "agent"}},
+		{ref: "cronjob/backup", wantErr: true},
+		{ref: "deployment/", wantErr: true},
+		{ref: "api", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			workload, err := ParseWorkloadRef(tt.ref)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidInput)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, workload)
+		})
+	}
+}
+
+func TestExcludeNamespaces(t *testing.T) {
+	pods := []corev1.Pod{
+		*createMockPodForTest("coredns", "kube-system"),
+		*createMockPodForTest("api", "prod"),
+		*createMockPodForTest("ingress", "platform-ingress"),
+		*createMockPodForTest("monitor", "platform-monitoring"),
+	}
+
+	// No patterns keeps every pod
+	assert.Len(t, ExcludeNamespaces(pods, nil), 4)
+
+	filtered := ExcludeNamespaces(pods, []string{"kube-system", "platform-*"})
+	assert.Len(t, filtered, 1)
+	assert.Equal(t, "api", filtered[0].Name)
+
+	// Invalid patterns are ignored rather than excluding everything
+	assert.Len(t, ExcludeNamespaces(pods, []string{"["}), 4)
+}
+
+func TestFilterRunningPods(t *testing.T) {
+	running := createMockPodForTest("running", "default")
+	running.Status.Phase = corev1.PodRunning
+	pending := createMockPodForTest("pending", "default")
+	pending.Status.Phase = corev1.PodPending
+
+	filtered := filterRunningPods([]corev1.Pod{*running, *pending})
+	assert.Len(t, filtered, 1)
+	assert.Equal(t, "running", filtered[0].Name)
+}
+
+func TestGetBrokerPods(t *testing.T) {
+	origGetPodDetailsFunc := api.GetPodDetailsFunc
+	defer func() { api.GetPodDetailsFunc = origGetPodDetailsFunc }()
+
+	api.GetPodDetailsFunc = func() ([]api.PodDetail, error) {
+		return []api.PodDetail{
+			{Name: "backup-28391", Namespace: "batch", Pod: *createMockPodForTest("backup-28391", "batch")},
+			// Record without a pod object falls back to the broker's name and namespace
+			{Name: "worker-0", Namespace: "batch"},
+			{Name: "api", Namespace: "prod", Pod: *createMockPodForTest("api", "prod")},
+		}, nil
+	}
+
+	pods, err := GetBrokerPods(context.TODO(), "batch")
+	assert.NoError(t, err)
+	assert.Len(t, pods, 2)
+	assert.Equal(t, "backup-28391", pods[0].Name)
+	assert.Equal(t, "worker-0", pods[1].Name)
+	assert.Equal(t, "batch", pods[1].Namespace)
+
+	allPods, err := GetBrokerPods(context.TODO(), "")
+	assert.NoError(t, err)
+	assert.Len(t, allPods, 3)
+
+	api.GetPodDetailsFunc = func() ([]api.PodDetail, error) {
+		return nil, assert.AnError
+	}
+	_, err = GetBrokerPods(context.TODO(), "")
+	assert.Error(t, err)
+}
@@ -1,111 +1,452 @@
 package k8s
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
 
 	log "github.com/rs/zerolog/log"
 	api "github.com/xentra-ai/advisor/pkg/api"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
-// SeccompProfile represents the structure of a seccomp security profile
+// SeccompProfile represents the structure of a seccomp security profile. Runtimes reject profiles
+// setting both architectures and archMap.
 type SeccompProfile struct {
-	DefaultAction string   `json:"defaultAction"`
-	Architectures []string `json:"architectures"`
-	Syscalls      []Rule   `json:"syscalls"`
+	DefaultAction string `json:"defaultAction"`
+	// DefaultErrnoRet is the errno returned by an SCMP_ACT_ERRNO or SCMP_ACT_TRACE default action
+	DefaultErrnoRet *uint     `json:"defaultErrnoRet,omitempty"`
+	Architectures   []string  `json:"architectures,omitempty"`
+	ArchMap         []ArchMap `json:"archMap,omitempty"`
+	// Flags are SECCOMP_FILTER_FLAG_* flags for loading the filter
+	Flags []string `json:"flags,omitempty"`
+	// ListenerPath is the socket SCMP_ACT_NOTIFY rules are forwarded to, with ListenerMetadata
+	ListenerPath     string `json:"listenerPath,omitempty"`
+	ListenerMetadata string `json:"listenerMetadata,omitempty"`
+	Syscalls         []Rule `json:"syscalls"`
+}
+
+// ArchMap is a native architecture of a profile with the sub-architectures it also applies to
+type ArchMap struct {
+	Architecture     string   `json:"architecture"`
+	SubArchitectures []string `json:"subArchitectures,omitempty"`
 }
 
 // Rule represents a seccomp rule with action and syscalls
 type Rule struct {
 	Names  []string `json:"names"`
 	Action string   `json:"action"`
+	// Args restricts the rule to calls whose arguments match all comparisons
+	Args []Arg `json:"args,omitempty"`
+	// ErrnoRet is the errno returned by an SCMP_ACT_ERRNO rule
+	ErrnoRet *uint `json:"errnoRet,omitempty"`
+	// Includes limits the rule to some architectures, capabilities or kernels, Excludes skips them
+	Includes *RuleFilter `json:"includes,omitempty"`
+	Excludes *RuleFilter `json:"excludes,omitempty"`
+	// Comment documents the rule in Docker's profile format
+	Comment string `json:"comment,omitempty"`
+}
+
+// Arg compares a syscall argument, by its index, with a value. SCMP_CMP_MASKED_EQ compares the
+// argument masked with Value to ValueTwo.
+type Arg struct {
+	Index    uint   `json:"index"`
+	Value    uint64 `json:"value"`
+	ValueTwo uint64 `json:"valueTwo,omitempty"`
+	Op       string `json:"op"`
+}
+
+// RuleFilter selects the architectures, by their Go name, the capabilities and the minimum kernel
+// version a rule applies to, as in Docker's profile format
+type RuleFilter struct {
+	Arches    []string `json:"arches,omitempty"`
+	Caps      []string `json:"caps,omitempty"`
+	MinKernel string   `json:"minKernel,omitempty"`
+}
+
+// SeccompFormat is the output format of generated seccomp profiles
+type SeccompFormat string
+
+const (
+	// SeccompFormatJSON writes raw seccomp profile JSON files to install on the nodes
+	SeccompFormatJSON SeccompFormat = "json"
+	// SeccompFormatSPO writes Security Profiles Operator SeccompProfile custom resources
+	SeccompFormatSPO SeccompFormat = "spo"
+)
+
+// ParseSeccompFormat converts a format flag value to a SeccompFormat
+func ParseSeccompFormat(format string) (SeccompFormat, error) {
+	switch SeccompFormat(strings.ToLower(format)) {
+	case SeccompFormatJSON:
+		return SeccompFormatJSON, nil
+	case SeccompFormatSPO:
+		return SeccompFormatSPO, nil
+	default:
+		return "", fmt.Errorf("%w: unknown seccomp format %q, expected json or spo", ErrInvalidInput, format)
+	}
 }
 
 // ProfileOptions contains configuration for profile generation
 type ProfileOptions struct {
 	OutputDir     string
 	DefaultAction string
+	// Architectures overrides the archMap derived from the observed architectures
 	Architectures []string
+	// PodUnion also writes a pod-level profile allowing the syscalls of all containers
+	PodUnion bool
+	// Format selects raw JSON files or SeccompProfile custom resources
+	Format SeccompFormat
+	// PatchWorkloads writes patches setting the generated profiles on the pods' workloads
+	PatchWorkloads bool
+	// PatchFormat selects strategic-merge or kustomize patch files
+	PatchFormat PatchFormat
+	// ProfileDir is the directory below the kubelet seccomp root raw profiles are installed in
+	ProfileDir string
+	// ProfilesInstalled confirms the raw profiles are installed on the nodes, so workload patches
+	// referencing them may be applied
+	ProfilesInstalled bool
+	// FirstRecordedWithin only uses the pods the broker first recorded within this window, zero uses
+	// all pods. The broker keeps one cumulative syscall set per container, so older syscalls of a pod
+	// that is still recorded are not dropped.
+	FirstRecordedWithin time.Duration
+	// Baseline adds a set of syscalls to every profile
+	Baseline SeccompBaseline
+	// Report also writes a risk report of the observed syscalls for every workload
+	Report bool
+	// ArgFilters restricts high-risk syscalls with the built-in argument filter templates
+	ArgFilters bool
+	// Rollout stages profiles in log mode, or promotes staged profiles to enforcement
+	Rollout RolloutMode
+	// PromoteAfter is how long no new syscalls must be observed before a staged profile is promoted
+	PromoteAfter time.Duration
 }
 
-func GenerateSeccompProfile(options GenerateOptions, config *Config) {
-
-	var Architectures = map[string][]string{
-		"x86_64": {"SCMP_ARCH_X86_64"},
-		"ARM64":  {"SCMP_ARCH_ARM64"},
-	}
-
-	// Default profile options
-	profileOpts := ProfileOptions{
+// DefaultProfileOptions returns the profile options used when no flags are set
+func DefaultProfileOptions() ProfileOptions {
+	return ProfileOptions{
 		OutputDir:     "seccomp-profiles",
 		DefaultAction: "SCMP_ACT_ERRNO",
+		Format:        SeccompFormatJSON,
+		PatchFormat:   PatchFormatStrategic,
+		Baseline:      SeccompBaselineNone,
+		Rollout:       RolloutNone,
+		PromoteAfter:  7 * 24 * time.Hour,
 	}
+}
 
+// containerSeccompProfile is a generated profile for a container, or for the whole pod when the
+// container is empty
+type containerSeccompProfile struct {
+	Container string
+	Profile   SeccompProfile
+}
+
+// seccompSubject is a workload, or a bare pod, whose observed pods share one set of profiles
+type seccompSubject struct {
+	// Name is the workload name, or the pod name for bare pods
+	Name      string
+	Namespace string
+	// Owner is the workload owning the pods, nil for bare pods
+	Owner *Owner
+	// Pod is the first targeted pod, its spec stands for all replicas
+	Pod corev1.Pod
+	// PodNames lists the pods whose syscalls were observed
+	PodNames []string
+	// Observations holds the syscalls recorded for each pod, possibly on different architectures
+	Observations []api.PodSysCall
+}
+
+// GenerateSeccompProfile writes a seccomp profile for every container of the targeted workloads,
+// as the securityContext seccompProfile is set per container. All replicas of a workload the
+// broker recorded, including replicas running on nodes of different architectures, are merged
+// into one profile.
+func GenerateSeccompProfile(options GenerateOptions, config *Config, profileOpts ProfileOptions) {
 	// Fetch pods based on options
-	pods := GetResource(options, config)
+	pods, err := GetResource(options, config)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to resolve the target pods")
+	}
 
 	// Create output directory if it doesn't exist
 	if err := os.MkdirAll(profileOpts.OutputDir, 0755); err != nil {
 		log.Fatal().Err(err).Msgf("failed to create output directory")
 	}
 
-	// Generate seccompprofile for each pod in pods
+	if profileOpts.Format == SeccompFormatJSON && config != nil && !config.DryRun {
+		log.Warn().Msg("Raw seccomp profiles must be installed on the nodes, only saving them to files")
+		if profileOpts.PatchWorkloads && !profileOpts.ProfilesInstalled {
+			log.Warn().Msg("Workload patches are not applied until the profiles are installed on the nodes, pass --profiles-installed once they are")
+		}
+	}
+
+	var stageState *seccompStageState
+	if profileOpts.Rollout != RolloutNone {
+		var err error
+		if stageState, err = loadStageState(profileOpts.OutputDir); err != nil {
+			log.Fatal().Err(err).Msg("Failed to load the staged rollout state")
+		}
+	}
+	now := time.Now().UTC()
+
+	// Generate seccompprofile for each workload of the pods
+	for _, subject := range collectSeccompSubjects(context.TODO(), config, pods, profileOpts.FirstRecordedWithin) {
+		profiles := buildSeccompProfiles(subject, profileOpts)
+		valid := profiles[:0]
+		for _, containerProfile := range profiles {
+			containerProfile.Profile = checkSeccompSyscalls(subject, containerProfile)
+			if err := ValidateProfile(containerProfile.Profile); err != nil {
+				log.Error().Err(err).Msgf("Invalid seccomp profile for %s, skipping", seccompSubjectContainer(subject, containerProfile.Container))
+				continue
+			}
+			if stageState != nil {
+				key := seccompProfileName(subject.Namespace, subject.Name, containerProfile.Container)
+				containerProfile.Profile.DefaultAction = stageState.rollout(key, containerProfile.Profile, profileOpts, now)
+			}
+			valid = append(valid, containerProfile)
+			if err := handleSeccompProfileOutput(config, subject, containerProfile, profileOpts); err != nil {
+				log.Error().Err(err).Msgf("Failed to output the seccomp profile of %s", seccompSubjectContainer(subject, containerProfile.Container))
+			}
+		}
+
+		if profileOpts.Report {
+			if _, err := writeSeccompReportFile(profileOpts.OutputDir, subject); err != nil {
+				log.Error().Err(err).Msgf("Failed to write the seccomp report of %s/%s", subject.Namespace, subject.Name)
+			}
+		}
+
+		if profileOpts.PatchWorkloads {
+			if err := handleWorkloadPatch(context.TODO(), config, subject, valid, profileOpts); err != nil {
+				log.Error().Err(err).Msgf("Failed to patch the workload of %s/%s", subject.Namespace, subject.Name)
+			}
+		}
+	}
+
+	if stageState != nil {
+		if err := stageState.save(profileOpts.OutputDir); err != nil {
+			log.Error().Err(err).Msg("Failed to save the staged rollout state")
+		}
+	}
+}
+
+// collectSeccompSubjects fetches the syscalls recorded for the pods and the other replicas of their
+// workloads, only using the pods first recorded within the window when it is set
+func collectSeccompSubjects(ctx context.Context, config *Config, pods []corev1.Pod, firstRecordedWithin time.Duration) []seccompSubject {
+	var since time.Time
+	if firstRecordedWithin > 0 {
+		since = time.Now().UTC().Add(-firstRecordedWithin)
+		log.Info().Msgf("Using pods first recorded since %s", since.Format(time.RFC3339))
+	}
+
+	subjects := seccompSubjects(ctx, config, pods, since)
+	addRecordedReplicas(ctx, subjects, since)
+	return subjects
+}
+
+// seccompSubjects fetches the syscalls of the pods and groups them by owning workload, keeping the
+// order the pods were targeted in. Pods whose owner cannot be resolved are handled on their own.
+func seccompSubjects(ctx context.Context, config *Config, pods []corev1.Pod, since time.Time) []seccompSubject {
+	var subjects []seccompSubject
+	index := make(map[string]int)
+
 	for _, pod := range pods {
-		podSysCalls, err := api.GetPodSysCall(pod.Name)
+		podSysCalls, err := api.GetPodSysCall(pod.Name, since)
 		if err != nil {
 			log.Debug().Err(err).Msgf("Error retrieving %s pod syscall", pod.Name)
 			continue
 		}
 
-		profile := SeccompProfile{
-			DefaultAction: profileOpts.DefaultAction,
-			Architectures: Architectures[podSysCalls.Arch],
-			Syscalls: []Rule{
-				{
-					Names:  podSysCalls.Syscalls,
-					Action: "SCMP_ACT_ALLOW",
-				},
-			},
+		owner, err := ResolveOwner(ctx, config, &pod)
+		if err != nil && !errors.Is(err, ErrNoClientset) {
+			log.Debug().Err(err).Msgf("Failed to resolve the workload of pod %s/%s, generating its own profiles", pod.Namespace, pod.Name)
 		}
 
-		// Generate profile JSON
-		profileJSON, err := json.MarshalIndent(profile, "", "    ")
-		if err != nil {
-			log.Error().Err(err).Msgf("Failed to marshal profile for pod %s", pod.Name)
+		subject := seccompSubject{Name: pod.Name, Namespace: pod.Namespace, Pod: pod}
+		key := fmt.Sprintf("%s/Pod/%s", pod.Namespace, pod.Name)
+		if owner != nil {
+			subject.Name, subject.Owner = owner.Name, owner
+			key = fmt.Sprintf("%s/%s/%s", owner.Namespace, owner.Kind, owner.Name)
+		}
+
+		i, ok := index[key]
+		if !ok {
+			i = len(subjects)
+			index[key] = i
+			subjects = append(subjects, subject)
+		}
+		subjects[i].PodNames = append(subjects[i].PodNames, pod.Name)
+		subjects[i].Observations = append(subjects[i].Observations, podSysCalls)
+	}
+	return subjects
+}
+
+// addRecordedReplicas adds the syscalls of the workloads' replicas the broker recorded but that were
+// not targeted, such as pods of earlier rollouts or scaled-down replicas. Replicas are found by the
+// workload's selector, as their ReplicaSet may be gone.
+func addRecordedReplicas(ctx context.Context, subjects []seccompSubject, since time.Time) {
+	brokerPods := make(map[string][]corev1.Pod)
+
+	for i := range subjects {
+		subject := &subjects[i]
+		if subject.Owner == nil || len(subject.Owner.Selector) == 0 {
 			continue
 		}
 
-		// Write profile to file
-		filename := filepath.Join(profileOpts.OutputDir, fmt.Sprintf("%s-seccomp.json", pod.Name))
-		if err := os.WriteFile(filename, profileJSON, 0644); err != nil {
-			log.Error().Err(err).Msgf("Failed to write profile for pod %s", pod.Name)
+		pods, ok := brokerPods[subject.Namespace]
+		if !ok {
+			var err error
+			if pods, err = GetBrokerPods(ctx, subject.Namespace); err != nil {
+				log.Warn().Err(err).Msgf("Failed to list the pods recorded in namespace %s, only using the targeted replicas", subject.Namespace)
+			}
+			brokerPods[subject.Namespace] = pods
+		}
+
+		selector := labels.SelectorFromSet(subject.Owner.Selector)
+		for _, pod := range pods {
+			if slices.Contains(subject.PodNames, pod.Name) || !selector.Matches(labels.Set(pod.Labels)) {
+				continue
+			}
+			podSysCalls, err := api.GetPodSysCall(pod.Name, since)
+			if err != nil {
+				log.Debug().Err(err).Msgf("Error retrieving %s pod syscall", pod.Name)
+				continue
+			}
+			log.Debug().Msgf("Adding the syscalls of replica %s to %s %s/%s", pod.Name, subject.Owner.Kind, subject.Namespace, subject.Name)
+			subject.PodNames = append(subject.PodNames, pod.Name)
+			subject.Observations = append(subject.Observations, podSysCalls)
+		}
+	}
+}
+
+// seccompSubjectContainer names a container of the subject in log messages
+func seccompSubjectContainer(subject seccompSubject, container string) string {
+	if container == "" {
+		return fmt.Sprintf("%s/%s", subject.Namespace, subject.Name)
+	}
+	return fmt.Sprintf("container %s of %s/%s", container, subject.Namespace, subject.Name)
+}
+
+// handleSeccompProfileOutput writes the profile in the requested format, applying SeccompProfile
+// resources unless in dry run mode
+func handleSeccompProfileOutput(config *Config, subject seccompSubject, containerProfile containerSeccompProfile, opts ProfileOptions) error {
+	if opts.Format == SeccompFormatSPO {
+		return handleSPOSeccompProfile(context.TODO(), config, subject, containerProfile, opts)
+	}
+
+	filename, err := writeSeccompProfile(opts.OutputDir, seccompProfileName(subject.Namespace, subject.Name, containerProfile.Container), containerProfile.Profile)
+	if err != nil {
+		return err
+	}
+	log.Info().Msgf("Generated seccomp profile for %s: %s", seccompSubjectContainer(subject, containerProfile.Container), filename)
+	return nil
+}
+
+// buildSeccompProfiles creates a profile per container with recorded syscalls, and a pod-level
+// profile allowing the union when requested or when the broker did not record containers. The
+// observations of all pods of the subject are merged, for every architecture they ran on, and
+// the baseline is added to every profile.
+func buildSeccompProfiles(subject seccompSubject, opts ProfileOptions) []containerSeccompProfile {
+	var profiles []containerSeccompProfile
+
+	var arches, podSyscalls []string
+	containerSyscalls := make(map[string][]string)
+	for _, observation := range subject.Observations {
+		if arch, err := normalizeArch(observation.Arch); err != nil {
+			log.Warn().Err(err).Msgf("Syscalls of %s/%s were recorded on an unknown architecture", subject.Namespace, subject.Name)
+		} else if !slices.Contains(arches, arch) {
+			arches = append(arches, arch)
+		}
+		podSyscalls = MergeSyscalls(podSyscalls, observation.Syscalls)
+		for container, syscalls := range observation.Containers {
+			containerSyscalls[container] = MergeSyscalls(containerSyscalls[container], syscalls)
+		}
+	}
+
+	containers := make([]string, 0, len(containerSyscalls))
+	for container := range containerSyscalls {
+		containers = append(containers, container)
+	}
+	sort.Strings(containers)
+
+	for _, container := range containers {
+		syscalls := containerSyscalls[container]
+		if len(syscalls) == 0 {
+			log.Warn().Msgf("No syscalls recorded for %s, skipping", seccompSubjectContainer(subject, container))
 			continue
 		}
+		podSyscalls = MergeSyscalls(podSyscalls, syscalls)
+		profiles = append(profiles, containerSeccompProfile{
+			Container: container,
+			Profile:   newSeccompProfile(MergeSyscalls(syscalls, baselineSyscalls(opts.Baseline)), arches, opts),
+		})
+	}
 
-		log.Info().Msgf("Generated seccomp profile for pod %s: %s", pod.Name, filename)
+	if len(profiles) > 0 && !opts.PodUnion {
+		return profiles
+	}
+	if len(profiles) == 0 {
+		log.Debug().Msgf("No per-container syscalls recorded for %s/%s, generating a pod-level profile", subject.Namespace, subject.Name)
 	}
+
+	if len(podSyscalls) == 0 {
+		log.Warn().Msgf("No syscalls recorded for %s/%s, skipping", subject.Namespace, subject.Name)
+		return profiles
+	}
+	return append(profiles, containerSeccompProfile{
+		Profile: newSeccompProfile(MergeSyscalls(podSyscalls, baselineSyscalls(opts.Baseline)), arches, opts),
+	})
 }
 
-// ValidateProfile checks if the generated profile is valid
-func ValidateProfile(profile SeccompProfile) error {
-	if profile.DefaultAction == "" {
-		return fmt.Errorf("default action is required")
+// newSeccompProfile creates a profile allowing the syscalls on the architectures, given by their Go
+// name. Syscalls missing on some of the architectures are only allowed where they exist, and
+// high-risk syscalls are restricted by their arguments when enabled.
+func newSeccompProfile(syscalls []string, arches []string, opts ProfileOptions) SeccompProfile {
+	profile := SeccompProfile{
+		DefaultAction: opts.DefaultAction,
+		Syscalls:      allowRules(syscalls, arches),
+	}
+	if opts.ArgFilters {
+		profile.Syscalls = applyArgFilters(profile.Syscalls)
 	}
+	if len(opts.Architectures) > 0 {
+		profile.Architectures = opts.Architectures
+	} else {
+		profile.ArchMap = archMapFor(arches)
+	}
+	return profile
+}
 
-	if len(profile.Architectures) == 0 {
-		return fmt.Errorf("at least one architecture must be specified")
+// seccompProfileName returns the profile file name, <ns>-<name>-<container>-seccomp.json for a
+// container and <ns>-<name>-seccomp.json for the pod, named after its workload or the bare pod
+func seccompProfileName(namespace, name, container string) string {
+	if container == "" {
+		return fmt.Sprintf("%s-%s-seccomp.json", namespace, name)
 	}
+	return fmt.Sprintf("%s-%s-%s-seccomp.json", namespace, name, container)
+}
 
-	if len(profile.Syscalls) == 0 {
-		return fmt.Errorf("at least one syscall rule must be specified")
+// writeSeccompProfile writes the profile JSON to the output directory
+func writeSeccompProfile(outputDir, name string, profile SeccompProfile) (string, error) {
+	profileJSON, err := json.MarshalIndent(profile, "", "    ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal profile: %w", err)
 	}
 
-	return nil
+	filename := filepath.Join(outputDir, name)
+	if err := os.WriteFile(filename, profileJSON, 0644); err != nil {
+		return "", err
+	}
+	return filename, nil
 }
 
-// Helper function to merge multiple syscall lists
+// MergeSyscalls returns the union of the syscall lists, in no particular order
 func MergeSyscalls(syscallLists ...[]string) []string {
 	syscallMap := make(map[string]struct{})
 
@@ -0,0 +1,194 @@
+package k8s
+
+import (
+	"bufio"
+	"embed"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// syscallTables holds the syscall names of every supported architecture, one per line
+//
+//go:embed syscalls/*.txt
+var syscallTables embed.FS
+
+// seccompArch describes how an architecture is expressed in seccomp profiles
+type seccompArch struct {
+	// Architecture is the native seccomp architecture
+	Architecture string
+	// SubArchitectures are the compat ABIs the kernel runs on the native architecture
+	SubArchitectures []string
+}
+
+// seccompArches maps the supported architectures, by their Go name, to seccomp architectures
+var seccompArches = map[string]seccompArch{
+	"amd64": {Architecture: "SCMP_ARCH_X86_64", SubArchitectures: []string{"SCMP_ARCH_X86", "SCMP_ARCH_X32"}},
+	"arm64": {Architecture: "SCMP_ARCH_AARCH64", SubArchitectures: []string{"SCMP_ARCH_ARM"}},
+}
+
+// syscallTableArches are the architectures with an embedded syscall table, by their Go name. The
+// 386 and arm tables cover the compat sub-architectures.
+var syscallTableArches = []string{"386", "amd64", "arm", "arm64"}
+
+// scmpArchTables maps seccomp architectures to the syscall table of their names
+var scmpArchTables = map[string]string{
+	"SCMP_ARCH_X86":     "386",
+	"SCMP_ARCH_X86_64":  "amd64",
+	"SCMP_ARCH_X32":     "amd64",
+	"SCMP_ARCH_ARM":     "arm",
+	"SCMP_ARCH_AARCH64": "arm64",
+}
+
+// normalizeArch maps the architecture reported by the broker, in uname or Go style, to its Go name
+func normalizeArch(arch string) (string, error) {
+	switch strings.ToLower(arch) {
+	case "x86_64", "x86-64", "amd64":
+		return "amd64", nil
+	case "aarch64", "arm64":
+		return "arm64", nil
+	default:
+		return "", fmt.Errorf("%w: unsupported architecture %q", ErrInvalidInput, arch)
+	}
+}
+
+// loadSyscallTables reads the embedded syscall tables once
+var loadSyscallTables = sync.OnceValue(func() map[string]map[string]bool {
+	tables := make(map[string]map[string]bool, len(syscallTableArches))
+	for _, arch := range syscallTableArches {
+		names, err := readSyscallNames(syscallTables, fmt.Sprintf("syscalls/%s.txt", arch))
+		if err != nil {
+			panic(fmt.Sprintf("missing embedded syscall table for %s: %v", arch, err))
+		}
+
+		table := make(map[string]bool, len(names))
+		for _, name := range names {
+			table[name] = true
+		}
+		tables[arch] = table
+	}
+	return tables
+})
+
+// readSyscallNames reads an embedded list of syscall names, one per line, skipping # comments
+func readSyscallNames(fsys embed.FS, name string) ([]string, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var names []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			names = append(names, line)
+		}
+	}
+	return names, scanner.Err()
+}
+
+// syscallExists reports whether the syscall is defined on the architecture
+func syscallExists(arch, name string) bool {
+	return loadSyscallTables()[arch][name]
+}
+
+// archMapFor returns the archMap entries of the architectures, in a stable order
+func archMapFor(arches []string) []ArchMap {
+	sorted := slices.Clone(arches)
+	sort.Strings(sorted)
+
+	archMap := make([]ArchMap, 0, len(sorted))
+	for _, arch := range sorted {
+		archMap = append(archMap, ArchMap{
+			Architecture:     seccompArches[arch].Architecture,
+			SubArchitectures: seccompArches[arch].SubArchitectures,
+		})
+	}
+	return archMap
+}
+
+// allowRules splits the syscalls into allow rules for the architectures they exist on. Syscalls
+// defined on every architecture, or unknown to all tables, share an unrestricted rule; the others
+// are limited to their architectures with includes, so e.g. open is not allowed on arm64.
+func allowRules(syscalls []string, arches []string) []Rule {
+	sortedArches := slices.Clone(arches)
+	sort.Strings(sortedArches)
+
+	namesByArches := make(map[string][]string)
+	for _, name := range syscalls {
+		var existsOn []string
+		for _, arch := range sortedArches {
+			if syscallExists(arch, name) {
+				existsOn = append(existsOn, arch)
+			}
+		}
+		if len(existsOn) == len(sortedArches) || len(existsOn) == 0 {
+			existsOn = nil
+		}
+		key := strings.Join(existsOn, ",")
+		namesByArches[key] = append(namesByArches[key], name)
+	}
+
+	keys := make([]string, 0, len(namesByArches))
+	for key := range namesByArches {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	rules := make([]Rule, 0, len(keys))
+	for _, key := range keys {
+		names := namesByArches[key]
+		sort.Strings(names)
+		rule := Rule{Names: names, Action: "SCMP_ACT_ALLOW"}
+		if key != "" {
+			rule.Includes = &RuleFilter{Arches: strings.Split(key, ",")}
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// flattenArchitectures lists the native and sub-architectures of the profile, for consumers such
+// as the Security Profiles Operator that do not support archMap
+func flattenArchitectures(profile SeccompProfile) []string {
+	if len(profile.ArchMap) == 0 {
+		return profile.Architectures
+	}
+	var architectures []string
+	for _, entry := range profile.ArchMap {
+		architectures = append(architectures, entry.Architecture)
+		architectures = append(architectures, entry.SubArchitectures...)
+	}
+	return architectures
+}
+
+// flattenRules merges rules that only differ in their architecture includes, for consumers that
+// do not support includes. Runtimes skip syscall names unknown on their architecture. Rules with
+// argument filters or an errno are kept as they are.
+func flattenRules(rules []Rule) []Rule {
+	var flattened []Rule
+	for _, rule := range rules {
+		rule.Includes = nil
+		if len(rule.Args) > 0 || rule.ErrnoRet != nil {
+			flattened = append(flattened, rule)
+			continue
+		}
+
+		index := slices.IndexFunc(flattened, func(existing Rule) bool {
+			return existing.Action == rule.Action && len(existing.Args) == 0 && existing.ErrnoRet == nil
+		})
+		if index < 0 {
+			flattened = append(flattened, Rule{Names: slices.Clone(rule.Names), Action: rule.Action})
+			continue
+		}
+		flattened[index].Names = append(flattened[index].Names, rule.Names...)
+	}
+	for i := range flattened {
+		sort.Strings(flattened[i].Names)
+		flattened[i].Names = slices.Compact(flattened[i].Names)
+	}
+	return flattened
+}
@@ -0,0 +1,40 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeArch(t *testing.T) {
+	for input, expected := range map[string]string{"x86_64": "amd64", "AMD64": "amd64", "aarch64": "arm64", "ARM64": "arm64"} {
+		arch, err := normalizeArch(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, expected, arch, input)
+	}
+
+	_, err := normalizeArch("s390x")
+	assert.ErrorIs(t, err, ErrInvalidInput)
+}
+
+func TestSyscallExists(t *testing.T) {
+	assert.True(t, syscallExists("amd64", "open"))
+	assert.False(t, syscallExists("arm64", "open"))
+	assert.True(t, syscallExists("arm64", "openat"))
+	assert.False(t, syscallExists("amd64", "# Linux amd64 syscall names"))
+	assert.False(t, syscallExists("riscv64", "read"))
+}
+
+func TestAllowRules(t *testing.T) {
+	// A single architecture never needs includes
+	assert.Equal(t, []Rule{{Names: []string{"open", "read"}, Action: "SCMP_ACT_ALLOW"}}, allowRules([]string{"read", "open"}, []string{"amd64"}))
+
+	// Names unknown to every table are kept in the common rule
+	rules := allowRules([]string{"read", "open", "custom_call"}, []string{"arm64", "amd64"})
+	assert.Equal(t, []Rule{
+		{Names: []string{"custom_call", "read"}, Action: "SCMP_ACT_ALLOW"},
+		{Names: []string{"open"}, Action: "SCMP_ACT_ALLOW", Includes: &RuleFilter{Arches: []string{"amd64"}}},
+	}, rules)
+	assert.Equal(t, []Rule{{Names: []string{"custom_call", "open", "read"}, Action: "SCMP_ACT_ALLOW"}}, flattenRules(rules))
+}
@@ -0,0 +1,109 @@
+package k8s
+
+import (
+	"slices"
+	"sort"
+)
+
+// Seccomp argument comparison operators
+const (
+	opEqualTo     = "SCMP_CMP_EQ"
+	opNotEqual    = "SCMP_CMP_NE"
+	opMaskedEqual = "SCMP_CMP_MASKED_EQ"
+)
+
+const (
+	// cloneNamespaceFlags are the CLONE_NEW* flags creating namespaces: CLONE_NEWNS, CLONE_NEWCGROUP,
+	// CLONE_NEWUTS, CLONE_NEWIPC, CLONE_NEWUSER, CLONE_NEWPID and CLONE_NEWNET
+	cloneNamespaceFlags = 0x7E020000
+	// errnoENOSYS makes callers fall back to an older syscall, as for an unknown syscall
+	errnoENOSYS = 38
+	// ioctlTIOCSTI injects input into the controlling terminal
+	ioctlTIOCSTI = 0x5412
+)
+
+// Socket address families allowed by the socket template
+const (
+	afUnix    = 1
+	afInet    = 2
+	afInet6   = 10
+	afNetlink = 16
+)
+
+// argFilterTemplates are the conservative rules replacing the plain allow rule of high-risk
+// syscalls, mirroring containerd's RuntimeDefault. Rules for the same syscall are alternatives,
+// while the args of one rule must all match.
+var argFilterTemplates = map[string][]Rule{
+	// Threads and processes, but no new namespaces
+	"clone": {
+		{Action: "SCMP_ACT_ALLOW", Args: []Arg{{Index: 0, Value: cloneNamespaceFlags, ValueTwo: 0, Op: opMaskedEqual}}},
+	},
+	// clone3 passes its flags in a struct seccomp cannot inspect, so callers fall back to clone
+	"clone3": {
+		{Action: "SCMP_ACT_ERRNO", ErrnoRet: errnoRet(errnoENOSYS)},
+	},
+	// PER_LINUX, PER_LINUX32, UNAME26, UNAME26|PER_LINUX32 and querying the persona
+	"personality": {
+		{Action: "SCMP_ACT_ALLOW", Args: []Arg{{Index: 0, Value: 0x0, Op: opEqualTo}}},
+		{Action: "SCMP_ACT_ALLOW", Args: []Arg{{Index: 0, Value: 0x8, Op: opEqualTo}}},
+		{Action: "SCMP_ACT_ALLOW", Args: []Arg{{Index: 0, Value: 0x20000, Op: opEqualTo}}},
+		{Action: "SCMP_ACT_ALLOW", Args: []Arg{{Index: 0, Value: 0x20008, Op: opEqualTo}}},
+		{Action: "SCMP_ACT_ALLOW", Args: []Arg{{Index: 0, Value: 0xffffffff, Op: opEqualTo}}},
+	},
+	// Unix, IPv4, IPv6 and netlink sockets only. Resolvers and runtimes such as glibc's getaddrinfo
+	// and Go's net package list the interface addresses over netlink.
+	"socket": {
+		{Action: "SCMP_ACT_ALLOW", Args: []Arg{{Index: 0, Value: afUnix, Op: opEqualTo}}},
+		{Action: "SCMP_ACT_ALLOW", Args: []Arg{{Index: 0, Value: afInet, Op: opEqualTo}}},
+		{Action: "SCMP_ACT_ALLOW", Args: []Arg{{Index: 0, Value: afInet6, Op: opEqualTo}}},
+		{Action: "SCMP_ACT_ALLOW", Args: []Arg{{Index: 0, Value: afNetlink, Op: opEqualTo}}},
+	},
+	// Everything but TIOCSTI. The kernel truncates the request to 32 bits while seccomp compares
+	// 64 bits, so this only stops callers that do not set the upper bits.
+	"ioctl": {
+		{Action: "SCMP_ACT_ALLOW", Args: []Arg{{Index: 1, Value: ioctlTIOCSTI, Op: opNotEqual}}},
+	},
+}
+
+// errnoRet returns a pointer to the errno, for rules returning an error
+func errnoRet(errno uint) *uint {
+	return &errno
+}
+
+// applyArgFilters moves the templated syscalls out of the plain allow rules into their argument
+// filter rules, which are appended in name order
+func applyArgFilters(rules []Rule) []Rule {
+	var filtered []Rule
+	var templated []string
+
+	for _, rule := range rules {
+		if rule.Action != "SCMP_ACT_ALLOW" || len(rule.Args) > 0 {
+			filtered = append(filtered, rule)
+			continue
+		}
+
+		var names []string
+		for _, name := range rule.Names {
+			if _, ok := argFilterTemplates[name]; ok {
+				templated = append(templated, name)
+				continue
+			}
+			names = append(names, name)
+		}
+		if len(names) > 0 {
+			rule.Names = names
+			filtered = append(filtered, rule)
+		}
+	}
+
+	sort.Strings(templated)
+	for _, name := range slices.Compact(templated) {
+		for _, template := range argFilterTemplates[name] {
+			rule := template
+			rule.Names = []string{name}
+			rule.Args = slices.Clone(template.Args)
+			filtered = append(filtered, rule)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,59 @@
+package k8s
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyArgFilters(t *testing.T) {
+	opts := DefaultProfileOptions()
+	opts.ArgFilters = true
+
+	profile := newSeccompProfile([]string{"read", "socket", "clone", "clone3", "open"}, []string{"amd64", "arm64"}, opts)
+	require.Len(t, profile.Syscalls, 8)
+	assert.Equal(t, Rule{Names: []string{"read"}, Action: "SCMP_ACT_ALLOW"}, profile.Syscalls[0])
+	assert.Equal(t, []string{"open"}, profile.Syscalls[1].Names)
+	assert.NotNil(t, profile.Syscalls[1].Includes)
+
+	assert.Equal(t, []string{"clone"}, profile.Syscalls[2].Names)
+	assert.Equal(t, []Arg{{Index: 0, Value: cloneNamespaceFlags, Op: "SCMP_CMP_MASKED_EQ"}}, profile.Syscalls[2].Args)
+	assert.Equal(t, []string{"clone3"}, profile.Syscalls[3].Names)
+	assert.Equal(t, "SCMP_ACT_ERRNO", profile.Syscalls[3].Action)
+	require.NotNil(t, profile.Syscalls[3].ErrnoRet)
+	assert.Equal(t, uint(38), *profile.Syscalls[3].ErrnoRet)
+	var families []uint64
+	for _, rule := range profile.Syscalls[4:] {
+		assert.Equal(t, []string{"socket"}, rule.Names)
+		assert.Equal(t, "SCMP_CMP_EQ", rule.Args[0].Op)
+		families = append(families, rule.Args[0].Value)
+	}
+	assert.Equal(t, []uint64{afUnix, afInet, afInet6, afNetlink}, families)
+
+	// Templates only apply to observed syscalls
+	plain := applyArgFilters([]Rule{{Names: []string{"read"}, Action: "SCMP_ACT_ALLOW"}})
+	assert.Equal(t, []Rule{{Names: []string{"read"}, Action: "SCMP_ACT_ALLOW"}}, plain)
+
+	// Without the option syscalls are allowed by name
+	opts.ArgFilters = false
+	assert.Len(t, newSeccompProfile([]string{"read", "socket"}, []string{"amd64"}, opts).Syscalls, 1)
+}
+
+func TestArgFilterJSON(t *testing.T) {
+	data, err := json.Marshal(argFilterTemplates["clone"][0])
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"names":null,"action":"SCMP_ACT_ALLOW","args":[{"index":0,"value":2114060288,"op":"SCMP_CMP_MASKED_EQ"}]}`, string(data))
+}
+
+func TestFlattenRules_KeepsArgFilters(t *testing.T) {
+	opts := DefaultProfileOptions()
+	opts.ArgFilters = true
+	profile := newSeccompProfile([]string{"read", "open", "ioctl"}, []string{"amd64", "arm64"}, opts)
+
+	assert.Equal(t, []Rule{
+		{Names: []string{"open", "read"}, Action: "SCMP_ACT_ALLOW"},
+		{Names: []string{"ioctl"}, Action: "SCMP_ACT_ALLOW", Args: []Arg{{Index: 1, Value: ioctlTIOCSTI, Op: "SCMP_CMP_NE"}}},
+	}, flattenRules(profile.Syscalls))
+}
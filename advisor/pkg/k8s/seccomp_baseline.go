@@ -0,0 +1,54 @@
+package k8s
+
+import (
+	"embed"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// seccompBaselines holds the embedded baseline syscall lists
+//
+//go:embed baselines/*.txt
+var seccompBaselines embed.FS
+
+// SeccompBaseline is a set of syscalls added to every generated profile, covering code paths such
+// as signal handling that are rarely exercised while recording
+type SeccompBaseline string
+
+const (
+	// SeccompBaselineNone only allows the recorded syscalls
+	SeccompBaselineNone SeccompBaseline = "none"
+	// SeccompBaselineRuntimeDefault adds the syscalls containerd's RuntimeDefault profile allows
+	// unconditionally
+	SeccompBaselineRuntimeDefault SeccompBaseline = "runtime-default"
+)
+
+// ParseSeccompBaseline converts a baseline flag value to a SeccompBaseline
+func ParseSeccompBaseline(baseline string) (SeccompBaseline, error) {
+	switch SeccompBaseline(strings.ToLower(baseline)) {
+	case SeccompBaselineNone, "":
+		return SeccompBaselineNone, nil
+	case SeccompBaselineRuntimeDefault:
+		return SeccompBaselineRuntimeDefault, nil
+	default:
+		return "", fmt.Errorf("%w: unknown seccomp baseline %q, expected none or runtime-default", ErrInvalidInput, baseline)
+	}
+}
+
+// loadRuntimeDefaultBaseline reads the embedded RuntimeDefault list once
+var loadRuntimeDefaultBaseline = sync.OnceValue(func() []string {
+	names, err := readSyscallNames(seccompBaselines, "baselines/runtime-default.txt")
+	if err != nil {
+		panic(fmt.Sprintf("missing embedded RuntimeDefault baseline: %v", err))
+	}
+	return names
+})
+
+// baselineSyscalls returns the syscalls of the baseline
+func baselineSyscalls(baseline SeccompBaseline) []string {
+	if baseline == SeccompBaselineRuntimeDefault {
+		return loadRuntimeDefaultBaseline()
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// SeccompDiff compares a newly generated profile with the existing one
+type SeccompDiff struct {
+	Namespace string
+	Name      string
+	Container string
+	// Source is the file or resource the existing profile was read from
+	Source string
+	// Missing is set when there is no existing profile
+	Missing bool
+	// Added are syscalls the new profile allows and the existing one does not
+	Added []string
+	// Removed are syscalls the existing profile allows and the new one does not
+	Removed []string
+	// OldDefaultAction and NewDefaultAction are set when the default action changes
+	OldDefaultAction string
+	NewDefaultAction string
+}
+
+// DiffSeccomp generates the profiles of the targeted pods and compares them with the raw profiles in
+// existingDir, or with the SeccompProfile resources in the cluster for the spo format. Profiles staged
+// by gen seccomp --stage, as recorded in the rollout state in existingDir, are compared with the
+// default action of their rollout. It reports whether any profile would change.
+func DiffSeccomp(options GenerateOptions, config *Config, profileOpts ProfileOptions, existingDir string, out io.Writer) (bool, error) {
+	pods, err := GetResource(options, config)
+	if err != nil {
+		return false, err
+	}
+
+	subjects := collectSeccompSubjects(context.TODO(), config, pods, profileOpts.FirstRecordedWithin)
+	if len(subjects) == 0 {
+		return false, fmt.Errorf("no syscalls recorded for the targeted pods")
+	}
+
+	stageState, err := loadStageState(existingDir)
+	if err != nil {
+		return false, fmt.Errorf("failed to load the staged rollout state: %w", err)
+	}
+
+	changed := false
+	for _, subject := range subjects {
+		for _, containerProfile := range buildSeccompProfiles(subject, profileOpts) {
+			// Compare the profile gen seccomp would write
+			containerProfile.Profile = checkSeccompSyscalls(subject, containerProfile)
+			if err := ValidateProfile(containerProfile.Profile); err != nil {
+				return changed, fmt.Errorf("invalid seccomp profile for %s: %w", seccompSubjectContainer(subject, containerProfile.Container), err)
+			}
+			if action, staged := stageState.stagedAction(seccompProfileName(subject.Namespace, subject.Name, containerProfile.Container), profileOpts); staged {
+				containerProfile.Profile.DefaultAction = action
+			}
+			existing, source, err := existingSeccompProfile(context.TODO(), config, subject, containerProfile.Container, profileOpts, existingDir)
+			if err != nil {
+				return changed, err
+			}
+			diff := diffSeccompProfiles(existing, containerProfile.Profile)
+			diff.Namespace, diff.Name, diff.Container, diff.Source = subject.Namespace, subject.Name, containerProfile.Container, source
+			writeSeccompDiff(out, diff)
+			changed = changed || diff.Changed()
+		}
+	}
+	return changed, nil
+}
+
+// existingSeccompProfile loads the profile the generated one would replace, nil when there is none
+func existingSeccompProfile(ctx context.Context, config *Config, subject seccompSubject, container string, opts ProfileOptions, existingDir string) (*SeccompProfile, string, error) {
+	if opts.Format == SeccompFormatSPO {
+		name := spoProfileName(subject.Name, container)
+		source := fmt.Sprintf("SeccompProfile %s/%s", subject.Namespace, name)
+		if config == nil || config.DynamicClient == nil {
+			return nil, source, fmt.Errorf("no dynamic client available to read %s", source)
+		}
+
+		obj, err := config.DynamicClient.Resource(spoSeccompProfileResource).Namespace(subject.Namespace).Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil, source, nil
+		}
+		if err != nil {
+			return nil, source, fmt.Errorf("failed to get %s: %w", source, err)
+		}
+		var spoProfile SPOSeccompProfile
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &spoProfile); err != nil {
+			return nil, source, fmt.Errorf("failed to convert %s: %w", source, err)
+		}
+		return &SeccompProfile{
+			DefaultAction: spoProfile.Spec.DefaultAction,
+			Architectures: spoProfile.Spec.Architectures,
+			Syscalls:      spoProfile.Spec.Syscalls,
+		}, source, nil
+	}
+
+	source := filepath.Join(existingDir, seccompProfileName(subject.Namespace, subject.Name, container))
+	data, err := os.ReadFile(source)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, source, nil
+	}
+	if err != nil {
+		return nil, source, err
+	}
+	var profile SeccompProfile
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return nil, source, fmt.Errorf("invalid seccomp profile %s: %w", source, err)
+	}
+	return &profile, source, nil
+}
+
+// Changed reports whether the generated profile differs from the existing one
+func (d SeccompDiff) Changed() bool {
+	return d.Missing || len(d.Added) > 0 || len(d.Removed) > 0 || d.NewDefaultAction != ""
+}
+
+// diffSeccompProfiles compares the syscalls both profiles allow
+func diffSeccompProfiles(existing *SeccompProfile, generated SeccompProfile) SeccompDiff {
+	if existing == nil {
+		return SeccompDiff{Missing: true, Added: allowedSyscalls(generated)}
+	}
+
+	diff := SeccompDiff{
+		Added:   syscallDelta(allowedSyscalls(generated), allowedSyscalls(*existing)),
+		Removed: syscallDelta(allowedSyscalls(*existing), allowedSyscalls(generated)),
+	}
+	if existing.DefaultAction != generated.DefaultAction {
+		diff.OldDefaultAction, diff.NewDefaultAction = existing.DefaultAction, generated.DefaultAction
+	}
+	return diff
+}
+
+// allowedSyscalls returns the sorted names of the syscalls the profile allows, with or without
+// argument filters
+func allowedSyscalls(profile SeccompProfile) []string {
+	var names []string
+	for _, rule := range profile.Syscalls {
+		if rule.Action == "SCMP_ACT_ALLOW" {
+			names = append(names, rule.Names...)
+		}
+	}
+	sort.Strings(names)
+	return slices.Compact(names)
+}
+
+// syscallDelta returns the syscalls in a that are not in b
+func syscallDelta(a, b []string) []string {
+	var delta []string
+	for _, name := range a {
+		if !slices.Contains(b, name) {
+			delta = append(delta, name)
+		}
+	}
+	return delta
+}
+
+// writeSeccompDiff prints the diff of one profile, + for added and - for removed syscalls
+func writeSeccompDiff(out io.Writer, diff SeccompDiff) {
+	fmt.Fprintf(out, "%s (%s)\n", seccompSubjectContainer(seccompSubject{Namespace: diff.Namespace, Name: diff.Name}, diff.Container), diff.Source)
+	if diff.Missing {
+		fmt.Fprintf(out, "  no existing profile, %d syscalls would be allowed\n", len(diff.Added))
+		return
+	}
+	if diff.NewDefaultAction != "" {
+		fmt.Fprintf(out, "  defaultAction: %s -> %s\n", diff.OldDefaultAction, diff.NewDefaultAction)
+	}
+	for _, name := range diff.Added {
+		fmt.Fprintf(out, "  + %s\n", name)
+	}
+	for _, name := range diff.Removed {
+		fmt.Fprintf(out, "  - %s\n", name)
+	}
+	if !diff.Changed() {
+		fmt.Fprintln(out, "  no changes")
+	}
+}
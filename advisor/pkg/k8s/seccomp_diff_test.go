@@ -0,0 +1,151 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xentra-ai/advisor/pkg/api"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestDiffSeccompProfiles(t *testing.T) {
+	opts := DefaultProfileOptions()
+	existing := newSeccompProfile([]string{"read", "write", "poll"}, []string{"amd64"}, opts)
+	existing.DefaultAction = "SCMP_ACT_LOG"
+	generated := newSeccompProfile([]string{"read", "write", "openat"}, []string{"amd64"}, opts)
+
+	diff := diffSeccompProfiles(&existing, generated)
+	assert.Equal(t, []string{"openat"}, diff.Added)
+	assert.Equal(t, []string{"poll"}, diff.Removed)
+	assert.Equal(t, "SCMP_ACT_LOG", diff.OldDefaultAction)
+	assert.Equal(t, "SCMP_ACT_ERRNO", diff.NewDefaultAction)
+
+	missing := diffSeccompProfiles(nil, generated)
+	assert.True(t, missing.Missing)
+	assert.Equal(t, []string{"openat", "read", "write"}, missing.Added)
+
+	var out bytes.Buffer
+	diff.Namespace, diff.Name, diff.Container, diff.Source = "shop", "api", "app", "profiles/shop-api-app-seccomp.json"
+	writeSeccompDiff(&out, diff)
+	assert.Equal(t, "container app of shop/api (profiles/shop-api-app-seccomp.json)\n"+
+		"  defaultAction: SCMP_ACT_LOG -> SCMP_ACT_ERRNO\n"+
+		"  + openat\n"+
+		"  - poll\n", out.String())
+}
+
+func TestExistingSeccompProfile(t *testing.T) {
+	subject := seccompSubject{Name: "api", Namespace: "shop"}
+	opts := DefaultProfileOptions()
+	dir := t.TempDir()
+
+	profile, source, err := existingSeccompProfile(context.Background(), nil, subject, "app", opts, dir)
+	require.NoError(t, err)
+	assert.Nil(t, profile)
+	assert.Equal(t, filepath.Join(dir, "shop-api-app-seccomp.json"), source)
+
+	_, err = writeSeccompProfile(dir, "shop-api-app-seccomp.json", newSeccompProfile([]string{"read"}, []string{"amd64"}, opts))
+	require.NoError(t, err)
+	profile, _, err = existingSeccompProfile(context.Background(), nil, subject, "app", opts, dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"read"}, allowedSyscalls(*profile))
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "shop-api-seccomp.json"), []byte("not json"), 0644))
+	_, _, err = existingSeccompProfile(context.Background(), nil, subject, "", opts, dir)
+	assert.Error(t, err)
+
+	t.Run("spo", func(t *testing.T) {
+		config := &Config{
+			DynamicClient: dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+				map[schema.GroupVersionResource]string{spoSeccompProfileResource: "SeccompProfileList"}),
+		}
+		opts.Format = SeccompFormatSPO
+
+		profile, source, err := existingSeccompProfile(context.Background(), config, subject, "app", opts, dir)
+		require.NoError(t, err)
+		assert.Nil(t, profile)
+		assert.Equal(t, "SeccompProfile shop/api-app", source)
+
+		require.NoError(t, applySPOSeccompProfile(context.Background(), config, newSPOSeccompProfile(subject, testContainerSeccompProfile("app"))))
+		profile, _, err = existingSeccompProfile(context.Background(), config, subject, "app", opts, dir)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"open", "read", "write"}, allowedSyscalls(*profile))
+	})
+}
+
+func TestDiffSeccomp(t *testing.T) {
+	origGetPodFunc := getPodFunc
+	origGetPodSysCallFunc := api.GetPodSysCallFunc
+	defer func() {
+		getPodFunc = origGetPodFunc
+		api.GetPodSysCallFunc = origGetPodSysCallFunc
+	}()
+
+	getPodFunc = func(ctx context.Context, cfg *Config, ns, name string) (*corev1.Pod, error) {
+		return createMockPodForTest(name, ns), nil
+	}
+	observation := api.PodSysCall{Arch: "x86_64", Syscalls: []string{"read", "write"}, Containers: map[string][]string{"app": {"read", "write"}}}
+	api.GetPodSysCallFunc = func(podName string, since time.Time) (api.PodSysCall, error) {
+		return observation, nil
+	}
+
+	options := GenerateOptions{Mode: SinglePod, PodName: "web-0", Namespace: "shop"}
+	opts := DefaultProfileOptions()
+	dir := t.TempDir()
+
+	var out bytes.Buffer
+	changed, err := DiffSeccomp(options, &Config{}, opts, dir, &out)
+	require.NoError(t, err)
+	assert.True(t, changed, "a missing profile is a change")
+	assert.Contains(t, out.String(), "no existing profile")
+
+	// The profile gen seccomp writes compares as unchanged
+	profiles := buildSeccompProfiles(seccompSubject{Name: "web-0", Namespace: "shop", Observations: []api.PodSysCall{observation}}, opts)
+	require.Len(t, profiles, 1)
+	_, err = writeSeccompProfile(dir, "shop-web-0-app-seccomp.json", profiles[0].Profile)
+	require.NoError(t, err)
+
+	out.Reset()
+	changed, err = DiffSeccomp(options, &Config{}, opts, dir, &out)
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Contains(t, out.String(), "no changes")
+
+	// A profile staged by gen seccomp --stage logs unlisted syscalls until it is promoted
+	staged := profiles[0].Profile
+	staged.DefaultAction = seccompActionLog
+	_, err = writeSeccompProfile(dir, "shop-web-0-app-seccomp.json", staged)
+	require.NoError(t, err)
+	state := &seccompStageState{Profiles: map[string]stagedProfile{
+		"shop-web-0-app-seccomp.json": {Syscalls: allowedSyscalls(staged)},
+	}}
+	require.NoError(t, state.save(dir))
+
+	out.Reset()
+	changed, err = DiffSeccomp(options, &Config{}, opts, dir, &out)
+	require.NoError(t, err)
+	assert.False(t, changed, "the staged default action is not a change")
+	assert.NotContains(t, out.String(), "defaultAction")
+
+	state.Profiles["shop-web-0-app-seccomp.json"] = stagedProfile{Syscalls: allowedSyscalls(staged), Promoted: true}
+	require.NoError(t, state.save(dir))
+	out.Reset()
+	changed, err = DiffSeccomp(options, &Config{}, opts, dir, &out)
+	require.NoError(t, err)
+	assert.True(t, changed, "a promoted profile still logging is a change")
+	assert.Contains(t, out.String(), "defaultAction: SCMP_ACT_LOG -> SCMP_ACT_ERRNO")
+
+	api.GetPodSysCallFunc = func(podName string, since time.Time) (api.PodSysCall, error) {
+		return api.PodSysCall{}, assert.AnError
+	}
+	_, err = DiffSeccomp(options, &Config{}, opts, dir, &out)
+	assert.Error(t, err, "pods without recorded syscalls cannot be compared")
+}
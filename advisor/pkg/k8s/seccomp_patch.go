@@ -0,0 +1,212 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	log "github.com/rs/zerolog/log"
+	"github.com/xentra-ai/advisor/pkg/common"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/yaml"
+)
+
+// PatchFormat selects how workload patches referencing the generated profiles are written
+type PatchFormat string
+
+const (
+	// PatchFormatStrategic writes strategic-merge patches for kubectl patch --patch-file
+	PatchFormatStrategic PatchFormat = "strategic"
+	// PatchFormatKustomize writes patches identifying their target, for kustomize patches entries
+	PatchFormatKustomize PatchFormat = "kustomize"
+)
+
+// ParsePatchFormat converts a patch format flag value to a PatchFormat
+func ParsePatchFormat(format string) (PatchFormat, error) {
+	switch PatchFormat(strings.ToLower(format)) {
+	case PatchFormatStrategic:
+		return PatchFormatStrategic, nil
+	case PatchFormatKustomize:
+		return PatchFormatKustomize, nil
+	default:
+		return "", fmt.Errorf("%w: unknown patch format %q, expected strategic or kustomize", ErrInvalidInput, format)
+	}
+}
+
+// strategicMergeKinds are the workload kinds the API server accepts strategic-merge patches for.
+// Custom resources such as Argo Rollouts only get a patch file.
+var strategicMergeKinds = []schema.GroupKind{
+	{Group: "apps", Kind: "Deployment"},
+	{Group: "apps", Kind: "StatefulSet"},
+	{Group: "apps", Kind: "DaemonSet"},
+	{Group: "apps", Kind: "ReplicaSet"},
+	{Group: "batch", Kind: "Job"},
+	{Group: "batch", Kind: "CronJob"},
+	{Group: "", Kind: "ReplicationController"},
+}
+
+// handleWorkloadPatch writes a patch setting the localhost seccomp profiles on the workload of the
+// subject, and applies it unless in dry run mode
+func handleWorkloadPatch(ctx context.Context, config *Config, subject seccompSubject, profiles []containerSeccompProfile, opts ProfileOptions) error {
+	if len(profiles) == 0 {
+		return nil
+	}
+
+	owner := subject.Owner
+	if owner == nil {
+		log.Warn().Msgf("Pod %s/%s is not owned by a workload, set its seccompProfile in the pod manifest", subject.Namespace, subject.Name)
+		return nil
+	}
+
+	patch, err := seccompWorkloadPatch(subject, profiles, opts)
+	if err != nil {
+		return err
+	}
+
+	if opts.OutputDir != "" {
+		patchYAML, err := yaml.Marshal(patch)
+		if err != nil {
+			return fmt.Errorf("failed to marshal the patch for %s %s: %w", owner.Kind, owner.Name, err)
+		}
+		resourceType := "seccomp-patch"
+		if opts.PatchFormat == PatchFormatKustomize {
+			resourceType = "seccomp-kustomize-patch"
+		}
+		filename, err := common.SaveToFile(opts.OutputDir, resourceType, owner.Namespace, fmt.Sprintf("%s-%s", strings.ToLower(owner.Kind), owner.Name), patchYAML)
+		if err != nil {
+			return err
+		}
+		log.Info().Msgf("Generated seccomp patch for %s %s/%s: %s", owner.Kind, owner.Namespace, owner.Name, filename)
+	}
+
+	if config.DryRun {
+		log.Info().Msgf("Dry run: Would patch %s %s/%s to use the generated seccomp profiles", owner.Kind, owner.Namespace, owner.Name)
+		return nil
+	}
+	// Pods referencing a Localhost profile missing on their node fail to start
+	if opts.Format == SeccompFormatJSON && !opts.ProfilesInstalled {
+		log.Warn().Msgf("Not patching %s %s/%s, its raw profiles only exist locally; install them on the nodes and pass --profiles-installed, or use --format spo", owner.Kind, owner.Namespace, owner.Name)
+		return nil
+	}
+	return applyWorkloadPatch(ctx, config, subject, profiles, opts)
+}
+
+// seccompWorkloadPatch builds the patch setting the localhost profile of each container, and of
+// the pod for a pod-level profile, in the workload's pod template
+func seccompWorkloadPatch(subject seccompSubject, profiles []containerSeccompProfile, opts ProfileOptions) (map[string]interface{}, error) {
+	owner := subject.Owner
+	podSpec := map[string]interface{}{}
+	var containers, initContainers []interface{}
+
+	for _, profile := range profiles {
+		securityContext := map[string]interface{}{
+			"seccompProfile": map[string]interface{}{
+				"type":             string(corev1.SeccompProfileTypeLocalhost),
+				"localhostProfile": localhostProfilePath(subject, profile.Container, opts),
+			},
+		}
+
+		switch {
+		case profile.Container == "":
+			podSpec["securityContext"] = securityContext
+		case isInitContainer(subject.Pod, profile.Container):
+			initContainers = append(initContainers, map[string]interface{}{"name": profile.Container, "securityContext": securityContext})
+		default:
+			containers = append(containers, map[string]interface{}{"name": profile.Container, "securityContext": securityContext})
+		}
+	}
+	if len(containers) > 0 {
+		podSpec["containers"] = containers
+	}
+	if len(initContainers) > 0 {
+		podSpec["initContainers"] = initContainers
+	}
+
+	patch := map[string]interface{}{}
+	if err := unstructured.SetNestedField(patch, podSpec, podTemplateSpecPath(owner.Kind)...); err != nil {
+		return nil, fmt.Errorf("failed to build the patch for %s %s: %w", owner.Kind, owner.Name, err)
+	}
+
+	if opts.PatchFormat == PatchFormatKustomize {
+		setKustomizeTarget(patch, owner)
+	}
+	return patch, nil
+}
+
+// setKustomizeTarget names the resource the patch applies to, as kustomize patches require
+func setKustomizeTarget(patch map[string]interface{}, owner *Owner) {
+	patch["apiVersion"] = owner.APIVersion
+	patch["kind"] = owner.Kind
+	patch["metadata"] = map[string]interface{}{"name": owner.Name, "namespace": owner.Namespace}
+}
+
+// podTemplateSpecPath returns the path of the pod spec in the workload's pod template
+func podTemplateSpecPath(kind string) []string {
+	if kind == "CronJob" {
+		return []string{"spec", "jobTemplate", "spec", "template", "spec"}
+	}
+	return []string{"spec", "template", "spec"}
+}
+
+// localhostProfilePath returns the profile path relative to the kubelet seccomp directory. Profiles
+// installed by the Security Profiles Operator live below operator/<namespace>/.
+func localhostProfilePath(subject seccompSubject, container string, opts ProfileOptions) string {
+	if opts.Format == SeccompFormatSPO {
+		return fmt.Sprintf("operator/%s/%s.json", subject.Namespace, spoProfileName(subject.Name, container))
+	}
+	return path.Join(opts.ProfileDir, seccompProfileName(subject.Namespace, subject.Name, container))
+}
+
+// isInitContainer reports whether the container is one of the pod's init containers
+func isInitContainer(pod corev1.Pod, container string) bool {
+	return slices.ContainsFunc(pod.Spec.InitContainers, func(c corev1.Container) bool {
+		return c.Name == container
+	})
+}
+
+// applyWorkloadPatch applies the patch to the workload as a strategic-merge patch
+func applyWorkloadPatch(ctx context.Context, config *Config, subject seccompSubject, profiles []containerSeccompProfile, opts ProfileOptions) error {
+	owner := subject.Owner
+	if config.DynamicClient == nil {
+		return fmt.Errorf("no dynamic client available to patch %s %s/%s", owner.Kind, owner.Namespace, owner.Name)
+	}
+
+	gv, err := schema.ParseGroupVersion(owner.APIVersion)
+	if err != nil {
+		return fmt.Errorf("invalid apiVersion %q of %s %s: %w", owner.APIVersion, owner.Kind, owner.Name, err)
+	}
+	if !slices.Contains(strategicMergeKinds, schema.GroupKind{Group: gv.Group, Kind: owner.Kind}) {
+		log.Warn().Msgf("%s %s/%s does not support strategic-merge patches, apply the saved patch manually", owner.Kind, owner.Namespace, owner.Name)
+		return nil
+	}
+
+	// The target is identified by the request, so the patch body never carries kustomize metadata
+	strategicOpts := opts
+	strategicOpts.PatchFormat = PatchFormatStrategic
+	patch, err := seccompWorkloadPatch(subject, profiles, strategicOpts)
+	if err != nil {
+		return err
+	}
+	patchJSON, err := json.Marshal(patch)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the patch for %s %s: %w", owner.Kind, owner.Name, err)
+	}
+
+	gvr, _, err := ownerResource(config.RESTMapper, metav1.OwnerReference{APIVersion: owner.APIVersion, Kind: owner.Kind, Name: owner.Name})
+	if err != nil {
+		return fmt.Errorf("failed to map %s %s/%s to a resource: %w", owner.Kind, owner.Namespace, owner.Name, err)
+	}
+	if _, err := config.DynamicClient.Resource(gvr).Namespace(owner.Namespace).Patch(ctx, owner.Name, types.StrategicMergePatchType, patchJSON, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to patch %s %s/%s: %w", owner.Kind, owner.Namespace, owner.Name, err)
+	}
+
+	log.Info().Msgf("Patched %s %s/%s to use the generated seccomp profiles", owner.Kind, owner.Namespace, owner.Name)
+	return nil
+}
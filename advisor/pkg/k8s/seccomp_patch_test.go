@@ -0,0 +1,138 @@
+package k8s
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"sigs.k8s.io/yaml"
+)
+
+func TestParsePatchFormat(t *testing.T) {
+	format, err := ParsePatchFormat("Kustomize")
+	require.NoError(t, err)
+	assert.Equal(t, PatchFormatKustomize, format)
+
+	_, err = ParsePatchFormat("json")
+	assert.ErrorIs(t, err, ErrInvalidInput)
+}
+
+func TestSeccompWorkloadPatch(t *testing.T) {
+	pod := *mockOwnedPod(mockOwnerRef("apps/v1", "ReplicaSet", "api-abc123"))
+	pod.Spec.InitContainers = []v1.Container{{Name: "migrate"}}
+	profiles := []containerSeccompProfile{{Container: "app"}, {Container: "migrate"}, {}}
+	owner := &Owner{APIVersion: "apps/v1", Kind: "Deployment", Name: "api", Namespace: "default"}
+	subject := seccompSubject{Name: "api", Namespace: "default", Owner: owner, Pod: pod}
+
+	opts := DefaultProfileOptions()
+	opts.ProfileDir = "xentra"
+	patch, err := seccompWorkloadPatch(subject, profiles, opts)
+	require.NoError(t, err)
+
+	containers, _, _ := unstructured.NestedSlice(patch, "spec", "template", "spec", "containers")
+	assert.Equal(t, []interface{}{map[string]interface{}{
+		"name": "app",
+		"securityContext": map[string]interface{}{"seccompProfile": map[string]interface{}{
+			"type":             "Localhost",
+			"localhostProfile": "xentra/default-api-app-seccomp.json",
+		}},
+	}}, containers)
+	initContainers, _, _ := unstructured.NestedSlice(patch, "spec", "template", "spec", "initContainers")
+	assert.Len(t, initContainers, 1)
+	podProfile, _, _ := unstructured.NestedString(patch, "spec", "template", "spec", "securityContext", "seccompProfile", "localhostProfile")
+	assert.Equal(t, "xentra/default-api-seccomp.json", podProfile)
+	assert.NotContains(t, patch, "kind")
+
+	t.Run("kustomize with SPO profiles", func(t *testing.T) {
+		opts.Format = SeccompFormatSPO
+		opts.PatchFormat = PatchFormatKustomize
+		patch, err := seccompWorkloadPatch(subject, profiles[:1], opts)
+		require.NoError(t, err)
+
+		assert.Equal(t, "Deployment", patch["kind"])
+		assert.Equal(t, "apps/v1", patch["apiVersion"])
+		assert.Equal(t, map[string]interface{}{"name": "api", "namespace": "default"}, patch["metadata"])
+		containers, _, _ := unstructured.NestedSlice(patch, "spec", "template", "spec", "containers")
+		profile, _, _ := unstructured.NestedString(containers[0].(map[string]interface{}), "securityContext", "seccompProfile", "localhostProfile")
+		assert.Equal(t, "operator/default/api-app.json", profile)
+	})
+
+	t.Run("cronjob", func(t *testing.T) {
+		cronJob := seccompSubject{Name: "backup", Namespace: "default", Owner: &Owner{APIVersion: "batch/v1", Kind: "CronJob", Name: "backup", Namespace: "default"}, Pod: pod}
+		patch, err := seccompWorkloadPatch(cronJob, profiles[:1], DefaultProfileOptions())
+		require.NoError(t, err)
+		_, found, _ := unstructured.NestedSlice(patch, "spec", "jobTemplate", "spec", "template", "spec", "containers")
+		assert.True(t, found)
+	})
+}
+
+func TestHandleWorkloadPatch(t *testing.T) {
+	config := &Config{DryRun: true}
+	opts := DefaultProfileOptions()
+	opts.OutputDir = t.TempDir()
+	subject := seccompSubject{
+		Name:      "api",
+		Namespace: "default",
+		Owner:     &Owner{APIVersion: "apps/v1", Kind: "Deployment", Name: "api", Namespace: "default"},
+		Pod:       *mockOwnedPod(mockOwnerRef("apps/v1", "ReplicaSet", "api-abc123")),
+	}
+	profiles := []containerSeccompProfile{{Container: "app"}}
+
+	require.NoError(t, handleWorkloadPatch(context.Background(), config, subject, profiles, opts))
+	data, err := os.ReadFile(filepath.Join(opts.OutputDir, "default-deployment-api-seccomp-patch.yaml"))
+	require.NoError(t, err)
+	var patch map[string]interface{}
+	require.NoError(t, yaml.Unmarshal(data, &patch))
+	containers, _, _ := unstructured.NestedSlice(patch, "spec", "template", "spec", "containers")
+	assert.Len(t, containers, 1)
+
+	// Bare pods have no workload to patch
+	bare := seccompSubject{Name: "debug", Namespace: "default", Pod: *createMockPodForTest("debug", "default")}
+	assert.NoError(t, handleWorkloadPatch(context.Background(), config, bare, profiles, opts))
+
+	// Raw profiles only exist locally, so the patch is not applied unless they are installed
+	applied := &Config{
+		DynamicClient: dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()),
+		RESTMapper:    failingRESTMapper{err: assert.AnError},
+	}
+	require.NoError(t, handleWorkloadPatch(context.Background(), applied, subject, profiles, opts))
+	opts.ProfilesInstalled = true
+	assert.ErrorIs(t, handleWorkloadPatch(context.Background(), applied, subject, profiles, opts), assert.AnError)
+}
+
+func TestApplyWorkloadPatch_UnsupportedKind(t *testing.T) {
+	config := &Config{DynamicClient: dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())}
+	subject := seccompSubject{
+		Name:      "canary",
+		Namespace: "default",
+		Owner:     &Owner{APIVersion: "argoproj.io/v1alpha1", Kind: "Rollout", Name: "canary", Namespace: "default"},
+		Pod:       *mockOwnedPod(&metav1.OwnerReference{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "canary-5f6d"}),
+	}
+
+	// Custom resources do not support strategic-merge patches, the saved patch is applied manually
+	assert.NoError(t, applyWorkloadPatch(context.Background(), config, subject, []containerSeccompProfile{{Container: "app"}}, DefaultProfileOptions()))
+}
+
+func TestApplyWorkloadPatch_MappingError(t *testing.T) {
+	config := &Config{
+		DynamicClient: dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()),
+		RESTMapper:    failingRESTMapper{err: assert.AnError},
+	}
+	subject := seccompSubject{
+		Name:      "api",
+		Namespace: "default",
+		Owner:     &Owner{APIVersion: "apps/v1", Kind: "Deployment", Name: "api", Namespace: "default"},
+		Pod:       *mockOwnedPod(mockOwnerRef("apps/v1", "ReplicaSet", "api-abc123")),
+	}
+
+	err := applyWorkloadPatch(context.Background(), config, subject, []containerSeccompProfile{{Container: "app"}}, DefaultProfileOptions())
+	assert.ErrorIs(t, err, assert.AnError)
+}
@@ -0,0 +1,339 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	log "github.com/rs/zerolog/log"
+)
+
+// SyscallSeverity rates how much a syscall widens the attack surface of a container
+type SyscallSeverity string
+
+const (
+	// SeverityNone means only syscalls RuntimeDefault allows were observed
+	SeverityNone SyscallSeverity = "none"
+	// SeverityLow covers obsolete interfaces and syscalls outside RuntimeDefault
+	SeverityLow SyscallSeverity = "low"
+	// SeverityMedium covers syscalls touching node-wide state or large kernel attack surfaces
+	SeverityMedium SyscallSeverity = "medium"
+	// SeverityHigh covers container escape and kernel manipulation primitives
+	SeverityHigh SyscallSeverity = "high"
+)
+
+// severityWeights are the score each catalogued syscall adds to a report
+var severityWeights = map[SyscallSeverity]int{
+	SeverityLow:    2,
+	SeverityMedium: 5,
+	SeverityHigh:   10,
+}
+
+// outsideRuntimeDefaultWeight is the score of an uncatalogued syscall RuntimeDefault does not allow
+const outsideRuntimeDefaultWeight = 1
+
+// dangerousSyscall is a catalogue entry explaining why a syscall is risky in a container
+type dangerousSyscall struct {
+	Severity SyscallSeverity
+	Reason   string
+}
+
+// dangerousSyscalls is the catalogue of syscalls that reviewers should question. Most of them are
+// blocked by RuntimeDefault or need capabilities containers should not have.
+var dangerousSyscalls = map[string]dangerousSyscall{
+	"ptrace":            {SeverityHigh, "inspects and modifies other processes"},
+	"process_vm_readv":  {SeverityHigh, "reads the memory of other processes"},
+	"process_vm_writev": {SeverityHigh, "writes the memory of other processes"},
+	"mount":             {SeverityHigh, "mounts filesystems, a common container escape step"},
+	"umount2":           {SeverityHigh, "unmounts filesystems"},
+	"fsopen":            {SeverityHigh, "mounts filesystems through the new mount API"},
+	"fsmount":           {SeverityHigh, "mounts filesystems through the new mount API"},
+	"fsconfig":          {SeverityHigh, "configures filesystems through the new mount API"},
+	"move_mount":        {SeverityHigh, "moves mounts through the new mount API"},
+	"open_tree":         {SeverityHigh, "clones mounts through the new mount API"},
+	"pivot_root":        {SeverityHigh, "changes the root filesystem"},
+	"unshare":           {SeverityHigh, "creates namespaces, exposing kernel code paths to unprivileged users"},
+	"setns":             {SeverityHigh, "joins other namespaces"},
+	"bpf":               {SeverityHigh, "loads eBPF programs into the kernel"},
+	"perf_event_open":   {SeverityHigh, "exposes kernel performance counters, frequent exploit target"},
+	"userfaultfd":       {SeverityHigh, "handles page faults in userspace, used to win kernel races"},
+	"init_module":       {SeverityHigh, "loads kernel modules"},
+	"finit_module":      {SeverityHigh, "loads kernel modules"},
+	"delete_module":     {SeverityHigh, "unloads kernel modules"},
+	"kexec_load":        {SeverityHigh, "replaces the running kernel"},
+	"kexec_file_load":   {SeverityHigh, "replaces the running kernel"},
+	"open_by_handle_at": {SeverityHigh, "opens host files by handle, used by the shocker escape"},
+	"keyctl":            {SeverityHigh, "manages kernel keyrings, which are not namespaced"},
+	"add_key":           {SeverityHigh, "adds keys to kernel keyrings, which are not namespaced"},
+	"request_key":       {SeverityHigh, "requests keys from kernel keyrings, which are not namespaced"},
+	"reboot":            {SeverityHigh, "reboots the node"},
+	"iopl":              {SeverityHigh, "changes the I/O privilege level"},
+	"ioperm":            {SeverityHigh, "grants access to I/O ports"},
+	"swapon":            {SeverityMedium, "enables swap devices"},
+	"swapoff":           {SeverityMedium, "disables swap devices"},
+	"settimeofday":      {SeverityMedium, "sets the node clock, which is not namespaced"},
+	"clock_settime":     {SeverityMedium, "sets the node clock, which is not namespaced"},
+	"stime":             {SeverityMedium, "sets the node clock, which is not namespaced"},
+	"syslog":            {SeverityMedium, "reads the kernel log, leaking kernel addresses"},
+	"acct":              {SeverityMedium, "toggles process accounting"},
+	"quotactl":          {SeverityMedium, "manages disk quotas"},
+	"chroot":            {SeverityMedium, "changes the root directory"},
+	"name_to_handle_at": {SeverityMedium, "obtains file handles for open_by_handle_at"},
+	"kcmp":              {SeverityMedium, "compares kernel resources of other processes"},
+	"personality":       {SeverityMedium, "changes the execution domain, e.g. disabling ASLR"},
+	"io_uring_setup":    {SeverityMedium, "sets up io_uring, a large kernel attack surface"},
+	"io_uring_enter":    {SeverityMedium, "submits io_uring operations, a large kernel attack surface"},
+	"io_uring_register": {SeverityMedium, "registers io_uring resources, a large kernel attack surface"},
+	"fanotify_init":     {SeverityMedium, "monitors filesystem events"},
+	"lookup_dcookie":    {SeverityMedium, "resolves kernel directory entry cookies"},
+	"nfsservctl":        {SeverityMedium, "controls the kernel NFS server"},
+	"uselib":            {SeverityMedium, "loads shared libraries through an obsolete kernel path"},
+	"_sysctl":           {SeverityMedium, "changes kernel parameters through an obsolete interface"},
+	"vm86":              {SeverityMedium, "enters virtual 8086 mode"},
+	"vm86old":           {SeverityMedium, "enters virtual 8086 mode"},
+	"modify_ldt":        {SeverityLow, "changes the local descriptor table, a past exploit target"},
+	"mbind":             {SeverityLow, "sets the NUMA policy of memory ranges"},
+	"set_mempolicy":     {SeverityLow, "sets the NUMA policy of the process"},
+	"move_pages":        {SeverityLow, "moves pages of other processes between NUMA nodes"},
+	"migrate_pages":     {SeverityLow, "moves pages of other processes between NUMA nodes"},
+	"sysfs":             {SeverityLow, "queries filesystem types through an obsolete interface"},
+	"ustat":             {SeverityLow, "queries filesystem statistics through an obsolete interface"},
+}
+
+// runtimeDefaultFiltered are syscalls RuntimeDefault allows with argument filters, so they are not
+// in the unconditional baseline but are not reported as outside RuntimeDefault either: clone
+// without namespace flags, clone3 answered with ENOSYS and personality with the standard personas.
+// Syscalls RuntimeDefault gates on capabilities, such as ptrace or mount, are still reported.
+var runtimeDefaultFiltered = []string{"clone", "clone3", "personality"}
+
+// ReportFormat is the output format of seccomp reports
+type ReportFormat string
+
+const (
+	// ReportFormatText writes a human readable report for security reviewers
+	ReportFormatText ReportFormat = "text"
+	// ReportFormatJSON writes the report as JSON for other tools
+	ReportFormatJSON ReportFormat = "json"
+)
+
+// ParseReportFormat converts an output flag value to a ReportFormat
+func ParseReportFormat(format string) (ReportFormat, error) {
+	switch ReportFormat(strings.ToLower(format)) {
+	case ReportFormatText:
+		return ReportFormatText, nil
+	case ReportFormatJSON:
+		return ReportFormatJSON, nil
+	default:
+		return "", fmt.Errorf("%w: unknown report format %q, expected text or json", ErrInvalidInput, format)
+	}
+}
+
+// SyscallRisk is a catalogued syscall observed in a container
+type SyscallRisk struct {
+	Name     string          `json:"name"`
+	Severity SyscallSeverity `json:"severity"`
+	Reason   string          `json:"reason"`
+}
+
+// ContainerSyscallReport classifies the syscalls observed in a container, or in the whole pod when
+// the container is empty
+type ContainerSyscallReport struct {
+	Container string   `json:"container,omitempty"`
+	Syscalls  []string `json:"syscalls"`
+	// Dangerous lists the observed syscalls found in the catalogue, most severe first
+	Dangerous []SyscallRisk `json:"dangerous,omitempty"`
+	// OutsideRuntimeDefault lists the observed syscalls RuntimeDefault does not allow
+	OutsideRuntimeDefault []string        `json:"outsideRuntimeDefault,omitempty"`
+	Score                 int             `json:"score"`
+	Risk                  SyscallSeverity `json:"risk"`
+}
+
+// SeccompReport explains the syscalls observed for a workload, or a bare pod
+type SeccompReport struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// Kind is the workload kind, Pod for bare pods
+	Kind          string   `json:"kind"`
+	Pods          []string `json:"pods"`
+	Architectures []string `json:"architectures,omitempty"`
+	// Score and Risk rate the union of the syscalls of all containers
+	Score      int                      `json:"score"`
+	Risk       SyscallSeverity          `json:"risk"`
+	Containers []ContainerSyscallReport `json:"containers"`
+}
+
+// ExplainSeccomp writes a report classifying the syscalls observed for the targeted pods, grouped by
+// workload like the generated profiles
+func ExplainSeccomp(options GenerateOptions, config *Config, profileOpts ProfileOptions, format ReportFormat, out io.Writer) error {
+	pods, err := GetResource(options, config)
+	if err != nil {
+		return err
+	}
+
+	subjects := collectSeccompSubjects(context.TODO(), config, pods, profileOpts.FirstRecordedWithin)
+	if len(subjects) == 0 {
+		return fmt.Errorf("no syscalls recorded for the targeted pods")
+	}
+
+	reports := make([]SeccompReport, 0, len(subjects))
+	for _, subject := range subjects {
+		reports = append(reports, newSeccompReport(subject))
+	}
+	return writeSeccompReports(out, reports, format)
+}
+
+// newSeccompReport classifies the merged syscalls of the subject's containers
+func newSeccompReport(subject seccompSubject) SeccompReport {
+	report := SeccompReport{
+		Namespace: subject.Namespace,
+		Name:      subject.Name,
+		Kind:      "Pod",
+		Pods:      slices.Clone(subject.PodNames),
+	}
+	if subject.Owner != nil {
+		report.Kind = subject.Owner.Kind
+	}
+
+	var podSyscalls []string
+	containerSyscalls := make(map[string][]string)
+	for _, observation := range subject.Observations {
+		if arch, err := normalizeArch(observation.Arch); err == nil && !slices.Contains(report.Architectures, arch) {
+			report.Architectures = append(report.Architectures, arch)
+		}
+		podSyscalls = MergeSyscalls(podSyscalls, observation.Syscalls)
+		for container, syscalls := range observation.Containers {
+			containerSyscalls[container] = MergeSyscalls(containerSyscalls[container], syscalls)
+			podSyscalls = MergeSyscalls(podSyscalls, syscalls)
+		}
+	}
+	sort.Strings(report.Architectures)
+
+	containers := make([]string, 0, len(containerSyscalls))
+	for container := range containerSyscalls {
+		containers = append(containers, container)
+	}
+	sort.Strings(containers)
+	for _, container := range containers {
+		report.Containers = append(report.Containers, classifySyscalls(container, containerSyscalls[container]))
+	}
+
+	pod := classifySyscalls("", podSyscalls)
+	if len(report.Containers) == 0 {
+		report.Containers = []ContainerSyscallReport{pod}
+	}
+	report.Score, report.Risk = pod.Score, pod.Risk
+	return report
+}
+
+// classifySyscalls scores the syscalls against the catalogue and the RuntimeDefault baseline.
+// Catalogued syscalls add their severity weight, other syscalls outside RuntimeDefault add one.
+// The risk is the highest catalogued severity, or low for uncatalogued syscalls outside it.
+func classifySyscalls(container string, syscalls []string) ContainerSyscallReport {
+	names := slices.Clone(syscalls)
+	sort.Strings(names)
+	report := ContainerSyscallReport{Container: container, Syscalls: names, Risk: SeverityNone}
+
+	runtimeDefault := baselineSyscalls(SeccompBaselineRuntimeDefault)
+	for _, name := range names {
+		if !slices.Contains(runtimeDefault, name) && !slices.Contains(runtimeDefaultFiltered, name) {
+			report.OutsideRuntimeDefault = append(report.OutsideRuntimeDefault, name)
+		}
+
+		entry, dangerous := dangerousSyscalls[name]
+		if !dangerous {
+			if slices.Contains(report.OutsideRuntimeDefault, name) {
+				report.Score += outsideRuntimeDefaultWeight
+				report.Risk = maxSeverity(report.Risk, SeverityLow)
+			}
+			continue
+		}
+		report.Dangerous = append(report.Dangerous, SyscallRisk{Name: name, Severity: entry.Severity, Reason: entry.Reason})
+		report.Score += severityWeights[entry.Severity]
+		report.Risk = maxSeverity(report.Risk, entry.Severity)
+	}
+
+	sort.SliceStable(report.Dangerous, func(i, j int) bool {
+		return severityWeights[report.Dangerous[i].Severity] > severityWeights[report.Dangerous[j].Severity]
+	})
+	return report
+}
+
+// maxSeverity returns the more severe of the two severities
+func maxSeverity(a, b SyscallSeverity) SyscallSeverity {
+	if severityWeights[b] > severityWeights[a] {
+		return b
+	}
+	return a
+}
+
+// writeSeccompReports writes the reports in the requested format
+func writeSeccompReports(out io.Writer, reports []SeccompReport, format ReportFormat) error {
+	if format == ReportFormatJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "    ")
+		return encoder.Encode(reports)
+	}
+
+	for i, report := range reports {
+		if i > 0 {
+			fmt.Fprintln(out)
+		}
+		if err := writeSeccompReportText(out, report); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSeccompReportText writes a report for security reviewers
+func writeSeccompReportText(out io.Writer, report SeccompReport) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "%s %s/%s\n", report.Kind, report.Namespace, report.Name)
+	fmt.Fprintf(w, "  Pods:\t%s\n", strings.Join(report.Pods, ", "))
+	if len(report.Architectures) > 0 {
+		fmt.Fprintf(w, "  Architectures:\t%s\n", strings.Join(report.Architectures, ", "))
+	}
+	fmt.Fprintf(w, "  Risk:\t%s (score %d)\n", report.Risk, report.Score)
+
+	for _, container := range report.Containers {
+		name := container.Container
+		if name == "" {
+			name = "(pod)"
+		}
+		fmt.Fprintf(w, "\n  Container %s: %d syscalls, risk %s (score %d)\n", name, len(container.Syscalls), container.Risk, container.Score)
+		if len(container.Dangerous) == 0 {
+			fmt.Fprintln(w, "    No dangerous syscalls observed")
+		}
+		for _, risk := range container.Dangerous {
+			fmt.Fprintf(w, "    %s\t%s\t%s\n", risk.Name, risk.Severity, risk.Reason)
+		}
+		if len(container.OutsideRuntimeDefault) > 0 {
+			fmt.Fprintf(w, "    Outside RuntimeDefault:\t%s\n", strings.Join(container.OutsideRuntimeDefault, ", "))
+		} else {
+			fmt.Fprintln(w, "    Within RuntimeDefault")
+		}
+	}
+	return w.Flush()
+}
+
+// writeSeccompReportFile saves the text report of the subject next to its profiles
+func writeSeccompReportFile(outputDir string, subject seccompSubject) (string, error) {
+	var builder strings.Builder
+	if err := writeSeccompReportText(&builder, newSeccompReport(subject)); err != nil {
+		return "", err
+	}
+
+	filename := filepath.Join(outputDir, fmt.Sprintf("%s-%s-seccomp-report.txt", subject.Namespace, subject.Name))
+	if err := os.WriteFile(filename, []byte(builder.String()), 0644); err != nil {
+		return "", err
+	}
+	log.Info().Msgf("Generated seccomp report for %s/%s: %s", subject.Namespace, subject.Name, filename)
+	return filename, nil
+}
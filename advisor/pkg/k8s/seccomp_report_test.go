@@ -0,0 +1,104 @@
+package k8s
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	api "github.com/xentra-ai/advisor/pkg/api"
+)
+
+func TestClassifySyscalls(t *testing.T) {
+	report := classifySyscalls("app", []string{"read", "ptrace", "clone", "mbind", "custom_call", "personality"})
+
+	assert.Equal(t, []SyscallRisk{
+		{Name: "ptrace", Severity: SeverityHigh, Reason: dangerousSyscalls["ptrace"].Reason},
+		{Name: "personality", Severity: SeverityMedium, Reason: dangerousSyscalls["personality"].Reason},
+		{Name: "mbind", Severity: SeverityLow, Reason: dangerousSyscalls["mbind"].Reason},
+	}, report.Dangerous)
+	// clone and personality are allowed by RuntimeDefault with argument filters
+	assert.Equal(t, []string{"custom_call", "mbind", "ptrace"}, report.OutsideRuntimeDefault)
+	assert.Equal(t, 10+5+2+1, report.Score)
+	assert.Equal(t, SeverityHigh, report.Risk)
+
+	benign := classifySyscalls("app", []string{"read", "write", "clone", "clone3"})
+	assert.Zero(t, benign.Score)
+	assert.Equal(t, SeverityNone, benign.Risk)
+	assert.Empty(t, benign.OutsideRuntimeDefault)
+
+	assert.Equal(t, SeverityLow, classifySyscalls("", []string{"custom_call"}).Risk)
+}
+
+func TestNewSeccompReport(t *testing.T) {
+	subject := seccompSubject{
+		Name:      "api",
+		Namespace: "shop",
+		Owner:     &Owner{Kind: "Deployment", Name: "api", Namespace: "shop"},
+		PodNames:  []string{"api-1", "api-2"},
+		Observations: []api.PodSysCall{
+			{Arch: "x86_64", Containers: map[string][]string{"app": {"read"}, "debugger": {"ptrace"}}},
+			{Arch: "aarch64", Containers: map[string][]string{"app": {"bpf"}}},
+		},
+	}
+
+	report := newSeccompReport(subject)
+	assert.Equal(t, "Deployment", report.Kind)
+	assert.Equal(t, []string{"api-1", "api-2"}, report.Pods)
+	assert.Equal(t, []string{"amd64", "arm64"}, report.Architectures)
+	require.Len(t, report.Containers, 2)
+	assert.Equal(t, "app", report.Containers[0].Container)
+	assert.Equal(t, []string{"bpf", "read"}, report.Containers[0].Syscalls)
+	assert.Equal(t, 10, report.Containers[0].Score)
+	// The pod is rated on the union of its containers
+	assert.Equal(t, 20, report.Score)
+	assert.Equal(t, SeverityHigh, report.Risk)
+
+	bare := newSeccompReport(seccompSubject{Name: "debug", Namespace: "shop", Observations: []api.PodSysCall{{Syscalls: []string{"read"}}}})
+	assert.Equal(t, "Pod", bare.Kind)
+	require.Len(t, bare.Containers, 1)
+	assert.Empty(t, bare.Containers[0].Container)
+}
+
+func TestWriteSeccompReports(t *testing.T) {
+	reports := []SeccompReport{newSeccompReport(seccompSubject{
+		Name:         "api",
+		Namespace:    "shop",
+		PodNames:     []string{"api"},
+		Observations: []api.PodSysCall{{Arch: "x86_64", Containers: map[string][]string{"app": {"read", "keyctl"}}}},
+	})}
+
+	var text bytes.Buffer
+	require.NoError(t, writeSeccompReports(&text, reports, ReportFormatText))
+	assert.Contains(t, text.String(), "Pod shop/api")
+	assert.Contains(t, text.String(), "Risk:")
+	assert.Contains(t, text.String(), "keyctl")
+	assert.Contains(t, text.String(), "Outside RuntimeDefault:")
+
+	var out bytes.Buffer
+	require.NoError(t, writeSeccompReports(&out, reports, ReportFormatJSON))
+	var decoded []SeccompReport
+	require.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	assert.Equal(t, reports, decoded)
+
+	format, err := ParseReportFormat("JSON")
+	require.NoError(t, err)
+	assert.Equal(t, ReportFormatJSON, format)
+	_, err = ParseReportFormat("yaml")
+	assert.ErrorIs(t, err, ErrInvalidInput)
+}
+
+func TestWriteSeccompReportFile(t *testing.T) {
+	dir := t.TempDir()
+	subject := seccompSubject{Name: "api", Namespace: "shop", Observations: []api.PodSysCall{{Syscalls: []string{"read"}}}}
+
+	filename, err := writeSeccompReportFile(dir, subject)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "shop-api-seccomp-report.txt"), filename)
+	data, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "Within RuntimeDefault")
+}
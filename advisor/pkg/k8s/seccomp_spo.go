@@ -0,0 +1,128 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	log "github.com/rs/zerolog/log"
+	"github.com/xentra-ai/advisor/pkg/common"
+	"github.com/xentra-ai/advisor/pkg/network"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	spoAPIVersion = "security-profiles-operator.x-k8s.io/v1beta1"
+	spoKind       = "SeccompProfile"
+	// spoResourceType is the file name suffix of saved SeccompProfile resources
+	spoResourceType = "seccompprofile"
+)
+
+// spoSeccompProfileResource is the SeccompProfile resource of the Security Profiles Operator
+var spoSeccompProfileResource = schema.GroupVersionResource{
+	Group:    "security-profiles-operator.x-k8s.io",
+	Version:  "v1beta1",
+	Resource: "seccompprofiles",
+}
+
+// SPOSeccompProfile is a Security Profiles Operator SeccompProfile custom resource. The operator
+// installs the profile on every node, pods reference it as operator/<namespace>/<name>.json.
+type SPOSeccompProfile struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              SPOSeccompProfileSpec `json:"spec"`
+}
+
+// SPOSeccompProfileSpec mirrors the seccomp profile JSON
+type SPOSeccompProfileSpec struct {
+	DefaultAction string   `json:"defaultAction"`
+	Architectures []string `json:"architectures,omitempty"`
+	Syscalls      []Rule   `json:"syscalls,omitempty"`
+}
+
+// newSPOSeccompProfile wraps a generated profile in a SeccompProfile resource in the subject's
+// namespace. The operator supports neither archMap nor per-arch rules, so both are flattened.
+func newSPOSeccompProfile(subject seccompSubject, containerProfile containerSeccompProfile) *SPOSeccompProfile {
+	return &SPOSeccompProfile{
+		TypeMeta:   network.CreateTypeMeta(spoKind, spoAPIVersion),
+		ObjectMeta: network.CreateObjectMeta(spoProfileName(subject.Name, containerProfile.Container), subject.Namespace, network.CreateStandardLabels(subject.Name, "seccomp-profile")),
+		Spec: SPOSeccompProfileSpec{
+			DefaultAction: containerProfile.Profile.DefaultAction,
+			Architectures: flattenArchitectures(containerProfile.Profile),
+			Syscalls:      flattenRules(containerProfile.Profile.Syscalls),
+		},
+	}
+}
+
+// spoProfileName returns the resource name, <name>-<container> for a container and <name> for the
+// pod, named after its workload or the bare pod
+func spoProfileName(name, container string) string {
+	if container == "" {
+		return strings.ToLower(name)
+	}
+	return strings.ToLower(fmt.Sprintf("%s-%s", name, container))
+}
+
+// handleSPOSeccompProfile saves the SeccompProfile resource and applies it unless in dry run mode
+func handleSPOSeccompProfile(ctx context.Context, config *Config, subject seccompSubject, containerProfile containerSeccompProfile, opts ProfileOptions) error {
+	profile := newSPOSeccompProfile(subject, containerProfile)
+	profileYAML, err := yaml.Marshal(profile)
+	if err != nil {
+		return fmt.Errorf("failed to marshal SeccompProfile %s: %w", profile.Name, err)
+	}
+
+	if opts.OutputDir != "" {
+		filename, err := common.SaveToFile(opts.OutputDir, spoResourceType, profile.Namespace, profile.Name, profileYAML)
+		if err != nil {
+			return err
+		}
+		log.Info().Msgf("Generated SeccompProfile for %s: %s", seccompSubjectContainer(subject, containerProfile.Container), filename)
+	}
+
+	if config == nil || config.DryRun {
+		common.PrintDryRunMessage(spoResourceType, profile.Name, profileYAML, opts.OutputDir)
+		return nil
+	}
+
+	if err := applySPOSeccompProfile(ctx, config, profile); err != nil {
+		return err
+	}
+	log.Info().Msgf("Applied SeccompProfile %s/%s", profile.Namespace, profile.Name)
+	return nil
+}
+
+// applySPOSeccompProfile creates the SeccompProfile resource, or updates it when it already exists
+func applySPOSeccompProfile(ctx context.Context, config *Config, profile *SPOSeccompProfile) error {
+	if config.DynamicClient == nil {
+		return fmt.Errorf("no dynamic client available to apply SeccompProfile %s/%s", profile.Namespace, profile.Name)
+	}
+
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(profile)
+	if err != nil {
+		return fmt.Errorf("failed to convert SeccompProfile %s: %w", profile.Name, err)
+	}
+	obj := &unstructured.Unstructured{Object: content}
+
+	client := config.DynamicClient.Resource(spoSeccompProfileResource).Namespace(profile.Namespace)
+	existing, err := client.Get(ctx, profile.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		if _, err := client.Create(ctx, obj, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("failed to create SeccompProfile %s/%s: %w", profile.Namespace, profile.Name, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get SeccompProfile %s/%s: %w", profile.Namespace, profile.Name, err)
+	}
+
+	obj.SetResourceVersion(existing.GetResourceVersion())
+	if _, err := client.Update(ctx, obj, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update SeccompProfile %s/%s: %w", profile.Namespace, profile.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package k8s
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"sigs.k8s.io/yaml"
+)
+
+func TestParseSeccompFormat(t *testing.T) {
+	format, err := ParseSeccompFormat("SPO")
+	require.NoError(t, err)
+	assert.Equal(t, SeccompFormatSPO, format)
+
+	_, err = ParseSeccompFormat("yaml")
+	assert.ErrorIs(t, err, ErrInvalidInput)
+}
+
+func testContainerSeccompProfile(container string) containerSeccompProfile {
+	return containerSeccompProfile{
+		Container: container,
+		Profile: SeccompProfile{
+			DefaultAction: "SCMP_ACT_ERRNO",
+			ArchMap:       archMapFor([]string{"amd64"}),
+			Syscalls: []Rule{
+				{Names: []string{"read", "write"}, Action: "SCMP_ACT_ALLOW"},
+				{Names: []string{"open"}, Action: "SCMP_ACT_ALLOW", Includes: &RuleFilter{Arches: []string{"amd64"}}},
+			},
+		},
+	}
+}
+
+func TestHandleSPOSeccompProfile_DryRun(t *testing.T) {
+	subject := seccompSubject{Name: "web-0", Namespace: "shop", Pod: *createMockPodForTest("web-0", "shop")}
+	opts := DefaultProfileOptions()
+	opts.Format = SeccompFormatSPO
+	opts.OutputDir = t.TempDir()
+
+	err := handleSPOSeccompProfile(context.Background(), &Config{DryRun: true}, subject, testContainerSeccompProfile("envoy"), opts)
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(opts.OutputDir, "shop-web-0-envoy-seccompprofile.yaml"))
+	require.NoError(t, err)
+	var profile SPOSeccompProfile
+	require.NoError(t, yaml.Unmarshal(data, &profile))
+	assert.Equal(t, "security-profiles-operator.x-k8s.io/v1beta1", profile.APIVersion)
+	assert.Equal(t, "SeccompProfile", profile.Kind)
+	assert.Equal(t, "web-0-envoy", profile.Name)
+	assert.Equal(t, "shop", profile.Namespace)
+	assert.Equal(t, "xentra-advisor", profile.Labels["app.kubernetes.io/part-of"])
+	assert.Equal(t, "SCMP_ACT_ERRNO", profile.Spec.DefaultAction)
+	// The operator supports neither archMap nor includes
+	assert.Equal(t, []string{"SCMP_ARCH_X86_64", "SCMP_ARCH_X86", "SCMP_ARCH_X32"}, profile.Spec.Architectures)
+	assert.Equal(t, []Rule{{Names: []string{"open", "read", "write"}, Action: "SCMP_ACT_ALLOW"}}, profile.Spec.Syscalls)
+}
+
+func TestHandleSPOSeccompProfile_Apply(t *testing.T) {
+	subject := seccompSubject{Name: "web-0", Namespace: "shop", Pod: *createMockPodForTest("web-0", "shop")}
+	config := &Config{
+		DynamicClient: dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+			map[schema.GroupVersionResource]string{spoSeccompProfileResource: "SeccompProfileList"}),
+	}
+	opts := DefaultProfileOptions()
+	opts.Format = SeccompFormatSPO
+	opts.OutputDir = ""
+
+	// The first run creates the resource, the second updates it
+	require.NoError(t, handleSPOSeccompProfile(context.Background(), config, subject, testContainerSeccompProfile(""), opts))
+	updated := testContainerSeccompProfile("")
+	updated.Profile.DefaultAction = "SCMP_ACT_LOG"
+	require.NoError(t, handleSPOSeccompProfile(context.Background(), config, subject, updated, opts))
+
+	obj, err := config.DynamicClient.Resource(spoSeccompProfileResource).Namespace("shop").Get(context.Background(), "web-0", metav1.GetOptions{})
+	require.NoError(t, err)
+	defaultAction, _, _ := unstructured.NestedString(obj.Object, "spec", "defaultAction")
+	assert.Equal(t, "SCMP_ACT_LOG", defaultAction)
+}
+
+func TestApplySPOSeccompProfile_NoDynamicClient(t *testing.T) {
+	profile := newSPOSeccompProfile(seccompSubject{Name: "web-0", Namespace: "shop"}, testContainerSeccompProfile("app"))
+	assert.Error(t, applySPOSeccompProfile(context.Background(), &Config{}, profile))
+}
@@ -0,0 +1,132 @@
+package k8s
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	log "github.com/rs/zerolog/log"
+)
+
+// RolloutMode selects how generated profiles move from logging to enforcement
+type RolloutMode string
+
+const (
+	// RolloutNone writes profiles with the configured default action
+	RolloutNone RolloutMode = "none"
+	// RolloutStage writes profiles logging unlisted syscalls and records them in the state file
+	RolloutStage RolloutMode = "stage"
+	// RolloutPromote enforces staged profiles once no new syscalls were observed for a while
+	RolloutPromote RolloutMode = "promote"
+)
+
+const (
+	seccompActionLog   = "SCMP_ACT_LOG"
+	seccompActionErrno = "SCMP_ACT_ERRNO"
+	// seccompStageStateFile is saved in the output directory, so later runs see what was staged
+	seccompStageStateFile = "seccomp-rollout-state.json"
+)
+
+// seccompStageState tracks the staged profiles across runs, keyed by profile file name
+type seccompStageState struct {
+	Profiles map[string]stagedProfile `json:"profiles"`
+}
+
+// stagedProfile is a profile in staged rollout
+type stagedProfile struct {
+	// Syscalls are all syscalls allowed by the profile so far
+	Syscalls []string  `json:"syscalls"`
+	StagedAt time.Time `json:"stagedAt"`
+	// LastChange is when new syscalls were last observed
+	LastChange time.Time `json:"lastChange"`
+	Promoted   bool      `json:"promoted"`
+}
+
+// loadStageState reads the state file from the output directory, a missing file is an empty state
+func loadStageState(dir string) (*seccompStageState, error) {
+	state := &seccompStageState{Profiles: make(map[string]stagedProfile)}
+
+	data, err := os.ReadFile(filepath.Join(dir, seccompStageStateFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("invalid rollout state %s: %w", seccompStageStateFile, err)
+	}
+	if state.Profiles == nil {
+		state.Profiles = make(map[string]stagedProfile)
+	}
+	return state, nil
+}
+
+// save writes the state file to the output directory
+func (s *seccompStageState) save(dir string) error {
+	data, err := json.MarshalIndent(s, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal the rollout state: %w", err)
+	}
+	return os.WriteFile(filepath.Join(dir, seccompStageStateFile), data, 0644)
+}
+
+// stagedAction returns the default action the staged rollout has given the profile, and whether the
+// profile is staged, without changing the state
+func (s *seccompStageState) stagedAction(key string, opts ProfileOptions) (string, bool) {
+	entry, ok := s.Profiles[key]
+	if !ok {
+		return "", false
+	}
+	if !entry.Promoted {
+		return seccompActionLog, true
+	}
+	if opts.DefaultAction == "" || opts.DefaultAction == seccompActionLog {
+		return seccompActionErrno, true
+	}
+	return opts.DefaultAction, true
+}
+
+// rollout records the profile in the state and returns its default action. Profiles log unlisted
+// syscalls until their syscalls did not change for PromoteAfter in promote mode, and go back to
+// logging whenever new syscalls are observed.
+func (s *seccompStageState) rollout(key string, profile SeccompProfile, opts ProfileOptions, now time.Time) string {
+	syscalls := allowedSyscalls(profile)
+
+	entry, ok := s.Profiles[key]
+	if !ok {
+		s.Profiles[key] = stagedProfile{Syscalls: syscalls, StagedAt: now, LastChange: now}
+		log.Info().Msgf("Staged seccomp profile %s, logging unlisted syscalls", key)
+		return seccompActionLog
+	}
+
+	if added := syscallDelta(syscalls, entry.Syscalls); len(added) > 0 {
+		entry.Syscalls = MergeSyscalls(entry.Syscalls, syscalls)
+		sort.Strings(entry.Syscalls)
+		entry.LastChange = now
+		entry.Promoted = false
+		s.Profiles[key] = entry
+		log.Info().Msgf("New syscalls %v observed for %s, logging unlisted syscalls", added, key)
+		return seccompActionLog
+	}
+
+	quiet := now.Sub(entry.LastChange)
+	if !entry.Promoted && (opts.Rollout != RolloutPromote || quiet < opts.PromoteAfter) {
+		if opts.Rollout == RolloutPromote {
+			log.Info().Msgf("Not promoting %s, no new syscalls for %s of %s", key, quiet.Round(time.Minute), opts.PromoteAfter)
+		}
+		return seccompActionLog
+	}
+
+	if !entry.Promoted {
+		entry.Promoted = true
+		s.Profiles[key] = entry
+		log.Info().Msgf("Promoted %s to enforcement after %s without new syscalls", key, quiet.Round(time.Minute))
+	}
+	action, _ := s.stagedAction(key, opts)
+	return action
+}
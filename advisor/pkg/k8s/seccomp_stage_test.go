@@ -0,0 +1,56 @@
+package k8s
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeccompStageState_Rollout(t *testing.T) {
+	dir := t.TempDir()
+	state, err := loadStageState(dir)
+	require.NoError(t, err)
+
+	opts := DefaultProfileOptions()
+	opts.Rollout = RolloutStage
+	staged := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	profile := newSeccompProfile([]string{"read", "write"}, []string{"amd64"}, opts)
+
+	assert.Equal(t, "SCMP_ACT_LOG", state.rollout("p", profile, opts, staged))
+	require.NoError(t, state.save(dir))
+
+	state, err = loadStageState(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"read", "write"}, state.Profiles["p"].Syscalls)
+
+	// Promotion waits for the quiet period
+	opts.Rollout = RolloutPromote
+	assert.Equal(t, "SCMP_ACT_LOG", state.rollout("p", profile, opts, staged.Add(3*24*time.Hour)))
+
+	// New syscalls restart the quiet period
+	grown := newSeccompProfile([]string{"read", "write", "openat"}, []string{"amd64"}, opts)
+	assert.Equal(t, "SCMP_ACT_LOG", state.rollout("p", grown, opts, staged.Add(5*24*time.Hour)))
+	assert.Equal(t, staged.Add(5*24*time.Hour), state.Profiles["p"].LastChange)
+	assert.Equal(t, "SCMP_ACT_LOG", state.rollout("p", grown, opts, staged.Add(11*24*time.Hour)))
+
+	assert.Equal(t, "SCMP_ACT_ERRNO", state.rollout("p", grown, opts, staged.Add(12*24*time.Hour)))
+	assert.True(t, state.Profiles["p"].Promoted)
+
+	// Promoted profiles stay enforced when staged again without new syscalls
+	opts.Rollout = RolloutStage
+	assert.Equal(t, "SCMP_ACT_ERRNO", state.rollout("p", grown, opts, staged.Add(13*24*time.Hour)))
+	more := newSeccompProfile([]string{"read", "write", "openat", "mmap"}, []string{"amd64"}, opts)
+	assert.Equal(t, "SCMP_ACT_LOG", state.rollout("p", more, opts, staged.Add(14*24*time.Hour)))
+	assert.False(t, state.Profiles["p"].Promoted)
+}
+
+func TestLoadStageState_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, seccompStageStateFile), []byte("{"), 0644))
+	_, err := loadStageState(dir)
+	assert.Error(t, err)
+}
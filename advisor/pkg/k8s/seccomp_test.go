@@ -0,0 +1,229 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	api "github.com/xentra-ai/advisor/pkg/api"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestBuildSeccompProfiles_PerContainer(t *testing.T) {
+	pod := *createMockPodForTest("web-0", "shop")
+	podSysCalls := api.PodSysCall{
+		Arch:     "x86_64",
+		Syscalls: []string{"read", "write", "epoll_wait", "connect"},
+		Containers: map[string][]string{
+			"envoy": {"epoll_wait", "read", "write"},
+			"app":   {"write", "read", "connect"},
+		},
+	}
+	subject := seccompSubject{Name: "web-0", Namespace: "shop", Pod: pod, Observations: []api.PodSysCall{podSysCalls}}
+	opts := DefaultProfileOptions()
+
+	profiles := buildSeccompProfiles(subject, opts)
+	require.Len(t, profiles, 2)
+	assert.Equal(t, "app", profiles[0].Container)
+	assert.Equal(t, []string{"connect", "read", "write"}, profiles[0].Profile.Syscalls[0].Names)
+	assert.Equal(t, "envoy", profiles[1].Container)
+	assert.Equal(t, []string{"epoll_wait", "read", "write"}, profiles[1].Profile.Syscalls[0].Names)
+	assert.Equal(t, []ArchMap{{Architecture: "SCMP_ARCH_X86_64", SubArchitectures: []string{"SCMP_ARCH_X86", "SCMP_ARCH_X32"}}}, profiles[1].Profile.ArchMap)
+	assert.Empty(t, profiles[1].Profile.Architectures)
+	assert.Equal(t, "SCMP_ACT_ERRNO", profiles[1].Profile.DefaultAction)
+
+	t.Run("pod union", func(t *testing.T) {
+		opts.PodUnion = true
+		profiles := buildSeccompProfiles(subject, opts)
+		require.Len(t, profiles, 3)
+		assert.Empty(t, profiles[2].Container)
+		assert.Equal(t, []string{"connect", "epoll_wait", "read", "write"}, profiles[2].Profile.Syscalls[0].Names)
+	})
+}
+
+func TestBuildSeccompProfiles_WithoutContainers(t *testing.T) {
+	pod := *createMockPodForTest("web-0", "shop")
+	opts := DefaultProfileOptions()
+	opts.DefaultAction = "SCMP_ACT_LOG"
+	opts.Architectures = []string{"SCMP_ARCH_AARCH64"}
+
+	// Brokers that do not record containers only provide the pod-level list
+	subject := seccompSubject{Name: "web-0", Namespace: "shop", Pod: pod}
+	subject.Observations = []api.PodSysCall{{Arch: "x86_64", Syscalls: []string{"write", "read"}}}
+	profiles := buildSeccompProfiles(subject, opts)
+	require.Len(t, profiles, 1)
+	assert.Empty(t, profiles[0].Container)
+	assert.Equal(t, []string{"read", "write"}, profiles[0].Profile.Syscalls[0].Names)
+	assert.Equal(t, "SCMP_ACT_LOG", profiles[0].Profile.DefaultAction)
+	assert.Equal(t, []string{"SCMP_ARCH_AARCH64"}, profiles[0].Profile.Architectures)
+	assert.Empty(t, profiles[0].Profile.ArchMap)
+
+	subject.Observations = []api.PodSysCall{{}}
+	assert.Empty(t, buildSeccompProfiles(subject, opts))
+}
+
+func TestBuildSeccompProfiles_MultiArch(t *testing.T) {
+	pod := *createMockPodForTest("web-0", "shop")
+	subject := seccompSubject{Name: "web", Namespace: "shop", Pod: pod, Observations: []api.PodSysCall{
+		{Arch: "x86_64", Containers: map[string][]string{"app": {"read", "open", "arch_prctl"}}},
+		{Arch: "aarch64", Containers: map[string][]string{"app": {"read", "openat"}}},
+	}}
+
+	profiles := buildSeccompProfiles(subject, DefaultProfileOptions())
+	require.Len(t, profiles, 1)
+	profile := profiles[0].Profile
+	require.NoError(t, ValidateProfile(profile))
+	assert.Equal(t, []ArchMap{
+		{Architecture: "SCMP_ARCH_X86_64", SubArchitectures: []string{"SCMP_ARCH_X86", "SCMP_ARCH_X32"}},
+		{Architecture: "SCMP_ARCH_AARCH64", SubArchitectures: []string{"SCMP_ARCH_ARM"}},
+	}, profile.ArchMap)
+	assert.Equal(t, []Rule{
+		{Names: []string{"openat", "read"}, Action: "SCMP_ACT_ALLOW"},
+		{Names: []string{"arch_prctl", "open"}, Action: "SCMP_ACT_ALLOW", Includes: &RuleFilter{Arches: []string{"amd64"}}},
+	}, profile.Syscalls)
+
+	t.Run("unknown architecture", func(t *testing.T) {
+		subject := seccompSubject{Name: "web", Namespace: "shop", Pod: pod, Observations: []api.PodSysCall{
+			{Arch: "riscv64", Syscalls: []string{"read"}},
+		}}
+		profiles := buildSeccompProfiles(subject, DefaultProfileOptions())
+		require.Len(t, profiles, 1)
+		assert.Error(t, ValidateProfile(profiles[0].Profile))
+	})
+}
+
+func TestSeccompSubjects(t *testing.T) {
+	origGetPodSysCallFunc := api.GetPodSysCallFunc
+	defer func() { api.GetPodSysCallFunc = origGetPodSysCallFunc }()
+	api.GetPodSysCallFunc = func(podName string, since time.Time) (api.PodSysCall, error) {
+		if podName == "unrecorded" {
+			return api.PodSysCall{}, assert.AnError
+		}
+		return api.PodSysCall{Arch: "x86_64", Syscalls: []string{"read"}}, nil
+	}
+
+	objects := []runtime.Object{
+		mockOwnerObject("apps/v1", "ReplicaSet", "api-abc123", map[string]interface{}{
+			"selector": map[string]interface{}{"matchLabels": map[string]interface{}{"app": "api"}},
+		}, nil),
+	}
+	config := &Config{DynamicClient: dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), objects...)}
+
+	replica1 := *mockOwnedPod(mockOwnerRef("apps/v1", "ReplicaSet", "api-abc123"))
+	replica2 := *mockOwnedPod(mockOwnerRef("apps/v1", "ReplicaSet", "api-abc123"))
+	replica2.Name = "owned-pod-2"
+	bare := *createMockPodForTest("debug", "default")
+	unrecorded := *createMockPodForTest("unrecorded", "default")
+
+	subjects := seccompSubjects(context.Background(), config, []corev1.Pod{replica1, bare, replica2, unrecorded}, time.Time{})
+	require.Len(t, subjects, 2)
+	assert.Equal(t, "api-abc123", subjects[0].Name)
+	assert.Equal(t, "ReplicaSet", subjects[0].Owner.Kind)
+	assert.Equal(t, []string{"owned-pod", "owned-pod-2"}, subjects[0].PodNames)
+	assert.Len(t, subjects[0].Observations, 2)
+	assert.Equal(t, "debug", subjects[1].Name)
+	assert.Nil(t, subjects[1].Owner)
+	assert.Len(t, subjects[1].Observations, 1)
+}
+
+func TestGenerateSeccompProfile(t *testing.T) {
+	origGetPodFunc := getPodFunc
+	origGetPodSysCallFunc := api.GetPodSysCallFunc
+	defer func() {
+		getPodFunc = origGetPodFunc
+		api.GetPodSysCallFunc = origGetPodSysCallFunc
+	}()
+
+	getPodFunc = func(ctx context.Context, cfg *Config, ns, name string) (*corev1.Pod, error) {
+		return createMockPodForTest(name, ns), nil
+	}
+	api.GetPodSysCallFunc = func(podName string, since time.Time) (api.PodSysCall, error) {
+		return api.PodSysCall{
+			Arch:       "x86_64",
+			Syscalls:   []string{"read", "write"},
+			Containers: map[string][]string{"app": {"read", "write"}},
+		}, nil
+	}
+
+	opts := DefaultProfileOptions()
+	opts.OutputDir = t.TempDir()
+	GenerateSeccompProfile(GenerateOptions{Mode: SinglePod, PodName: "web-0", Namespace: "shop"}, &Config{}, opts)
+
+	data, err := os.ReadFile(filepath.Join(opts.OutputDir, "shop-web-0-app-seccomp.json"))
+	require.NoError(t, err)
+	var profile SeccompProfile
+	require.NoError(t, json.Unmarshal(data, &profile))
+	assert.Equal(t, []string{"read", "write"}, profile.Syscalls[0].Names)
+
+	_, err = os.Stat(filepath.Join(opts.OutputDir, "shop-web-0-seccomp.json"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestAddRecordedReplicas(t *testing.T) {
+	origGetBrokerPodsFunc := getBrokerPodsFunc
+	origGetPodSysCallFunc := api.GetPodSysCallFunc
+	defer func() {
+		getBrokerPodsFunc = origGetBrokerPodsFunc
+		api.GetPodSysCallFunc = origGetPodSysCallFunc
+	}()
+
+	since := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	api.GetPodSysCallFunc = func(podName string, requestedSince time.Time) (api.PodSysCall, error) {
+		assert.Equal(t, since, requestedSince)
+		return api.PodSysCall{Arch: "aarch64", Containers: map[string][]string{"app": {podName}}}, nil
+	}
+	getBrokerPodsFunc = func(ctx context.Context, namespace string) ([]corev1.Pod, error) {
+		oldReplica := *createMockPodForTest("api-old-1", namespace)
+		oldReplica.Labels = map[string]string{"app": "api", "pod-template-hash": "old"}
+		other := *createMockPodForTest("db-0", namespace)
+		other.Labels = map[string]string{"app": "db"}
+		targeted := *createMockPodForTest("api-new-1", namespace)
+		targeted.Labels = map[string]string{"app": "api"}
+		return []corev1.Pod{oldReplica, other, targeted}, nil
+	}
+
+	subjects := []seccompSubject{
+		{
+			Name:         "api",
+			Namespace:    "shop",
+			Owner:        &Owner{Kind: "Deployment", Name: "api", Namespace: "shop", Selector: map[string]string{"app": "api"}},
+			PodNames:     []string{"api-new-1"},
+			Observations: []api.PodSysCall{{Arch: "x86_64", Containers: map[string][]string{"app": {"read"}}}},
+		},
+		{Name: "debug", Namespace: "shop", PodNames: []string{"debug"}, Observations: []api.PodSysCall{{}}},
+	}
+
+	addRecordedReplicas(context.Background(), subjects, since)
+	assert.Equal(t, []string{"api-new-1", "api-old-1"}, subjects[0].PodNames)
+	require.Len(t, subjects[0].Observations, 2)
+	assert.Equal(t, "aarch64", subjects[0].Observations[1].Arch)
+	assert.Equal(t, []string{"debug"}, subjects[1].PodNames)
+}
+
+func TestBuildSeccompProfiles_Baseline(t *testing.T) {
+	subject := seccompSubject{Name: "web", Namespace: "shop", Observations: []api.PodSysCall{
+		{Arch: "x86_64", Containers: map[string][]string{"app": {"custom_call"}}},
+	}}
+	opts := DefaultProfileOptions()
+	opts.Baseline = SeccompBaselineRuntimeDefault
+
+	profiles := buildSeccompProfiles(subject, opts)
+	require.Len(t, profiles, 1)
+	names := profiles[0].Profile.Syscalls[0].Names
+	assert.Contains(t, names, "custom_call")
+	assert.Contains(t, names, "rt_sigreturn")
+	assert.NotContains(t, names, "ptrace")
+
+	baseline, err := ParseSeccompBaseline("Runtime-Default")
+	require.NoError(t, err)
+	assert.Equal(t, SeccompBaselineRuntimeDefault, baseline)
+	_, err = ParseSeccompBaseline("docker")
+	assert.ErrorIs(t, err, ErrInvalidInput)
+}
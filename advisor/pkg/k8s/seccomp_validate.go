@@ -0,0 +1,307 @@
+package k8s
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	log "github.com/rs/zerolog/log"
+	"sigs.k8s.io/yaml"
+)
+
+// seccompActions are the actions runtimes accept for rules
+var seccompActions = []string{
+	"SCMP_ACT_KILL", "SCMP_ACT_KILL_PROCESS", "SCMP_ACT_KILL_THREAD", "SCMP_ACT_TRAP",
+	"SCMP_ACT_ERRNO", "SCMP_ACT_TRACE", "SCMP_ACT_ALLOW", "SCMP_ACT_LOG", "SCMP_ACT_NOTIFY",
+}
+
+// seccompOperators are the argument comparison operators
+var seccompOperators = []string{
+	"SCMP_CMP_NE", "SCMP_CMP_LT", "SCMP_CMP_LE", "SCMP_CMP_EQ", "SCMP_CMP_GE", "SCMP_CMP_GT", "SCMP_CMP_MASKED_EQ",
+}
+
+// seccompFilterFlags are the flags runtimes accept for loading the filter
+var seccompFilterFlags = []string{
+	"SECCOMP_FILTER_FLAG_TSYNC", "SECCOMP_FILTER_FLAG_LOG", "SECCOMP_FILTER_FLAG_SPEC_ALLOW", "SECCOMP_FILTER_FLAG_WAIT_KILLABLE_RECV",
+}
+
+// seccompArchitectureNames are the architectures libseccomp knows
+var seccompArchitectureNames = []string{
+	"SCMP_ARCH_X86", "SCMP_ARCH_X86_64", "SCMP_ARCH_X32", "SCMP_ARCH_ARM", "SCMP_ARCH_AARCH64",
+	"SCMP_ARCH_MIPS", "SCMP_ARCH_MIPS64", "SCMP_ARCH_MIPS64N32", "SCMP_ARCH_MIPSEL", "SCMP_ARCH_MIPSEL64",
+	"SCMP_ARCH_MIPSEL64N32", "SCMP_ARCH_PPC", "SCMP_ARCH_PPC64", "SCMP_ARCH_PPC64LE", "SCMP_ARCH_S390",
+	"SCMP_ARCH_S390X", "SCMP_ARCH_PARISC", "SCMP_ARCH_PARISC64", "SCMP_ARCH_RISCV64", "SCMP_ARCH_LOONGARCH64",
+	"SCMP_ARCH_M68K", "SCMP_ARCH_SH", "SCMP_ARCH_SHEB",
+}
+
+// maxSyscallArgs is the number of syscall arguments seccomp can compare
+const maxSyscallArgs = 6
+
+// ValidateProfile checks the profile for fields runtimes would reject or ignore, returning all
+// problems found
+func ValidateProfile(profile SeccompProfile) error {
+	var errs []error
+
+	if profile.DefaultAction == "" {
+		errs = append(errs, fmt.Errorf("default action is required"))
+	} else if !slices.Contains(seccompActions, profile.DefaultAction) {
+		errs = append(errs, fmt.Errorf("unknown default action %q", profile.DefaultAction))
+	} else if profile.DefaultAction == "SCMP_ACT_NOTIFY" {
+		errs = append(errs, fmt.Errorf("SCMP_ACT_NOTIFY cannot be the default action"))
+	}
+	if profile.DefaultErrnoRet != nil && !returnsErrno(profile.DefaultAction) {
+		errs = append(errs, fmt.Errorf("defaultErrnoRet requires an SCMP_ACT_ERRNO or SCMP_ACT_TRACE default action, not %s", profile.DefaultAction))
+	}
+
+	if len(profile.Architectures) == 0 && len(profile.ArchMap) == 0 {
+		errs = append(errs, fmt.Errorf("at least one architecture must be specified"))
+	}
+	if len(profile.Architectures) > 0 && len(profile.ArchMap) > 0 {
+		errs = append(errs, fmt.Errorf("architectures and archMap are mutually exclusive"))
+	}
+	for _, arch := range flattenArchitectures(profile) {
+		if !slices.Contains(seccompArchitectureNames, arch) {
+			errs = append(errs, fmt.Errorf("unknown architecture %q", arch))
+		}
+	}
+
+	for i, flag := range profile.Flags {
+		if !slices.Contains(seccompFilterFlags, flag) {
+			errs = append(errs, fmt.Errorf("unknown flag %q", flag))
+		} else if slices.Contains(profile.Flags[:i], flag) {
+			errs = append(errs, fmt.Errorf("duplicate flag %q", flag))
+		}
+	}
+
+	notify := slices.ContainsFunc(profile.Syscalls, func(rule Rule) bool { return rule.Action == "SCMP_ACT_NOTIFY" })
+	switch {
+	case notify && profile.ListenerPath == "":
+		errs = append(errs, fmt.Errorf("SCMP_ACT_NOTIFY rules require a listenerPath"))
+	case !notify && profile.ListenerPath != "":
+		errs = append(errs, fmt.Errorf("listenerPath is only used by SCMP_ACT_NOTIFY rules"))
+	}
+	if profile.ListenerMetadata != "" && profile.ListenerPath == "" {
+		errs = append(errs, fmt.Errorf("listenerMetadata requires a listenerPath"))
+	}
+
+	if len(profile.Syscalls) == 0 {
+		errs = append(errs, fmt.Errorf("at least one syscall rule must be specified"))
+	}
+	for i, rule := range profile.Syscalls {
+		if err := validateRule(rule); err != nil {
+			errs = append(errs, fmt.Errorf("syscall rule %d: %w", i, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateRule checks the names, action and arguments of a rule
+func validateRule(rule Rule) error {
+	var errs []error
+
+	if len(rule.Names) == 0 {
+		errs = append(errs, fmt.Errorf("at least one syscall name is required"))
+	}
+	if slices.Contains(rule.Names, "") {
+		errs = append(errs, fmt.Errorf("empty syscall name"))
+	}
+	if !slices.Contains(seccompActions, rule.Action) {
+		errs = append(errs, fmt.Errorf("unknown action %q", rule.Action))
+	}
+	if rule.ErrnoRet != nil && !returnsErrno(rule.Action) {
+		errs = append(errs, fmt.Errorf("errnoRet requires an SCMP_ACT_ERRNO or SCMP_ACT_TRACE action, not %s", rule.Action))
+	}
+	for _, arg := range rule.Args {
+		if arg.Index >= maxSyscallArgs {
+			errs = append(errs, fmt.Errorf("argument index %d is out of range, syscalls have %d arguments", arg.Index, maxSyscallArgs))
+		}
+		if !slices.Contains(seccompOperators, arg.Op) {
+			errs = append(errs, fmt.Errorf("unknown operator %q", arg.Op))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// returnsErrno reports whether the action returns an errno to the caller
+func returnsErrno(action string) bool {
+	return action == "SCMP_ACT_ERRNO" || action == "SCMP_ACT_TRACE"
+}
+
+// UnknownSyscalls returns the names no syscall table of the profile's architectures defines, which
+// are typos or syscalls of a newer kernel. Runtimes silently skip such names. Profiles only
+// targeting architectures without a table are not checked.
+func UnknownSyscalls(profile SeccompProfile) []string {
+	var tables []string
+	for _, arch := range flattenArchitectures(profile) {
+		if table, ok := scmpArchTables[arch]; ok && !slices.Contains(tables, table) {
+			tables = append(tables, table)
+		}
+	}
+	if len(tables) == 0 {
+		return nil
+	}
+
+	var unknown []string
+	for _, rule := range profile.Syscalls {
+		for _, name := range rule.Names {
+			if !slices.ContainsFunc(tables, func(table string) bool { return syscallExists(table, name) }) {
+				unknown = append(unknown, name)
+			}
+		}
+	}
+	sort.Strings(unknown)
+	return slices.Compact(unknown)
+}
+
+// DedupeSyscalls removes names repeated within a rule, or in an earlier rule with the same action,
+// errno and architectures that does not filter arguments. It returns the profile and the names
+// removed.
+func DedupeSyscalls(profile SeccompProfile) (SeccompProfile, []string) {
+	var removed []string
+	seen := make(map[string]map[string]bool)
+
+	rules := make([]Rule, 0, len(profile.Syscalls))
+	for _, rule := range profile.Syscalls {
+		key := ""
+		if len(rule.Args) == 0 {
+			key = ruleKey(rule)
+		}
+		if key != "" && seen[key] == nil {
+			seen[key] = make(map[string]bool)
+		}
+
+		var names []string
+		for _, name := range rule.Names {
+			if slices.Contains(names, name) || (key != "" && seen[key][name]) {
+				removed = append(removed, name)
+				continue
+			}
+			names = append(names, name)
+			if key != "" {
+				seen[key][name] = true
+			}
+		}
+		if len(names) == 0 {
+			continue
+		}
+		rule.Names = names
+		rules = append(rules, rule)
+	}
+
+	profile.Syscalls = rules
+	return profile, removed
+}
+
+// ruleKey identifies rules that apply to the same calls with the same outcome, apart from names
+func ruleKey(rule Rule) string {
+	key := rule.Action
+	if rule.ErrnoRet != nil {
+		key += fmt.Sprintf("/%d", *rule.ErrnoRet)
+	}
+	return key + "/" + ruleFilterKey(rule.Includes) + "/" + ruleFilterKey(rule.Excludes)
+}
+
+// ruleFilterKey identifies the calls a rule filter selects
+func ruleFilterKey(filter *RuleFilter) string {
+	if filter == nil {
+		return ""
+	}
+	return strings.Join(filter.Arches, ",") + ";" + strings.Join(filter.Caps, ",") + ";" + filter.MinKernel
+}
+
+// checkSeccompSyscalls removes duplicate names from a generated profile and warns about names the
+// syscall tables do not know, which are kept as the node's kernel may be newer than the tables
+func checkSeccompSyscalls(subject seccompSubject, containerProfile containerSeccompProfile) SeccompProfile {
+	profile, removed := DedupeSyscalls(containerProfile.Profile)
+	if len(removed) > 0 {
+		log.Debug().Msgf("Removed duplicate syscalls %v from the profile of %s", removed, seccompSubjectContainer(subject, containerProfile.Container))
+	}
+	if unknown := UnknownSyscalls(profile); len(unknown) > 0 {
+		log.Warn().Msgf("Syscalls %v of %s are unknown on %v, check for typos or syscalls of a newer kernel",
+			unknown, seccompSubjectContainer(subject, containerProfile.Container), flattenArchitectures(profile))
+	}
+	return profile
+}
+
+// ValidateSeccompFile validates a raw seccomp profile, or a SeccompProfile resource, and writes the
+// findings to out. With fix, duplicate names are removed from raw profiles in place, unless they
+// have fields the rewrite would drop. It returns whether the profile is valid.
+func ValidateSeccompFile(filename string, fix bool, out io.Writer) (bool, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return false, err
+	}
+	profile, isResource, err := parseSeccompProfile(data)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse %s: %w", filename, err)
+	}
+
+	var problems []string
+	validationErr := ValidateProfile(profile)
+	if validationErr != nil {
+		for _, line := range strings.Split(validationErr.Error(), "\n") {
+			problems = append(problems, fmt.Sprintf("  error: %s", line))
+		}
+	}
+	for _, name := range UnknownSyscalls(profile) {
+		problems = append(problems, fmt.Sprintf("  warning: %q is not a syscall on %s", name, strings.Join(flattenArchitectures(profile), ", ")))
+	}
+	deduped, removed := DedupeSyscalls(profile)
+	for _, name := range removed {
+		problems = append(problems, fmt.Sprintf("  warning: duplicate syscall %q", name))
+	}
+
+	valid := validationErr == nil
+	status := "valid"
+	if !valid {
+		status = "invalid"
+	}
+	fmt.Fprintf(out, "%s: %s\n", filename, status)
+	for _, problem := range problems {
+		fmt.Fprintln(out, problem)
+	}
+
+	if fix && len(removed) > 0 {
+		if isResource {
+			return valid, fmt.Errorf("--fix only rewrites raw profiles, edit the SeccompProfile resource %s instead", filename)
+		}
+		// Rewriting the profile would drop the fields SeccompProfile does not know
+		if err := yaml.UnmarshalStrict(data, &SeccompProfile{}); err != nil {
+			return valid, fmt.Errorf("--fix cannot rewrite %s without losing fields, remove the duplicates by hand: %w", filename, err)
+		}
+		if _, err := writeSeccompProfile(filepath.Dir(filename), filepath.Base(filename), deduped); err != nil {
+			return valid, err
+		}
+		fmt.Fprintf(out, "Removed %d duplicate syscalls from %s\n", len(removed), filename)
+	}
+	return valid, nil
+}
+
+// parseSeccompProfile reads a raw JSON profile, or the spec of a SeccompProfile resource in YAML or
+// JSON, reporting whether it was a resource
+func parseSeccompProfile(data []byte) (SeccompProfile, bool, error) {
+	var resource struct {
+		Kind string                 `json:"kind"`
+		Spec map[string]interface{} `json:"spec"`
+	}
+	if err := yaml.Unmarshal(data, &resource); err != nil {
+		return SeccompProfile{}, false, err
+	}
+
+	var profile SeccompProfile
+	if resource.Kind == spoKind {
+		spec, err := yaml.Marshal(resource.Spec)
+		if err != nil {
+			return profile, true, err
+		}
+		return profile, true, yaml.Unmarshal(spec, &profile)
+	}
+	return profile, false, yaml.Unmarshal(data, &profile)
+}
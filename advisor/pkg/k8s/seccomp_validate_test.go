@@ -0,0 +1,224 @@
+package k8s
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func validTestProfile() SeccompProfile {
+	return SeccompProfile{
+		DefaultAction: "SCMP_ACT_ERRNO",
+		ArchMap:       archMapFor([]string{"amd64"}),
+		Syscalls:      []Rule{{Names: []string{"read", "write"}, Action: "SCMP_ACT_ALLOW"}},
+	}
+}
+
+func TestValidateProfile(t *testing.T) {
+	require.NoError(t, ValidateProfile(validTestProfile()))
+	require.NoError(t, ValidateProfile(newSeccompProfile([]string{"read"}, []string{"amd64"}, DefaultProfileOptions())))
+
+	tests := []struct {
+		name     string
+		modify   func(*SeccompProfile)
+		expected string
+	}{
+		{"missing default action", func(p *SeccompProfile) { p.DefaultAction = "" }, "default action is required"},
+		{"unknown default action", func(p *SeccompProfile) { p.DefaultAction = "SCMP_ACT_DENY" }, `unknown default action "SCMP_ACT_DENY"`},
+		{"notify default action", func(p *SeccompProfile) { p.DefaultAction = "SCMP_ACT_NOTIFY" }, "cannot be the default action"},
+		{"defaultErrnoRet without errno", func(p *SeccompProfile) { p.DefaultAction = "SCMP_ACT_LOG"; p.DefaultErrnoRet = errnoRet(1) }, "defaultErrnoRet requires"},
+		{"no architectures", func(p *SeccompProfile) { p.ArchMap = nil }, "at least one architecture"},
+		{"architectures and archMap", func(p *SeccompProfile) { p.Architectures = []string{"SCMP_ARCH_X86_64"} }, "mutually exclusive"},
+		{"unknown architecture", func(p *SeccompProfile) { p.ArchMap = nil; p.Architectures = []string{"SCMP_ARCH_Z80"} }, `unknown architecture "SCMP_ARCH_Z80"`},
+		{"unknown flag", func(p *SeccompProfile) { p.Flags = []string{"SECCOMP_FILTER_FLAG_FAST"} }, `unknown flag "SECCOMP_FILTER_FLAG_FAST"`},
+		{"duplicate flag", func(p *SeccompProfile) { p.Flags = []string{"SECCOMP_FILTER_FLAG_LOG", "SECCOMP_FILTER_FLAG_LOG"} }, "duplicate flag"},
+		{"notify without listener", func(p *SeccompProfile) {
+			p.Syscalls = append(p.Syscalls, Rule{Names: []string{"mount"}, Action: "SCMP_ACT_NOTIFY"})
+		}, "require a listenerPath"},
+		{"listener without notify", func(p *SeccompProfile) { p.ListenerPath = "/run/agent.sock" }, "only used by SCMP_ACT_NOTIFY rules"},
+		{"metadata without listener", func(p *SeccompProfile) { p.ListenerMetadata = "agent" }, "listenerMetadata requires a listenerPath"},
+		{"no rules", func(p *SeccompProfile) { p.Syscalls = nil }, "at least one syscall rule"},
+		{"empty name", func(p *SeccompProfile) { p.Syscalls[0].Names = []string{""} }, "syscall rule 0: empty syscall name"},
+		{"errnoRet without errno", func(p *SeccompProfile) { p.Syscalls[0].ErrnoRet = errnoRet(1) }, "errnoRet requires"},
+		{"argument index", func(p *SeccompProfile) {
+			p.Syscalls[0].Args = []Arg{{Index: 6, Op: opEqualTo}}
+		}, "argument index 6 is out of range"},
+		{"unknown operator", func(p *SeccompProfile) {
+			p.Syscalls[0].Args = []Arg{{Index: 0, Op: "SCMP_CMP_XOR"}}
+		}, `unknown operator "SCMP_CMP_XOR"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile := validTestProfile()
+			tt.modify(&profile)
+			err := ValidateProfile(profile)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.expected)
+		})
+	}
+
+	t.Run("errno actions", func(t *testing.T) {
+		profile := validTestProfile()
+		profile.DefaultErrnoRet = errnoRet(1)
+		profile.Syscalls = append(profile.Syscalls, Rule{Names: []string{"clone3"}, Action: "SCMP_ACT_ERRNO", ErrnoRet: errnoRet(errnoENOSYS)})
+		profile.Flags = []string{"SECCOMP_FILTER_FLAG_SPEC_ALLOW"}
+		assert.NoError(t, ValidateProfile(profile))
+	})
+}
+
+func TestUnknownSyscalls(t *testing.T) {
+	profile := validTestProfile()
+	profile.Syscalls = []Rule{
+		{Names: []string{"read", "reed", "socketcall", "open"}, Action: "SCMP_ACT_ALLOW"},
+		{Names: []string{"reed"}, Action: "SCMP_ACT_LOG"},
+	}
+	// socketcall only exists on the x86 sub-architecture
+	assert.Equal(t, []string{"reed"}, UnknownSyscalls(profile))
+
+	profile.ArchMap = archMapFor([]string{"arm64"})
+	assert.Equal(t, []string{"reed", "socketcall"}, UnknownSyscalls(profile), "open exists on the arm sub-architecture")
+
+	profile.ArchMap = nil
+	profile.Architectures = []string{"SCMP_ARCH_AARCH64"}
+	assert.Equal(t, []string{"open", "reed", "socketcall"}, UnknownSyscalls(profile))
+
+	profile.Architectures = []string{"SCMP_ARCH_S390X"}
+	assert.Empty(t, UnknownSyscalls(profile), "architectures without a table are not checked")
+}
+
+func TestDedupeSyscalls(t *testing.T) {
+	profile := validTestProfile()
+	profile.Syscalls = []Rule{
+		{Names: []string{"read", "write", "read"}, Action: "SCMP_ACT_ALLOW"},
+		{Names: []string{"write", "open"}, Action: "SCMP_ACT_ALLOW"},
+		{Names: []string{"write"}, Action: "SCMP_ACT_LOG"},
+		{Names: []string{"open"}, Action: "SCMP_ACT_ALLOW", Includes: &RuleFilter{Arches: []string{"amd64"}}},
+		{Names: []string{"socket"}, Action: "SCMP_ACT_ALLOW", Args: []Arg{{Index: 0, Value: afUnix, Op: opEqualTo}}},
+		{Names: []string{"socket"}, Action: "SCMP_ACT_ALLOW", Args: []Arg{{Index: 0, Value: afInet, Op: opEqualTo}}},
+		{Names: []string{"read"}, Action: "SCMP_ACT_ALLOW"},
+	}
+
+	deduped, removed := DedupeSyscalls(profile)
+	assert.Equal(t, []string{"read", "write", "read"}, removed)
+	assert.Equal(t, []Rule{
+		{Names: []string{"read", "write"}, Action: "SCMP_ACT_ALLOW"},
+		{Names: []string{"open"}, Action: "SCMP_ACT_ALLOW"},
+		{Names: []string{"write"}, Action: "SCMP_ACT_LOG"},
+		{Names: []string{"open"}, Action: "SCMP_ACT_ALLOW", Includes: &RuleFilter{Arches: []string{"amd64"}}},
+		profile.Syscalls[4],
+		profile.Syscalls[5],
+	}, deduped.Syscalls)
+	assert.Len(t, profile.Syscalls, 7, "the input profile is not modified")
+}
+
+func TestValidateSeccompFile(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("valid with fix", func(t *testing.T) {
+		profile := validTestProfile()
+		profile.Syscalls[0].Names = []string{"read", "write", "read", "reed"}
+		data, err := json.Marshal(profile)
+		require.NoError(t, err)
+		filename := filepath.Join(dir, "web.json")
+		require.NoError(t, os.WriteFile(filename, data, 0644))
+
+		var out bytes.Buffer
+		valid, err := ValidateSeccompFile(filename, true, &out)
+		require.NoError(t, err)
+		assert.True(t, valid)
+		assert.Contains(t, out.String(), filename+": valid")
+		assert.Contains(t, out.String(), `warning: "reed" is not a syscall`)
+		assert.Contains(t, out.String(), `warning: duplicate syscall "read"`)
+
+		data, err = os.ReadFile(filename)
+		require.NoError(t, err)
+		var fixed SeccompProfile
+		require.NoError(t, json.Unmarshal(data, &fixed))
+		assert.Equal(t, []string{"read", "write", "reed"}, fixed.Syscalls[0].Names)
+	})
+
+	t.Run("moby profile with fix", func(t *testing.T) {
+		filename := filepath.Join(dir, "moby.json")
+		require.NoError(t, os.WriteFile(filename, []byte(`{
+	"defaultAction": "SCMP_ACT_ERRNO",
+	"defaultErrnoRet": 1,
+	"archMap": [{"architecture": "SCMP_ARCH_X86_64", "subArchitectures": ["SCMP_ARCH_X86", "SCMP_ARCH_X32"]}],
+	"syscalls": [
+		{"names": ["read", "write", "read"], "action": "SCMP_ACT_ALLOW"},
+		{"names": ["bpf", "perf_event_open"], "action": "SCMP_ACT_ALLOW", "comment": "allowed for CAP_SYS_ADMIN", "includes": {"caps": ["CAP_SYS_ADMIN"]}},
+		{"names": ["bpf"], "action": "SCMP_ACT_ALLOW", "includes": {"caps": ["CAP_BPF"]}},
+		{"names": ["ptrace"], "action": "SCMP_ACT_ALLOW", "includes": {"minKernel": "4.8"}},
+		{"names": ["ptrace"], "action": "SCMP_ACT_ALLOW", "excludes": {"caps": ["CAP_SYS_PTRACE"]}}
+	]
+}`), 0644))
+
+		var out bytes.Buffer
+		valid, err := ValidateSeccompFile(filename, true, &out)
+		require.NoError(t, err)
+		assert.True(t, valid)
+		assert.Contains(t, out.String(), "Removed 1 duplicate syscalls")
+
+		data, err := os.ReadFile(filename)
+		require.NoError(t, err)
+		var fixed SeccompProfile
+		require.NoError(t, json.Unmarshal(data, &fixed))
+		require.Len(t, fixed.Syscalls, 5, "rules with different filters are kept")
+		assert.Equal(t, []string{"read", "write"}, fixed.Syscalls[0].Names)
+		assert.Equal(t, "allowed for CAP_SYS_ADMIN", fixed.Syscalls[1].Comment)
+		assert.Equal(t, &RuleFilter{Caps: []string{"CAP_BPF"}}, fixed.Syscalls[2].Includes)
+		assert.Equal(t, &RuleFilter{MinKernel: "4.8"}, fixed.Syscalls[3].Includes)
+		assert.Equal(t, &RuleFilter{Caps: []string{"CAP_SYS_PTRACE"}}, fixed.Syscalls[4].Excludes)
+	})
+
+	t.Run("fix refuses unknown fields", func(t *testing.T) {
+		filename := filepath.Join(dir, "legacy.json")
+		original := []byte(`{
+	"defaultAction": "SCMP_ACT_ERRNO",
+	"architectures": ["SCMP_ARCH_X86_64"],
+	"syscalls": [
+		{"names": ["read", "read"], "action": "SCMP_ACT_ALLOW"},
+		{"name": "write", "action": "SCMP_ACT_ALLOW"}
+	]
+}`)
+		require.NoError(t, os.WriteFile(filename, original, 0644))
+
+		_, err := ValidateSeccompFile(filename, true, &bytes.Buffer{})
+		assert.ErrorContains(t, err, "without losing fields")
+		data, err := os.ReadFile(filename)
+		require.NoError(t, err)
+		assert.Equal(t, original, data, "the profile is not rewritten")
+	})
+
+	t.Run("invalid resource", func(t *testing.T) {
+		filename := filepath.Join(dir, "web.yaml")
+		require.NoError(t, os.WriteFile(filename, []byte(`apiVersion: security-profiles-operator.x-k8s.io/v1beta1
+kind: SeccompProfile
+metadata:
+  name: web
+spec:
+  defaultAction: SCMP_ACT_ERRNO
+  listenerPath: /run/agent.sock
+  syscalls:
+  - names: [read]
+    action: SCMP_ACT_ALLOW
+`), 0644))
+
+		var out bytes.Buffer
+		valid, err := ValidateSeccompFile(filename, false, &out)
+		require.NoError(t, err)
+		assert.False(t, valid)
+		assert.Contains(t, out.String(), filename+": invalid")
+		assert.Contains(t, out.String(), "error: at least one architecture must be specified")
+		assert.Contains(t, out.String(), "error: listenerPath is only used by SCMP_ACT_NOTIFY rules")
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := ValidateSeccompFile(filepath.Join(dir, "missing.json"), false, &bytes.Buffer{})
+		assert.Error(t, err)
+	})
+}
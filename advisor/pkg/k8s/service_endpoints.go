@@ -0,0 +1,117 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+
+	log "github.com/rs/zerolog/log"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ServiceEndpoints holds the ready endpoint addresses of a Service and the ports they listen on
+type ServiceEndpoints struct {
+	Addresses []string
+	// Ports are named after the Service port they implement
+	Ports []discoveryv1.EndpointPort
+}
+
+// Function variables for mocking in tests
+var (
+	getServiceEndpointsFunc     = getServiceEndpoints     // Internal function
+	getExternalNameServicesFunc = getExternalNameServices // Internal function
+	lookupHostFunc              = net.DefaultResolver.LookupHost
+)
+
+// GetServiceEndpoints collects the ready endpoints of a Service from its EndpointSlices
+func GetServiceEndpoints(ctx context.Context, config *Config, namespace, name string) (*ServiceEndpoints, error) {
+	return getServiceEndpointsFunc(ctx, config, namespace, name)
+}
+
+func getServiceEndpoints(ctx context.Context, config *Config, namespace, name string) (*ServiceEndpoints, error) {
+	if config == nil || config.Clientset == nil {
+		return nil, fmt.Errorf("not connected to a Kubernetes server")
+	}
+	return serviceEndpoints(ctx, config.Clientset, namespace, name)
+}
+
+// serviceEndpoints collects the Service endpoints using any clientset implementation
+func serviceEndpoints(ctx context.Context, clientset kubernetes.Interface, namespace, name string) (*ServiceEndpoints, error) {
+	endpointSlices, err := clientset.DiscoveryV1().EndpointSlices(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", discoveryv1.LabelServiceName, name),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the endpoint slices of service %s/%s: %w", namespace, name, err)
+	}
+
+	endpoints := &ServiceEndpoints{}
+	for _, slice := range endpointSlices.Items {
+		for _, endpoint := range slice.Endpoints {
+			// A nil ready condition means the endpoint is ready
+			if endpoint.Conditions.Ready != nil && !*endpoint.Conditions.Ready {
+				continue
+			}
+			endpoints.Addresses = appendUnique(endpoints.Addresses, endpoint.Addresses...)
+		}
+		for _, port := range slice.Ports {
+			if !slices.ContainsFunc(endpoints.Ports, func(existing discoveryv1.EndpointPort) bool {
+				return equality.Semantic.DeepEqual(existing, port)
+			}) {
+				endpoints.Ports = append(endpoints.Ports, port)
+			}
+		}
+	}
+
+	log.Debug().Msgf("Found endpoints %v for service %s/%s", endpoints.Addresses, namespace, name)
+	return endpoints, nil
+}
+
+// GetExternalNameServices maps the addresses the external names of the cluster's ExternalName
+// Services currently resolve to onto those Services. The controller only records Services by
+// ClusterIP, which ExternalName Services lack, so clients reaching them are recorded with the
+// resolved address.
+func GetExternalNameServices(ctx context.Context, config *Config) (map[string]corev1.Service, error) {
+	return getExternalNameServicesFunc(ctx, config)
+}
+
+func getExternalNameServices(ctx context.Context, config *Config) (map[string]corev1.Service, error) {
+	if config == nil || config.Clientset == nil {
+		return nil, fmt.Errorf("not connected to a Kubernetes server")
+	}
+	return externalNameServices(ctx, config.Clientset)
+}
+
+// externalNameServices resolves the ExternalName Services using any clientset implementation.
+// Names that do not resolve from here, e.g. cluster-internal names, are skipped.
+func externalNameServices(ctx context.Context, clientset kubernetes.Interface) (map[string]corev1.Service, error) {
+	services, err := clientset.CoreV1().Services(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list services: %w", err)
+	}
+
+	byAddress := make(map[string]corev1.Service)
+	for _, svc := range services.Items {
+		if svc.Spec.Type != corev1.ServiceTypeExternalName || svc.Spec.ExternalName == "" {
+			continue
+		}
+		addresses, err := lookupHostFunc(ctx, svc.Spec.ExternalName)
+		if err != nil {
+			log.Debug().Err(err).Msgf("Unable to resolve %s of ExternalName service %s/%s", svc.Spec.ExternalName, svc.Namespace, svc.Name)
+			continue
+		}
+		for _, address := range addresses {
+			if existing, ok := byAddress[address]; ok {
+				log.Debug().Msgf("Address %s of ExternalName service %s/%s is shared with %s/%s, keeping the first",
+					address, svc.Namespace, svc.Name, existing.Namespace, existing.Name)
+				continue
+			}
+			byAddress[address] = svc
+		}
+	}
+	return byAddress, nil
+}
@@ -0,0 +1,102 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestServiceEndpoints(t *testing.T) {
+	portName := "https"
+	port := int32(6443)
+	protocol := corev1.ProtocolTCP
+	notReady := false
+	endpointPorts := []discoveryv1.EndpointPort{{Name: &portName, Port: &port, Protocol: &protocol}}
+
+	clientset := fake.NewSimpleClientset(
+		&discoveryv1.EndpointSlice{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "kubernetes",
+				Namespace: metav1.NamespaceDefault,
+				Labels:    map[string]string{discoveryv1.LabelServiceName: "kubernetes"},
+			},
+			Endpoints: []discoveryv1.Endpoint{
+				{Addresses: []string{"172.18.0.2"}},
+				{Addresses: []string{"172.18.0.3"}, Conditions: discoveryv1.EndpointConditions{Ready: &notReady}},
+			},
+			Ports: endpointPorts,
+		},
+		&discoveryv1.EndpointSlice{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "kubernetes-v6",
+				Namespace: metav1.NamespaceDefault,
+				Labels:    map[string]string{discoveryv1.LabelServiceName: "kubernetes"},
+			},
+			Endpoints: []discoveryv1.Endpoint{{Addresses: []string{"fd00::2"}}},
+			Ports:     endpointPorts,
+		},
+		&discoveryv1.EndpointSlice{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "other",
+				Namespace: metav1.NamespaceDefault,
+				Labels:    map[string]string{discoveryv1.LabelServiceName: "other"},
+			},
+			Endpoints: []discoveryv1.Endpoint{{Addresses: []string{"10.0.0.9"}}},
+		},
+	)
+
+	endpoints, err := serviceEndpoints(context.Background(), clientset, metav1.NamespaceDefault, "kubernetes")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"172.18.0.2", "fd00::2"}, endpoints.Addresses)
+	assert.Equal(t, endpointPorts, endpoints.Ports)
+}
+
+func TestGetServiceEndpoints_NotConnected(t *testing.T) {
+	_, err := GetServiceEndpoints(context.Background(), nil, metav1.NamespaceDefault, "kubernetes")
+	assert.Error(t, err)
+}
+
+func TestExternalNameServices(t *testing.T) {
+	origLookupHostFunc := lookupHostFunc
+	defer func() { lookupHostFunc = origLookupHostFunc }()
+	lookupHostFunc = func(ctx context.Context, host string) ([]string, error) {
+		switch host {
+		case "api.payments.example.com":
+			return []string{"203.0.113.50", "2001:db8::50"}, nil
+		case "payments.example.com":
+			return []string{"203.0.113.50"}, nil
+		}
+		return nil, assert.AnError
+	}
+
+	externalName := func(namespace, name, externalName string) *corev1.Service {
+		return &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeExternalName, ExternalName: externalName},
+		}
+	}
+	clientset := fake.NewSimpleClientset(
+		externalName("shop", "payments-api", "api.payments.example.com"),
+		externalName("shop", "payments-alias", "payments.example.com"),
+		externalName("data", "db", "db.internal.svc.cluster.local"),
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+			Spec:       corev1.ServiceSpec{ClusterIP: "10.96.0.20"},
+		},
+	)
+
+	services, err := externalNameServices(context.Background(), clientset)
+	require.NoError(t, err)
+	require.Len(t, services, 2, "unresolvable names and ClusterIP services are skipped")
+	assert.Equal(t, "api.payments.example.com", services["2001:db8::50"].Spec.ExternalName)
+	assert.Contains(t, []string{"payments-api", "payments-alias"}, services["203.0.113.50"].Name)
+
+	_, err = GetExternalNameServices(context.Background(), nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,62 @@
+package k8s
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	log "github.com/rs/zerolog/log"
+	"k8s.io/apimachinery/pkg/version"
+)
+
+// endPortMinorVersion is the first Kubernetes 1.x release where NetworkPolicy endPort is GA
+const endPortMinorVersion = 25
+
+// Function variables for mocking in tests
+var (
+	getServerVersionFunc = getServerVersion // Internal function
+)
+
+// SupportsEndPort reports whether the cluster's API server accepts endPort in NetworkPolicy ports.
+// Unknown server versions are treated as unsupported.
+func SupportsEndPort(config *Config) bool {
+	info, err := getServerVersionFunc(config)
+	if err != nil {
+		log.Warn().Err(err).Msg("Unable to detect the Kubernetes server version, assuming endPort is not supported")
+		return false
+	}
+
+	supported, err := versionAtLeast(info, 1, endPortMinorVersion)
+	if err != nil {
+		log.Warn().Err(err).Msg("Unable to parse the Kubernetes server version, assuming endPort is not supported")
+		return false
+	}
+	log.Debug().Msgf("Kubernetes server version %s, endPort supported: %t", info.GitVersion, supported)
+	return supported
+}
+
+// getServerVersion fetches the version of the API server
+func getServerVersion(config *Config) (*version.Info, error) {
+	if config == nil || config.Clientset == nil {
+		return nil, fmt.Errorf("not connected to a Kubernetes server")
+	}
+	return config.Clientset.Discovery().ServerVersion()
+}
+
+// versionAtLeast compares a server version to major.minor. Managed distributions report minor
+// versions such as "27+", so non-numeric suffixes are ignored.
+func versionAtLeast(info *version.Info, major, minor int) (bool, error) {
+	serverMajor, err := strconv.Atoi(strings.TrimRight(info.Major, "+"))
+	if err != nil {
+		return false, fmt.Errorf("invalid major version %q: %w", info.Major, err)
+	}
+	serverMinor, err := strconv.Atoi(strings.TrimRight(info.Minor, "+"))
+	if err != nil {
+		return false, fmt.Errorf("invalid minor version %q: %w", info.Minor, err)
+	}
+
+	if serverMajor != major {
+		return serverMajor > major, nil
+	}
+	return serverMinor >= minor, nil
+}
@@ -0,0 +1,55 @@
+package k8s
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/version"
+)
+
+func TestVersionAtLeast(t *testing.T) {
+	tests := []struct {
+		name     string
+		info     version.Info
+		expected bool
+	}{
+		{name: "older minor", info: version.Info{Major: "1", Minor: "24"}, expected: false},
+		{name: "same minor", info: version.Info{Major: "1", Minor: "25"}, expected: true},
+		{name: "newer minor", info: version.Info{Major: "1", Minor: "33"}, expected: true},
+		{name: "managed suffix", info: version.Info{Major: "1", Minor: "27+"}, expected: true},
+		{name: "newer major", info: version.Info{Major: "2", Minor: "0"}, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			supported, err := versionAtLeast(&tt.info, 1, 25)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, supported)
+		})
+	}
+
+	_, err := versionAtLeast(&version.Info{Major: "1", Minor: ""}, 1, 25)
+	assert.Error(t, err)
+}
+
+func TestSupportsEndPort(t *testing.T) {
+	origGetServerVersionFunc := getServerVersionFunc
+	defer func() { getServerVersionFunc = origGetServerVersionFunc }()
+
+	getServerVersionFunc = func(config *Config) (*version.Info, error) {
+		return &version.Info{Major: "1", Minor: "30", GitVersion: "v1.30.2"}, nil
+	}
+	assert.True(t, SupportsEndPort(&Config{}))
+
+	getServerVersionFunc = func(config *Config) (*version.Info, error) {
+		return &version.Info{Major: "1", Minor: "21", GitVersion: "v1.21.14"}, nil
+	}
+	assert.False(t, SupportsEndPort(&Config{}))
+
+	getServerVersionFunc = func(config *Config) (*version.Info, error) {
+		return nil, errors.New("connection refused")
+	}
+	assert.False(t, SupportsEndPort(&Config{}))
+}
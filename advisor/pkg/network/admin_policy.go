@@ -0,0 +1,291 @@
+package network
+
+import (
+	"fmt"
+
+	log "github.com/rs/zerolog/log"
+	"github.com/xentra-ai/advisor/pkg/api"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	anpv1alpha1 "sigs.k8s.io/network-policy-api/apis/v1alpha1"
+)
+
+const (
+	// anpAPIVersion is the API version of AdminNetworkPolicy and BaselineAdminNetworkPolicy
+	anpAPIVersion = "policy.networking.k8s.io/v1alpha1"
+	// BaselineAdminPolicyName is the name of the BaselineAdminNetworkPolicy, which is a singleton
+	BaselineAdminPolicyName = "default"
+	// AdminGuardrailsPolicyName is the name of the AdminNetworkPolicy holding the cluster guardrails
+	AdminGuardrailsPolicyName = "xentra-guardrails"
+	// namespaceNameLabel is set on every namespace by the API server
+	namespaceNameLabel = "kubernetes.io/metadata.name"
+	// maxAdminPolicyPriority is the highest priority an AdminNetworkPolicy accepts
+	maxAdminPolicyPriority = 1000
+)
+
+// AdminPolicyOptions configures the AdminNetworkPolicy generator
+type AdminPolicyOptions struct {
+	// Priority of the first per-workload AdminNetworkPolicy, lower numbers take precedence. Every
+	// further policy gets the next priority, as the precedence of overlapping policies with the
+	// same priority is undefined.
+	Priority int32
+	// GuardrailPriority of the guardrails AdminNetworkPolicy, it should precede the workload policies
+	GuardrailPriority int32
+	// MonitoringNamespaces are always allowed to reach every pod, e.g. for Prometheus scrapes
+	MonitoringNamespaces []string
+	// DeniedEgressCIDRs can never be reached, e.g. cloud instance metadata endpoints
+	DeniedEgressCIDRs []string
+}
+
+// DefaultAdminPolicyOptions returns the default AdminNetworkPolicy options
+func DefaultAdminPolicyOptions() AdminPolicyOptions {
+	return AdminPolicyOptions{
+		Priority:             50,
+		GuardrailPriority:    10,
+		MonitoringNamespaces: []string{"monitoring"},
+		DeniedEgressCIDRs:    []string{"169.254.169.254/32"},
+	}
+}
+
+// AdminPolicyGenerator generates AdminNetworkPolicy and BaselineAdminNetworkPolicy resources
+//
+// Every workload gets an AdminNetworkPolicy allowing its observed flows. The baseline consists of
+// a guardrails AdminNetworkPolicy, which namespace owners cannot override, and the
+// BaselineAdminNetworkPolicy denying all other traffic of the targeted namespaces.
+type AdminPolicyGenerator struct {
+	standard *StandardPolicyGenerator
+	options  AdminPolicyOptions
+	// generated counts the per-workload policies, which get consecutive priorities
+	generated int32
+}
+
+// NewAdminPolicyGenerator creates a new generator for AdminNetworkPolicy resources
+func NewAdminPolicyGenerator() *AdminPolicyGenerator {
+	return NewAdminPolicyGeneratorWithOptions(DefaultAdminPolicyOptions())
+}
+
+// NewAdminPolicyGeneratorWithOptions creates a new generator for AdminNetworkPolicy resources
+func NewAdminPolicyGeneratorWithOptions(options AdminPolicyOptions) *AdminPolicyGenerator {
+	return &AdminPolicyGenerator{
+		standard: NewStandardPolicyGenerator(),
+		options:  options,
+	}
+}
+
+// GetType returns the policy type
+func (g *AdminPolicyGenerator) GetType() PolicyType {
+	return AdminPolicy
+}
+
+// Generate creates an AdminNetworkPolicy allowing the observed traffic of the specified pod
+func (g *AdminPolicyGenerator) Generate(podName string, podTraffic []api.PodTraffic, podDetail *api.PodDetail) (interface{}, error) {
+	log.Info().Msgf("Generating admin network policy for pod %s", podName)
+
+	if podDetail == nil {
+		return nil, fmt.Errorf("pod detail is nil for pod %s", podName)
+	}
+
+	priority := g.options.Priority + g.generated
+	if priority > maxAdminPolicyPriority {
+		return nil, fmt.Errorf("no AdminNetworkPolicy priority up to %d left for pod %s/%s, lower --admin-priority", maxAdminPolicyPriority, podDetail.Namespace, podDetail.Name)
+	}
+	g.generated++
+
+	ingressRules, egressRules := g.standard.processTrafficRules(podTraffic, podDetail)
+
+	policy := &anpv1alpha1.AdminNetworkPolicy{
+		TypeMeta: CreateTypeMeta("AdminNetworkPolicy", anpAPIVersion),
+		// AdminNetworkPolicies are cluster scoped, so the namespace is part of the name
+		ObjectMeta: CreateObjectMeta(
+			GetPolicyName(fmt.Sprintf("%s-%s", podDetail.Namespace, podDetail.Name), "admin-policy"),
+			"",
+			CreateStandardLabels(podDetail.Name, "admin-policy"),
+		),
+		Spec: anpv1alpha1.AdminNetworkPolicySpec{
+			Priority: priority,
+			Subject: anpv1alpha1.AdminNetworkPolicySubject{
+				Pods: namespacedPod(podDetail.Namespace, podDetail.Pod.Labels),
+			},
+		},
+	}
+
+	for _, rule := range ingressRules {
+		peer := ResolvePeer(rule.PeerIP)
+		if peer.Kind == PeerExternal {
+			// AdminNetworkPolicy ingress peers can only be namespaces or pods
+			log.Warn().Msgf("Skipping ingress from external peer %s, AdminNetworkPolicy cannot select CIDRs for ingress", rule.PeerIP)
+			continue
+		}
+		policy.Spec.Ingress = append(policy.Spec.Ingress, anpv1alpha1.AdminNetworkPolicyIngressRule{
+			Name:   fmt.Sprintf("allow-from-%s", rule.PeerIP),
+			Action: anpv1alpha1.AdminNetworkPolicyRuleActionAllow,
+			From:   []anpv1alpha1.AdminNetworkPolicyIngressPeer{{Pods: namespacedPod(peer.Namespace, peer.Labels)}},
+			Ports:  adminPorts(rule.Ports),
+		})
+	}
+
+	for _, rule := range egressRules {
+		peer := ResolvePeer(rule.PeerIP)
+		egressPeer := anpv1alpha1.AdminNetworkPolicyEgressPeer{}
+		if peer.Kind == PeerExternal {
+			egressPeer.Networks = []anpv1alpha1.CIDR{anpv1alpha1.CIDR(hostCIDR(rule.PeerIP))}
+		} else {
+			egressPeer.Pods = namespacedPod(peer.Namespace, peer.Labels)
+		}
+		policy.Spec.Egress = append(policy.Spec.Egress, anpv1alpha1.AdminNetworkPolicyEgressRule{
+			Name:   fmt.Sprintf("allow-to-%s", rule.PeerIP),
+			Action: anpv1alpha1.AdminNetworkPolicyRuleActionAllow,
+			To:     []anpv1alpha1.AdminNetworkPolicyEgressPeer{egressPeer},
+			Ports:  adminPorts(rule.Ports),
+		})
+	}
+
+	if len(policy.Spec.Ingress) == 0 && len(policy.Spec.Egress) == 0 {
+		// An AdminNetworkPolicy without rules has no effect, the BaselineAdminNetworkPolicy denies the traffic
+		log.Warn().Msgf("No valid ingress or egress rules generated for pod %s, its traffic is left to the baseline", podName)
+	}
+
+	return policy, nil
+}
+
+// GenerateBaseline creates the guardrails AdminNetworkPolicy and the BaselineAdminNetworkPolicy
+// denying all traffic of the given namespaces that no higher-precedence policy allows
+func (g *AdminPolicyGenerator) GenerateBaseline(namespaces []string, opts BaselineOptions) ([]interface{}, error) {
+	if opts.Clusterwide {
+		log.Debug().Msg("Admin baseline policies are always clusterwide")
+	}
+
+	log.Info().Msgf("Generating admin baseline policies for namespaces %v", namespaces)
+
+	subject := anpv1alpha1.AdminNetworkPolicySubject{
+		Namespaces: &metav1.LabelSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: namespaceNameLabel, Operator: metav1.LabelSelectorOpIn, Values: namespaces},
+			},
+		},
+	}
+
+	guardrails := &anpv1alpha1.AdminNetworkPolicy{
+		TypeMeta:   CreateTypeMeta("AdminNetworkPolicy", anpAPIVersion),
+		ObjectMeta: CreateObjectMeta(AdminGuardrailsPolicyName, "", CreateStandardLabels("baseline", "admin-policy-guardrails")),
+		Spec: anpv1alpha1.AdminNetworkPolicySpec{
+			Priority: g.options.GuardrailPriority,
+			Subject:  subject,
+		},
+	}
+
+	if len(g.options.MonitoringNamespaces) > 0 {
+		guardrails.Spec.Ingress = append(guardrails.Spec.Ingress, anpv1alpha1.AdminNetworkPolicyIngressRule{
+			Name:   "allow-monitoring",
+			Action: anpv1alpha1.AdminNetworkPolicyRuleActionAllow,
+			From: []anpv1alpha1.AdminNetworkPolicyIngressPeer{
+				{Namespaces: namespaceNameSelector(g.options.MonitoringNamespaces...)},
+			},
+		})
+	}
+
+	if len(g.options.DeniedEgressCIDRs) > 0 {
+		networks := make([]anpv1alpha1.CIDR, 0, len(g.options.DeniedEgressCIDRs))
+		for _, cidr := range g.options.DeniedEgressCIDRs {
+			networks = append(networks, anpv1alpha1.CIDR(cidr))
+		}
+		guardrails.Spec.Egress = append(guardrails.Spec.Egress, anpv1alpha1.AdminNetworkPolicyEgressRule{
+			Name:   "deny-guardrail-cidrs",
+			Action: anpv1alpha1.AdminNetworkPolicyRuleActionDeny,
+			To:     []anpv1alpha1.AdminNetworkPolicyEgressPeer{{Networks: networks}},
+		})
+	}
+
+	dnsPorts := adminPorts(dnsNetworkPolicyPorts())
+	baseline := &anpv1alpha1.BaselineAdminNetworkPolicy{
+		TypeMeta:   CreateTypeMeta("BaselineAdminNetworkPolicy", anpAPIVersion),
+		ObjectMeta: CreateObjectMeta(BaselineAdminPolicyName, "", CreateStandardLabels("baseline", "baseline-admin-policy")),
+		Spec: anpv1alpha1.BaselineAdminNetworkPolicySpec{
+			Subject: subject,
+			Ingress: []anpv1alpha1.BaselineAdminNetworkPolicyIngressRule{
+				{
+					Name:   "default-deny",
+					Action: anpv1alpha1.BaselineAdminNetworkPolicyRuleActionDeny,
+					From:   []anpv1alpha1.AdminNetworkPolicyIngressPeer{{Namespaces: &metav1.LabelSelector{}}},
+				},
+			},
+			// Rules are evaluated in order, so DNS is allowed before everything else is denied
+			Egress: []anpv1alpha1.BaselineAdminNetworkPolicyEgressRule{
+				{
+					Name:   "allow-dns",
+					Action: anpv1alpha1.BaselineAdminNetworkPolicyRuleActionAllow,
+					To: []anpv1alpha1.BaselineAdminNetworkPolicyEgressPeer{
+						{Pods: namespacedPod(opts.DNSNamespace, opts.DNSLabels)},
+					},
+					Ports: dnsPorts,
+				},
+				{
+					Name:   "default-deny",
+					Action: anpv1alpha1.BaselineAdminNetworkPolicyRuleActionDeny,
+					To: []anpv1alpha1.BaselineAdminNetworkPolicyEgressPeer{
+						{Namespaces: &metav1.LabelSelector{}},
+						{Networks: []anpv1alpha1.CIDR{"0.0.0.0/0", "::/0"}},
+					},
+				},
+			},
+		},
+	}
+
+	if opts.AllowSameNamespace {
+		// v1alpha1 has no same-namespace peer, allowing all pods instead would widen the baseline
+		log.Warn().Msg("BaselineAdminNetworkPolicy cannot express same-namespace peers, ignoring the allow-same-namespace option")
+	}
+
+	return []interface{}{guardrails, baseline}, nil
+}
+
+// namespacedPod selects the pods with the given labels in a single namespace
+func namespacedPod(namespace string, podLabels map[string]string) *anpv1alpha1.NamespacedPod {
+	return &anpv1alpha1.NamespacedPod{
+		NamespaceSelector: *namespaceNameSelector(namespace),
+		PodSelector:       metav1.LabelSelector{MatchLabels: podLabels},
+	}
+}
+
+// namespaceNameSelector selects namespaces by name
+func namespaceNameSelector(namespaces ...string) *metav1.LabelSelector {
+	if len(namespaces) == 1 {
+		return &metav1.LabelSelector{MatchLabels: map[string]string{namespaceNameLabel: namespaces[0]}}
+	}
+	return &metav1.LabelSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: namespaceNameLabel, Operator: metav1.LabelSelectorOpIn, Values: namespaces},
+		},
+	}
+}
+
+// adminPorts converts NetworkPolicy ports to AdminNetworkPolicy ports
+func adminPorts(ports []networkingv1.NetworkPolicyPort) *[]anpv1alpha1.AdminNetworkPolicyPort {
+	if len(ports) == 0 {
+		return nil
+	}
+
+	adminPorts := make([]anpv1alpha1.AdminNetworkPolicyPort, 0, len(ports))
+	for _, port := range ports {
+		if port.Port == nil {
+			continue
+		}
+		protocol := corev1.ProtocolTCP
+		if port.Protocol != nil {
+			protocol = *port.Protocol
+		}
+		if port.Port.Type == intstr.String {
+			// Named ports are resolved on the selected pods and match any protocol
+			name := port.Port.StrVal
+			adminPorts = append(adminPorts, anpv1alpha1.AdminNetworkPolicyPort{NamedPort: &name})
+			continue
+		}
+		adminPorts = append(adminPorts, anpv1alpha1.AdminNetworkPolicyPort{
+			PortNumber: &anpv1alpha1.Port{Protocol: protocol, Port: port.Port.IntVal},
+		})
+	}
+
+	return &adminPorts
+}
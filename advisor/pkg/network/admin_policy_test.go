@@ -0,0 +1,143 @@
+package network
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xentra-ai/advisor/pkg/api"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	anpv1alpha1 "sigs.k8s.io/network-policy-api/apis/v1alpha1"
+	"sigs.k8s.io/yaml"
+)
+
+func TestAdminPolicyGenerator_Generate(t *testing.T) {
+	// --- Setup Mocks ---
+	origGetPodSpecFunc := api.GetPodSpecFunc
+	origGetSvcSpecFunc := api.GetSvcSpecFunc
+	defer func() {
+		api.GetPodSpecFunc = origGetPodSpecFunc
+		api.GetSvcSpecFunc = origGetSvcSpecFunc
+	}()
+
+	api.GetPodSpecFunc = func(ip string) (*api.PodDetail, error) {
+		if ip == "10.0.0.1" {
+			return mockPodDetail("frontend-0", "web", ip, map[string]string{"app": "frontend"}), nil
+		}
+		return nil, nil
+	}
+	api.GetSvcSpecFunc = func(ip string) (*api.SvcDetail, error) {
+		if ip == "10.0.0.2" {
+			return mockSvcDetail("postgres", "data", ip, map[string]string{"app": "postgres"}), nil
+		}
+		return nil, nil
+	}
+	// --- End Mocks ---
+
+	gen := NewAdminPolicyGenerator()
+	podDetail := mockPodDetail("api-pod", "prod", "192.168.1.10", map[string]string{"app": "api"})
+	podTraffic := []api.PodTraffic{
+		{SrcIP: "192.168.1.10", SrcPodPort: "8080", DstIP: "10.0.0.1", Protocol: corev1.ProtocolTCP, TrafficType: "INGRESS"},
+		{SrcIP: "192.168.1.10", SrcPodPort: "8080", DstIP: "203.0.113.7", Protocol: corev1.ProtocolTCP, TrafficType: "INGRESS"},
+		{SrcIP: "192.168.1.10", DstIP: "10.0.0.2", DstPort: "5432", Protocol: corev1.ProtocolTCP, TrafficType: "EGRESS"},
+		{SrcIP: "192.168.1.10", DstIP: "8.8.8.8", DstPort: "53", Protocol: corev1.ProtocolUDP, TrafficType: "EGRESS"},
+		{SrcIP: "192.168.1.10", DstIP: "2001:4860:4860::8888", DstPort: "53", Protocol: corev1.ProtocolUDP, TrafficType: "EGRESS"},
+	}
+
+	policyInterface, err := gen.Generate("api-pod", podTraffic, podDetail)
+	require.NoError(t, err)
+	policy, ok := policyInterface.(*anpv1alpha1.AdminNetworkPolicy)
+	require.True(t, ok)
+
+	assert.Equal(t, "AdminNetworkPolicy", policy.Kind)
+	assert.Equal(t, "policy.networking.k8s.io/v1alpha1", policy.APIVersion)
+	assert.Equal(t, "prod-api-pod-admin-policy", policy.Name)
+	assert.Empty(t, policy.Namespace)
+	assert.Equal(t, int32(50), policy.Spec.Priority)
+	require.NotNil(t, policy.Spec.Subject.Pods)
+	assert.Equal(t, map[string]string{"kubernetes.io/metadata.name": "prod"}, policy.Spec.Subject.Pods.NamespaceSelector.MatchLabels)
+	assert.Equal(t, map[string]string{"app": "api"}, policy.Spec.Subject.Pods.PodSelector.MatchLabels)
+
+	// External ingress peers cannot be expressed and are skipped
+	require.Len(t, policy.Spec.Ingress, 1)
+	ingress := policy.Spec.Ingress[0]
+	assert.Equal(t, anpv1alpha1.AdminNetworkPolicyRuleActionAllow, ingress.Action)
+	assert.Equal(t, map[string]string{"kubernetes.io/metadata.name": "web"}, ingress.From[0].Pods.NamespaceSelector.MatchLabels)
+	assert.Equal(t, map[string]string{"app": "frontend"}, ingress.From[0].Pods.PodSelector.MatchLabels)
+	require.NotNil(t, ingress.Ports)
+	assert.Equal(t, &anpv1alpha1.Port{Protocol: corev1.ProtocolTCP, Port: 8080}, (*ingress.Ports)[0].PortNumber)
+
+	require.Len(t, policy.Spec.Egress, 3)
+	assert.Equal(t, map[string]string{"kubernetes.io/metadata.name": "data"}, policy.Spec.Egress[0].To[0].Pods.NamespaceSelector.MatchLabels)
+	assert.Equal(t, []anpv1alpha1.CIDR{"8.8.8.8/32"}, policy.Spec.Egress[1].To[0].Networks)
+	assert.Equal(t, &anpv1alpha1.Port{Protocol: corev1.ProtocolUDP, Port: 53}, (*policy.Spec.Egress[1].Ports)[0].PortNumber)
+	assert.Equal(t, []anpv1alpha1.CIDR{"2001:4860:4860::8888/128"}, policy.Spec.Egress[2].To[0].Networks)
+}
+
+func TestAdminPolicyGenerator_Generate_DistinctPriorities(t *testing.T) {
+	options := DefaultAdminPolicyOptions()
+	options.Priority = 999
+	gen := NewAdminPolicyGeneratorWithOptions(options)
+
+	// Overlapping policies of the same priority have no defined order, so every policy gets its own
+	for _, expected := range []int32{999, 1000} {
+		podDetail := mockPodDetail(fmt.Sprintf("api-%d", expected), "prod", "192.168.1.10", map[string]string{"app": "api"})
+		policyInterface, err := gen.Generate(podDetail.Name, nil, podDetail)
+		require.NoError(t, err)
+		assert.Equal(t, expected, policyInterface.(*anpv1alpha1.AdminNetworkPolicy).Spec.Priority)
+	}
+
+	_, err := gen.Generate("api-overflow", nil, mockPodDetail("api-overflow", "prod", "192.168.1.10", map[string]string{"app": "api"}))
+	assert.Error(t, err)
+}
+
+func TestAdminPolicyGenerator_GenerateBaseline(t *testing.T) {
+	options := DefaultAdminPolicyOptions()
+	options.MonitoringNamespaces = []string{"monitoring", "observability"}
+	gen := NewAdminPolicyGeneratorWithOptions(options)
+
+	policies, err := gen.GenerateBaseline([]string{"prod", "dev"}, DefaultBaselineOptions())
+	require.NoError(t, err)
+	require.Len(t, policies, 2)
+
+	guardrails, ok := policies[0].(*anpv1alpha1.AdminNetworkPolicy)
+	require.True(t, ok)
+	assert.Equal(t, AdminGuardrailsPolicyName, guardrails.Name)
+	assert.Equal(t, int32(10), guardrails.Spec.Priority)
+	require.NotNil(t, guardrails.Spec.Subject.Namespaces)
+	assert.Equal(t, []string{"prod", "dev"}, guardrails.Spec.Subject.Namespaces.MatchExpressions[0].Values)
+
+	require.Len(t, guardrails.Spec.Ingress, 1)
+	assert.Equal(t, anpv1alpha1.AdminNetworkPolicyRuleActionAllow, guardrails.Spec.Ingress[0].Action)
+	assert.Equal(t, []metav1.LabelSelectorRequirement{
+		{Key: "kubernetes.io/metadata.name", Operator: metav1.LabelSelectorOpIn, Values: []string{"monitoring", "observability"}},
+	}, guardrails.Spec.Ingress[0].From[0].Namespaces.MatchExpressions)
+
+	require.Len(t, guardrails.Spec.Egress, 1)
+	assert.Equal(t, "deny-guardrail-cidrs", guardrails.Spec.Egress[0].Name)
+	assert.Equal(t, anpv1alpha1.AdminNetworkPolicyRuleActionDeny, guardrails.Spec.Egress[0].Action)
+	assert.Equal(t, []anpv1alpha1.CIDR{"169.254.169.254/32"}, guardrails.Spec.Egress[0].To[0].Networks)
+
+	baseline, ok := policies[1].(*anpv1alpha1.BaselineAdminNetworkPolicy)
+	require.True(t, ok)
+	assert.Equal(t, "default", baseline.Name)
+	require.Len(t, baseline.Spec.Ingress, 1)
+	assert.Equal(t, anpv1alpha1.BaselineAdminNetworkPolicyRuleActionDeny, baseline.Spec.Ingress[0].Action)
+
+	// DNS is allowed before everything else is denied
+	require.Len(t, baseline.Spec.Egress, 2)
+	assert.Equal(t, anpv1alpha1.BaselineAdminNetworkPolicyRuleActionAllow, baseline.Spec.Egress[0].Action)
+	assert.Equal(t, map[string]string{"k8s-app": "kube-dns"}, baseline.Spec.Egress[0].To[0].Pods.PodSelector.MatchLabels)
+	assert.Len(t, *baseline.Spec.Egress[0].Ports, 2)
+	assert.Equal(t, anpv1alpha1.BaselineAdminNetworkPolicyRuleActionDeny, baseline.Spec.Egress[1].Action)
+
+	policyYAML, err := yaml.Marshal(baseline)
+	require.NoError(t, err)
+	assert.Contains(t, string(policyYAML), "kind: BaselineAdminNetworkPolicy")
+}
+
+func TestAdminPolicyGenerator_GetType(t *testing.T) {
+	assert.Equal(t, AdminPolicy, NewAdminPolicyGenerator().GetType())
+}
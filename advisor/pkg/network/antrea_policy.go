@@ -0,0 +1,235 @@
+package network
+
+import (
+	"fmt"
+
+	log "github.com/rs/zerolog/log"
+	"github.com/xentra-ai/advisor/pkg/api"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AntreaPolicyOptions configures the Antrea policy generators
+type AntreaPolicyOptions struct {
+	// Tier of the per-workload policies, the static tiers are emergency, securityops, networkops,
+	// platform, application and baseline
+	Tier string
+	// Priority of the per-workload policies within their tier
+	Priority float64
+}
+
+// DefaultAntreaPolicyOptions returns the default Antrea policy options, placing the observed-traffic
+// policies in the application tier below the security-ops tier
+func DefaultAntreaPolicyOptions() AntreaPolicyOptions {
+	return AntreaPolicyOptions{
+		Tier:     "application",
+		Priority: 5,
+	}
+}
+
+// AntreaPolicyGenerator generates Antrea NetworkPolicy or ClusterNetworkPolicy resources
+//
+// The baseline is always a ClusterNetworkPolicy in Antrea's baseline tier, which is evaluated
+// after Kubernetes NetworkPolicies.
+type AntreaPolicyGenerator struct {
+	standard    *StandardPolicyGenerator
+	options     AntreaPolicyOptions
+	clusterwide bool
+}
+
+// NewAntreaPolicyGenerator creates a new generator for namespaced Antrea NetworkPolicy resources
+func NewAntreaPolicyGenerator() *AntreaPolicyGenerator {
+	return NewAntreaPolicyGeneratorWithOptions(DefaultAntreaPolicyOptions())
+}
+
+// NewAntreaPolicyGeneratorWithOptions creates a new generator for namespaced Antrea NetworkPolicy resources
+func NewAntreaPolicyGeneratorWithOptions(options AntreaPolicyOptions) *AntreaPolicyGenerator {
+	return &AntreaPolicyGenerator{
+		standard: NewStandardPolicyGenerator(),
+		options:  options,
+	}
+}
+
+// NewAntreaClusterPolicyGenerator creates a new generator for Antrea ClusterNetworkPolicy resources
+func NewAntreaClusterPolicyGenerator() *AntreaPolicyGenerator {
+	return NewAntreaClusterPolicyGeneratorWithOptions(DefaultAntreaPolicyOptions())
+}
+
+// NewAntreaClusterPolicyGeneratorWithOptions creates a new generator for Antrea ClusterNetworkPolicy resources
+func NewAntreaClusterPolicyGeneratorWithOptions(options AntreaPolicyOptions) *AntreaPolicyGenerator {
+	generator := NewAntreaPolicyGeneratorWithOptions(options)
+	generator.clusterwide = true
+	return generator
+}
+
+// GetType returns the policy type
+func (g *AntreaPolicyGenerator) GetType() PolicyType {
+	if g.clusterwide {
+		return AntreaClusterPolicy
+	}
+	return AntreaPolicy
+}
+
+// Generate creates an Antrea NetworkPolicy or ClusterNetworkPolicy for the specified pod
+func (g *AntreaPolicyGenerator) Generate(podName string, podTraffic []api.PodTraffic, podDetail *api.PodDetail) (interface{}, error) {
+	log.Info().Msgf("Generating Antrea %s for pod %s in tier %s", g.kind(), podName, g.options.Tier)
+
+	if podDetail == nil {
+		return nil, fmt.Errorf("pod detail is nil for pod %s", podName)
+	}
+
+	ingressRules, egressRules := g.standard.processTrafficRules(podTraffic, podDetail)
+
+	spec := AntreaPolicySpec{
+		Tier:     g.options.Tier,
+		Priority: g.options.Priority,
+	}
+
+	for _, rule := range ingressRules {
+		spec.Ingress = append(spec.Ingress, AntreaPolicyRule{
+			Name:   fmt.Sprintf("allow-from-%s", rule.PeerIP),
+			Action: AntreaActionAllow,
+			From:   []AntreaPeer{antreaPeer(rule.PeerIP)},
+			Ports:  antreaPorts(rule.Ports),
+		})
+	}
+
+	for _, rule := range egressRules {
+		spec.Egress = append(spec.Egress, AntreaPolicyRule{
+			Name:   fmt.Sprintf("allow-to-%s", rule.PeerIP),
+			Action: AntreaActionAllow,
+			To:     []AntreaPeer{antreaPeer(rule.PeerIP)},
+			Ports:  antreaPorts(rule.Ports),
+		})
+	}
+
+	if len(spec.Ingress) == 0 && len(spec.Egress) == 0 {
+		// Antrea policies without rules have no effect, the baseline denies the traffic
+		log.Warn().Msgf("No valid ingress or egress rules generated for pod %s, its traffic is left to the baseline", podName)
+	}
+
+	podSelector := &metav1.LabelSelector{MatchLabels: podDetail.Pod.Labels}
+
+	if g.clusterwide {
+		spec.AppliedTo = []AntreaPeer{{PodSelector: podSelector, NamespaceSelector: antreaNamespaceSelector(podDetail.Namespace)}}
+		return &AntreaClusterNetworkPolicy{
+			TypeMeta: CreateTypeMeta("ClusterNetworkPolicy", antreaAPIVersion),
+			// ClusterNetworkPolicies are cluster scoped, so the namespace is part of the name
+			ObjectMeta: CreateObjectMeta(
+				GetPolicyName(fmt.Sprintf("%s-%s", podDetail.Namespace, podDetail.Name), "antrea-cluster-policy"),
+				"",
+				CreateStandardLabels(podDetail.Name, "antrea-cluster-policy"),
+			),
+			Spec: spec,
+		}, nil
+	}
+
+	spec.AppliedTo = []AntreaPeer{{PodSelector: podSelector}}
+	return &AntreaNetworkPolicy{
+		TypeMeta: CreateTypeMeta("NetworkPolicy", antreaAPIVersion),
+		ObjectMeta: CreateObjectMeta(
+			GetPolicyName(podDetail.Name, "antrea-policy"),
+			podDetail.Namespace,
+			CreateStandardLabels(podDetail.Name, "antrea-policy"),
+		),
+		Spec: spec,
+	}, nil
+}
+
+// GenerateBaseline creates a ClusterNetworkPolicy in the baseline tier dropping all traffic of the
+// given namespaces except DNS and, optionally, traffic within the same namespace
+func (g *AntreaPolicyGenerator) GenerateBaseline(namespaces []string, opts BaselineOptions) ([]interface{}, error) {
+	log.Info().Msgf("Generating Antrea baseline policy for namespaces %v", namespaces)
+	if !opts.Clusterwide {
+		log.Debug().Msg("Antrea baseline tier policies are cluster scoped, generating a ClusterNetworkPolicy")
+	}
+
+	// Pods of all namespaces and every IP address, so traffic of nodes and external hosts is dropped too
+	allPeers := []AntreaPeer{
+		{NamespaceSelector: &metav1.LabelSelector{}},
+		{IPBlock: &AntreaIPBlock{CIDR: "0.0.0.0/0"}},
+		{IPBlock: &AntreaIPBlock{CIDR: "::/0"}},
+	}
+	spec := AntreaPolicySpec{
+		Tier:     "baseline",
+		Priority: g.options.Priority,
+		AppliedTo: []AntreaPeer{{
+			NamespaceSelector: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: namespaceNameLabel, Operator: metav1.LabelSelectorOpIn, Values: namespaces},
+				},
+			},
+		}},
+	}
+
+	if opts.AllowSameNamespace {
+		self := []AntreaPeer{{Namespaces: &AntreaPeerNamespaces{Match: AntreaNamespaceMatchSelf}}}
+		spec.Ingress = append(spec.Ingress, AntreaPolicyRule{Name: "allow-same-namespace", Action: AntreaActionAllow, From: self})
+		spec.Egress = append(spec.Egress, AntreaPolicyRule{Name: "allow-same-namespace", Action: AntreaActionAllow, To: self})
+	}
+
+	// Rules are evaluated in order, so the allow rules precede the drop rules
+	spec.Ingress = append(spec.Ingress, AntreaPolicyRule{Name: "default-deny", Action: AntreaActionDrop, From: allPeers})
+	spec.Egress = append(spec.Egress,
+		AntreaPolicyRule{
+			Name:   "allow-dns",
+			Action: AntreaActionAllow,
+			To: []AntreaPeer{{
+				PodSelector:       &metav1.LabelSelector{MatchLabels: opts.DNSLabels},
+				NamespaceSelector: antreaNamespaceSelector(opts.DNSNamespace),
+			}},
+			Ports: antreaPorts(dnsNetworkPolicyPorts()),
+		},
+		AntreaPolicyRule{
+			Name:   "default-deny",
+			Action: AntreaActionDrop,
+			To:     allPeers,
+		},
+	)
+
+	return []interface{}{
+		&AntreaClusterNetworkPolicy{
+			TypeMeta:   CreateTypeMeta("ClusterNetworkPolicy", antreaAPIVersion),
+			ObjectMeta: CreateObjectMeta(BaselineDenyAllName, "", CreateStandardLabels("baseline", "antrea-cluster-policy-deny-all")),
+			Spec:       spec,
+		},
+	}, nil
+}
+
+// kind returns the kind of the per-workload policies
+func (g *AntreaPolicyGenerator) kind() string {
+	if g.clusterwide {
+		return "ClusterNetworkPolicy"
+	}
+	return "NetworkPolicy"
+}
+
+// antreaPeer resolves a peer IP to a namespaced pod selector or a single-address IP block
+func antreaPeer(peerIP string) AntreaPeer {
+	peer := ResolvePeer(peerIP)
+	if peer.Kind == PeerExternal {
+		return AntreaPeer{IPBlock: &AntreaIPBlock{CIDR: hostCIDR(peerIP)}}
+	}
+
+	return AntreaPeer{
+		PodSelector:       &metav1.LabelSelector{MatchLabels: peer.Labels},
+		NamespaceSelector: antreaNamespaceSelector(peer.Namespace),
+	}
+}
+
+// antreaNamespaceSelector selects a namespace by name
+func antreaNamespaceSelector(namespace string) *metav1.LabelSelector {
+	return &metav1.LabelSelector{MatchLabels: map[string]string{namespaceNameLabel: namespace}}
+}
+
+// antreaPorts converts NetworkPolicy ports to Antrea ports
+func antreaPorts(ports []networkingv1.NetworkPolicyPort) []AntreaPort {
+	antreaPorts := make([]AntreaPort, 0, len(ports))
+	for _, port := range ports {
+		if port.Port == nil {
+			continue
+		}
+		antreaPorts = append(antreaPorts, AntreaPort{Protocol: port.Protocol, Port: port.Port, EndPort: port.EndPort})
+	}
+	return antreaPorts
+}
@@ -0,0 +1,139 @@
+package network
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xentra-ai/advisor/pkg/api"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+func TestAntreaPolicyGenerator_Generate(t *testing.T) {
+	// --- Setup Mocks ---
+	origGetPodSpecFunc := api.GetPodSpecFunc
+	origGetSvcSpecFunc := api.GetSvcSpecFunc
+	defer func() {
+		api.GetPodSpecFunc = origGetPodSpecFunc
+		api.GetSvcSpecFunc = origGetSvcSpecFunc
+	}()
+
+	api.GetPodSpecFunc = func(ip string) (*api.PodDetail, error) {
+		switch ip {
+		case "10.0.0.1":
+			return mockPodDetail("frontend-0", "web", ip, map[string]string{"app": "frontend"}), nil
+		case "10.0.0.2":
+			return mockPodDetail("db-0", "prod", ip, map[string]string{"app": "db"}), nil
+		}
+		return nil, nil
+	}
+	api.GetSvcSpecFunc = func(ip string) (*api.SvcDetail, error) { return nil, nil }
+	// --- End Mocks ---
+
+	gen := NewAntreaPolicyGeneratorWithOptions(AntreaPolicyOptions{Tier: "application", Priority: 10})
+	podDetail := mockPodDetail("api-pod", "prod", "192.168.1.10", map[string]string{"app": "api"})
+	podTraffic := []api.PodTraffic{
+		{SrcIP: "192.168.1.10", SrcPodPort: "8080", DstIP: "10.0.0.1", DstPort: "51234", Protocol: corev1.ProtocolTCP, TrafficType: "INGRESS"},
+		{SrcIP: "192.168.1.10", DstIP: "10.0.0.2", DstPort: "5432", Protocol: corev1.ProtocolTCP, TrafficType: "EGRESS"},
+		{SrcIP: "192.168.1.10", DstIP: "203.0.113.7", DstPort: "443", Protocol: corev1.ProtocolTCP, TrafficType: "EGRESS"},
+		{SrcIP: "192.168.1.10", DstIP: "2001:db8::7", DstPort: "443", Protocol: corev1.ProtocolTCP, TrafficType: "EGRESS"},
+	}
+
+	policyInterface, err := gen.Generate("api-pod", podTraffic, podDetail)
+	require.NoError(t, err)
+	policy, ok := policyInterface.(*AntreaNetworkPolicy)
+	require.True(t, ok)
+
+	assert.Equal(t, "NetworkPolicy", policy.Kind)
+	assert.Equal(t, "crd.antrea.io/v1beta1", policy.APIVersion)
+	assert.Equal(t, GetPolicyName("api-pod", "antrea-policy"), policy.Name)
+	assert.Equal(t, "prod", policy.Namespace)
+	assert.Equal(t, "application", policy.Spec.Tier)
+	assert.Equal(t, float64(10), policy.Spec.Priority)
+	require.Len(t, policy.Spec.AppliedTo, 1)
+	assert.Equal(t, map[string]string{"app": "api"}, policy.Spec.AppliedTo[0].PodSelector.MatchLabels)
+	assert.Nil(t, policy.Spec.AppliedTo[0].NamespaceSelector)
+
+	require.Len(t, policy.Spec.Ingress, 1)
+	ingress := policy.Spec.Ingress[0]
+	assert.Equal(t, AntreaActionAllow, ingress.Action)
+	assert.Equal(t, map[string]string{"app": "frontend"}, ingress.From[0].PodSelector.MatchLabels)
+	assert.Equal(t, map[string]string{namespaceNameLabel: "web"}, ingress.From[0].NamespaceSelector.MatchLabels)
+	require.Len(t, ingress.Ports, 1)
+	assert.Equal(t, "8080", ingress.Ports[0].Port.String())
+
+	require.Len(t, policy.Spec.Egress, 3)
+	assert.Equal(t, map[string]string{"app": "db"}, policy.Spec.Egress[0].To[0].PodSelector.MatchLabels)
+	assert.Equal(t, "203.0.113.7/32", policy.Spec.Egress[1].To[0].IPBlock.CIDR)
+	assert.Equal(t, "2001:db8::7/128", policy.Spec.Egress[2].To[0].IPBlock.CIDR)
+
+	policyYAML, err := yaml.Marshal(policy)
+	require.NoError(t, err)
+	assert.Contains(t, string(policyYAML), "tier: application")
+	assert.Contains(t, string(policyYAML), "action: Allow")
+}
+
+func TestAntreaClusterPolicyGenerator_Generate(t *testing.T) {
+	origGetPodSpecFunc := api.GetPodSpecFunc
+	origGetSvcSpecFunc := api.GetSvcSpecFunc
+	defer func() {
+		api.GetPodSpecFunc = origGetPodSpecFunc
+		api.GetSvcSpecFunc = origGetSvcSpecFunc
+	}()
+	api.GetPodSpecFunc = func(ip string) (*api.PodDetail, error) { return nil, nil }
+	api.GetSvcSpecFunc = func(ip string) (*api.SvcDetail, error) { return nil, nil }
+
+	gen := NewAntreaClusterPolicyGenerator()
+	podDetail := mockPodDetail("api-pod", "prod", "192.168.1.10", map[string]string{"app": "api"})
+
+	policyInterface, err := gen.Generate("api-pod", []api.PodTraffic{
+		{SrcIP: "192.168.1.10", DstIP: "203.0.113.7", DstPort: "443", Protocol: corev1.ProtocolTCP, TrafficType: "EGRESS"},
+	}, podDetail)
+	require.NoError(t, err)
+	policy, ok := policyInterface.(*AntreaClusterNetworkPolicy)
+	require.True(t, ok)
+
+	assert.Equal(t, "ClusterNetworkPolicy", policy.Kind)
+	assert.Equal(t, GetPolicyName("prod-api-pod", "antrea-cluster-policy"), policy.Name)
+	assert.Empty(t, policy.Namespace)
+	assert.Equal(t, DefaultAntreaPolicyOptions().Tier, policy.Spec.Tier)
+	// Cluster scoped policies pin the applied pods to their namespace
+	assert.Equal(t, map[string]string{namespaceNameLabel: "prod"}, policy.Spec.AppliedTo[0].NamespaceSelector.MatchLabels)
+}
+
+func TestAntreaPolicyGenerator_GenerateBaseline(t *testing.T) {
+	gen := NewAntreaPolicyGenerator()
+	opts := DefaultBaselineOptions()
+	opts.AllowSameNamespace = true
+
+	policies, err := gen.GenerateBaseline([]string{"prod", "staging"}, opts)
+	require.NoError(t, err)
+	require.Len(t, policies, 1)
+
+	policy, ok := policies[0].(*AntreaClusterNetworkPolicy)
+	require.True(t, ok)
+	assert.Equal(t, BaselineDenyAllName, policy.Name)
+	assert.Equal(t, "baseline", policy.Spec.Tier)
+	assert.Equal(t, []string{"prod", "staging"}, policy.Spec.AppliedTo[0].NamespaceSelector.MatchExpressions[0].Values)
+
+	// Allow rules must precede the drop rules
+	require.Len(t, policy.Spec.Ingress, 2)
+	assert.Equal(t, AntreaNamespaceMatchSelf, policy.Spec.Ingress[0].From[0].Namespaces.Match)
+	assert.Equal(t, AntreaActionDrop, policy.Spec.Ingress[1].Action)
+	require.Len(t, policy.Spec.Ingress[1].From, 3)
+	assert.Equal(t, "0.0.0.0/0", policy.Spec.Ingress[1].From[1].IPBlock.CIDR)
+	assert.Equal(t, "::/0", policy.Spec.Ingress[1].From[2].IPBlock.CIDR)
+
+	require.Len(t, policy.Spec.Egress, 3)
+	assert.Equal(t, "allow-dns", policy.Spec.Egress[1].Name)
+	assert.Equal(t, map[string]string{namespaceNameLabel: "kube-system"}, policy.Spec.Egress[1].To[0].NamespaceSelector.MatchLabels)
+	assert.Len(t, policy.Spec.Egress[1].Ports, 2)
+	assert.Equal(t, AntreaActionDrop, policy.Spec.Egress[2].Action)
+	assert.Equal(t, policy.Spec.Ingress[1].From, policy.Spec.Egress[2].To)
+}
+
+func TestAntreaPolicyGenerator_GetType(t *testing.T) {
+	assert.Equal(t, AntreaPolicy, NewAntreaPolicyGenerator().GetType())
+	assert.Equal(t, AntreaClusterPolicy, NewAntreaClusterPolicyGenerator().GetType())
+}
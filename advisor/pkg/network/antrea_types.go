@@ -0,0 +1,85 @@
+package network
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// The crd.antrea.io/v1beta1 policy resources, limited to the fields the generator emits.
+// They are declared locally to avoid depending on the Antrea API module.
+
+// antreaAPIVersion is the API version of the Antrea policy resources
+const antreaAPIVersion = "crd.antrea.io/v1beta1"
+
+// AntreaRuleAction is the action of an Antrea rule
+type AntreaRuleAction string
+
+const (
+	// AntreaActionAllow allows the matching traffic
+	AntreaActionAllow AntreaRuleAction = "Allow"
+	// AntreaActionDrop silently drops the matching traffic
+	AntreaActionDrop AntreaRuleAction = "Drop"
+)
+
+// AntreaNamespaceMatchSelf matches the namespace of the selected pods
+const AntreaNamespaceMatchSelf = "Self"
+
+// AntreaNetworkPolicy is a namespaced crd.antrea.io/v1beta1 NetworkPolicy
+type AntreaNetworkPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              AntreaPolicySpec `json:"spec"`
+}
+
+// AntreaClusterNetworkPolicy is a cluster scoped crd.antrea.io/v1beta1 ClusterNetworkPolicy
+type AntreaClusterNetworkPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              AntreaPolicySpec `json:"spec"`
+}
+
+// AntreaPolicySpec is the spec shared by Antrea NetworkPolicy and ClusterNetworkPolicy
+type AntreaPolicySpec struct {
+	// Tier the policy belongs to, e.g. securityops or application
+	Tier string `json:"tier,omitempty"`
+	// Priority orders the policies within a tier, lower values take precedence
+	Priority  float64            `json:"priority"`
+	AppliedTo []AntreaPeer       `json:"appliedTo"`
+	Ingress   []AntreaPolicyRule `json:"ingress,omitempty"`
+	Egress    []AntreaPolicyRule `json:"egress,omitempty"`
+}
+
+// AntreaPolicyRule is a single Antrea ingress or egress rule
+type AntreaPolicyRule struct {
+	Name   string           `json:"name,omitempty"`
+	Action AntreaRuleAction `json:"action"`
+	From   []AntreaPeer     `json:"from,omitempty"`
+	To     []AntreaPeer     `json:"to,omitempty"`
+	Ports  []AntreaPort     `json:"ports,omitempty"`
+}
+
+// AntreaPeer selects pods, namespaces or IP blocks
+type AntreaPeer struct {
+	PodSelector       *metav1.LabelSelector `json:"podSelector,omitempty"`
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	Namespaces        *AntreaPeerNamespaces `json:"namespaces,omitempty"`
+	IPBlock           *AntreaIPBlock        `json:"ipBlock,omitempty"`
+}
+
+// AntreaPeerNamespaces matches namespaces relative to the selected pods
+type AntreaPeerNamespaces struct {
+	Match string `json:"match,omitempty"`
+}
+
+// AntreaIPBlock matches a CIDR
+type AntreaIPBlock struct {
+	CIDR string `json:"cidr"`
+}
+
+// AntreaPort matches a port or port range
+type AntreaPort struct {
+	Protocol *corev1.Protocol    `json:"protocol,omitempty"`
+	Port     *intstr.IntOrString `json:"port,omitempty"`
+	EndPort  *int32              `json:"endPort,omitempty"`
+}
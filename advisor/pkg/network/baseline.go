@@ -0,0 +1,310 @@
+package network
+
+import (
+	"fmt"
+
+	ciliumio "github.com/cilium/cilium/pkg/k8s/apis/cilium.io"
+	ciliumv2 "github.com/cilium/cilium/pkg/k8s/apis/cilium.io/v2"
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	"github.com/cilium/cilium/pkg/labels"
+	ciliumapi "github.com/cilium/cilium/pkg/policy/api"
+	log "github.com/rs/zerolog/log"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/yaml"
+)
+
+// Names of the baseline policies, the per-workload allow policies are layered on top of them
+const (
+	BaselineDenyAllName       = "baseline-default-deny-all"
+	BaselineAllowDNSName      = "baseline-allow-dns"
+	BaselineSameNamespaceName = "baseline-allow-same-namespace"
+)
+
+// ciliumNamespaceLabelKey is the selector key of the Cilium label carrying an endpoint's namespace.
+// Cilium selectors built in code use the "<source>.<key>" form, serialized as "k8s:<key>".
+const ciliumNamespaceLabelKey = labels.LabelSourceK8sKeyPrefix + ciliumio.PodNamespaceLabel
+
+// BaselineOptions configures the namespace baseline policies
+type BaselineOptions struct {
+	// AllowSameNamespace adds a policy allowing all traffic between pods of the same namespace
+	AllowSameNamespace bool
+	// Clusterwide emits the deny and DNS baselines as a single clusterwide policy where supported
+	Clusterwide bool
+	// DNSNamespace is the namespace running the cluster DNS pods
+	DNSNamespace string
+	// DNSLabels select the cluster DNS pods
+	DNSLabels map[string]string
+}
+
+// DefaultBaselineOptions returns baseline options targeting CoreDNS/kube-dns in kube-system
+func DefaultBaselineOptions() BaselineOptions {
+	return BaselineOptions{
+		DNSNamespace: "kube-system",
+		DNSLabels:    map[string]string{"k8s-app": "kube-dns"},
+	}
+}
+
+// BaselineGenerator is implemented by policy generators that can emit namespace-wide baselines
+type BaselineGenerator interface {
+	// GenerateBaseline creates the baseline policies for the given namespaces
+	GenerateBaseline(namespaces []string, opts BaselineOptions) ([]interface{}, error)
+}
+
+// GenerateBaselinePolicies generates the baseline policies for the given namespaces
+func (s *PolicyService) GenerateBaselinePolicies(namespaces []string, policyType PolicyType, opts BaselineOptions) ([]*PolicyOutput, error) {
+	generator, exists := s.generators[policyType]
+	if !exists {
+		return nil, fmt.Errorf("no generator available for policy type %s", policyType)
+	}
+
+	baselineGenerator, ok := generator.(BaselineGenerator)
+	if !ok {
+		return nil, fmt.Errorf("policy type %s does not support baseline policies", policyType)
+	}
+
+	policies, err := baselineGenerator.GenerateBaseline(namespaces, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := make([]*PolicyOutput, 0, len(policies))
+	for _, policy := range policies {
+		object, err := meta.Accessor(policy)
+		if err != nil {
+			return nil, fmt.Errorf("baseline policy has no object metadata: %w", err)
+		}
+
+		policyYAML, err := yaml.Marshal(policy)
+		if err != nil {
+			log.Error().Err(err).Msgf("Error converting %s baseline policy to YAML", policyType)
+			return nil, err
+		}
+
+		outputs = append(outputs, &PolicyOutput{
+			Policy:    policy,
+			YAML:      policyYAML,
+			PodName:   object.GetName(),
+			Namespace: object.GetNamespace(),
+			Type:      generator.GetType(),
+		})
+	}
+
+	return outputs, nil
+}
+
+// GenerateAndHandleBaseline generates the baseline policies and handles their output.
+// Clusterwide policies are saved under the "clusterwide" namespace directory.
+func (s *PolicyService) GenerateAndHandleBaseline(namespaces []string, policyType PolicyType, opts BaselineOptions) error {
+	outputs, err := s.GenerateBaselinePolicies(namespaces, policyType, opts)
+	if err != nil {
+		return err
+	}
+
+	for _, output := range outputs {
+		if output.Namespace == "" {
+			output.Namespace = "clusterwide"
+		}
+		if err := s.HandlePolicyOutput(output); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GenerateBaseline creates, per namespace, a default-deny-all policy, an allow-DNS egress policy
+// and optionally an allow-same-namespace policy
+func (g *StandardPolicyGenerator) GenerateBaseline(namespaces []string, opts BaselineOptions) ([]interface{}, error) {
+	if opts.Clusterwide {
+		log.Warn().Msg("Standard NetworkPolicies are namespaced, ignoring the clusterwide baseline option")
+	}
+
+	var policies []interface{}
+	for _, namespace := range namespaces {
+		log.Info().Msgf("Generating standard baseline policies for namespace %s", namespace)
+
+		policies = append(policies, &networkingv1.NetworkPolicy{
+			TypeMeta:   CreateTypeMeta("NetworkPolicy", "networking.k8s.io/v1"),
+			ObjectMeta: CreateObjectMeta(BaselineDenyAllName, namespace, CreateStandardLabels("baseline", "standard-policy-deny-all")),
+			Spec: networkingv1.NetworkPolicySpec{
+				// An empty pod selector selects every pod in the namespace
+				PodSelector: metav1.LabelSelector{},
+				PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+			},
+		})
+
+		policies = append(policies, &networkingv1.NetworkPolicy{
+			TypeMeta:   CreateTypeMeta("NetworkPolicy", "networking.k8s.io/v1"),
+			ObjectMeta: CreateObjectMeta(BaselineAllowDNSName, namespace, CreateStandardLabels("baseline", "standard-policy-allow-dns")),
+			Spec: networkingv1.NetworkPolicySpec{
+				PodSelector: metav1.LabelSelector{},
+				PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+				Egress: []networkingv1.NetworkPolicyEgressRule{
+					{
+						To: []networkingv1.NetworkPolicyPeer{
+							{
+								PodSelector: &metav1.LabelSelector{MatchLabels: opts.DNSLabels},
+								NamespaceSelector: &metav1.LabelSelector{
+									MatchLabels: map[string]string{"kubernetes.io/metadata.name": opts.DNSNamespace},
+								},
+							},
+						},
+						Ports: dnsNetworkPolicyPorts(),
+					},
+				},
+			},
+		})
+
+		if opts.AllowSameNamespace {
+			policies = append(policies, &networkingv1.NetworkPolicy{
+				TypeMeta:   CreateTypeMeta("NetworkPolicy", "networking.k8s.io/v1"),
+				ObjectMeta: CreateObjectMeta(BaselineSameNamespaceName, namespace, CreateStandardLabels("baseline", "standard-policy-same-namespace")),
+				Spec: networkingv1.NetworkPolicySpec{
+					PodSelector: metav1.LabelSelector{},
+					PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+					Ingress: []networkingv1.NetworkPolicyIngressRule{
+						{From: []networkingv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{}}}},
+					},
+					Egress: []networkingv1.NetworkPolicyEgressRule{
+						{To: []networkingv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{}}}},
+					},
+				},
+			})
+		}
+	}
+
+	return policies, nil
+}
+
+// GenerateBaseline creates the Cilium equivalent of the namespace baseline. With the clusterwide
+// option the deny and DNS baselines are emitted once as CiliumClusterwideNetworkPolicies selecting
+// all given namespaces.
+func (g *CiliumPolicyGenerator) GenerateBaseline(namespaces []string, opts BaselineOptions) ([]interface{}, error) {
+	var policies []interface{}
+
+	if opts.Clusterwide {
+		log.Info().Msgf("Generating clusterwide Cilium baseline policies for %d namespaces", len(namespaces))
+
+		selector := ciliumapi.NewESFromMatchRequirements(nil, []slim_metav1.LabelSelectorRequirement{
+			{Key: ciliumNamespaceLabelKey, Operator: slim_metav1.LabelSelectorOpIn, Values: namespaces},
+		})
+
+		policies = append(policies,
+			&ciliumv2.CiliumClusterwideNetworkPolicy{
+				TypeMeta:   CreateTypeMeta("CiliumClusterwideNetworkPolicy", "cilium.io/v2"),
+				ObjectMeta: CreateObjectMeta(BaselineDenyAllName, "", CreateStandardLabels("baseline", "cilium-clusterwide-policy-deny-all")),
+				Spec:       g.baselineDenyAllRule(selector, "Default-deny baseline for the selected namespaces"),
+			},
+			&ciliumv2.CiliumClusterwideNetworkPolicy{
+				TypeMeta:   CreateTypeMeta("CiliumClusterwideNetworkPolicy", "cilium.io/v2"),
+				ObjectMeta: CreateObjectMeta(BaselineAllowDNSName, "", CreateStandardLabels("baseline", "cilium-clusterwide-policy-allow-dns")),
+				Spec:       g.baselineAllowDNSRule(selector, opts),
+			},
+		)
+	}
+
+	for _, namespace := range namespaces {
+		log.Info().Msgf("Generating Cilium baseline policies for namespace %s", namespace)
+
+		if !opts.Clusterwide {
+			// An empty endpoint selector in a namespaced policy selects every endpoint of the namespace
+			policies = append(policies,
+				&ciliumv2.CiliumNetworkPolicy{
+					TypeMeta:   CreateTypeMeta("CiliumNetworkPolicy", "cilium.io/v2"),
+					ObjectMeta: CreateObjectMeta(BaselineDenyAllName, namespace, CreateStandardLabels("baseline", "cilium-policy-deny-all")),
+					Spec:       g.baselineDenyAllRule(ciliumapi.WildcardEndpointSelector, fmt.Sprintf("Default-deny baseline for namespace %s", namespace)),
+				},
+				&ciliumv2.CiliumNetworkPolicy{
+					TypeMeta:   CreateTypeMeta("CiliumNetworkPolicy", "cilium.io/v2"),
+					ObjectMeta: CreateObjectMeta(BaselineAllowDNSName, namespace, CreateStandardLabels("baseline", "cilium-policy-allow-dns")),
+					Spec:       g.baselineAllowDNSRule(ciliumapi.WildcardEndpointSelector, opts),
+				},
+			)
+		}
+
+		if opts.AllowSameNamespace {
+			policies = append(policies, &ciliumv2.CiliumNetworkPolicy{
+				TypeMeta:   CreateTypeMeta("CiliumNetworkPolicy", "cilium.io/v2"),
+				ObjectMeta: CreateObjectMeta(BaselineSameNamespaceName, namespace, CreateStandardLabels("baseline", "cilium-policy-same-namespace")),
+				Spec: &ciliumapi.Rule{
+					EndpointSelector: ciliumapi.WildcardEndpointSelector,
+					Description:      fmt.Sprintf("Allow all traffic within namespace %s", namespace),
+					Ingress: []ciliumapi.IngressRule{
+						{IngressCommonRule: ciliumapi.IngressCommonRule{FromEndpoints: []ciliumapi.EndpointSelector{ciliumapi.WildcardEndpointSelector}}},
+					},
+					Egress: []ciliumapi.EgressRule{
+						{EgressCommonRule: ciliumapi.EgressCommonRule{ToEndpoints: []ciliumapi.EndpointSelector{ciliumapi.WildcardEndpointSelector}}},
+					},
+				},
+			})
+		}
+	}
+
+	return policies, nil
+}
+
+// baselineDenyAllRule creates a Cilium rule putting the selected endpoints in default-deny
+func (g *CiliumPolicyGenerator) baselineDenyAllRule(selector ciliumapi.EndpointSelector, description string) *ciliumapi.Rule {
+	truePtr := true
+
+	return &ciliumapi.Rule{
+		EndpointSelector: selector,
+		Description:      description,
+		EnableDefaultDeny: ciliumapi.DefaultDenyConfig{
+			Ingress: &truePtr,
+			Egress:  &truePtr,
+		},
+		// Empty rules select the endpoints without allowing any traffic
+		Ingress: []ciliumapi.IngressRule{{}},
+		Egress:  []ciliumapi.EgressRule{{}},
+	}
+}
+
+// baselineAllowDNSRule creates a Cilium rule allowing DNS lookups against the cluster DNS pods.
+// The DNS L7 rule lets Cilium observe lookups, which toFQDNs rules rely on.
+func (g *CiliumPolicyGenerator) baselineAllowDNSRule(selector ciliumapi.EndpointSelector, opts BaselineOptions) *ciliumapi.Rule {
+	dnsLabels := make(map[string]string, len(opts.DNSLabels)+1)
+	for key, value := range opts.DNSLabels {
+		dnsLabels[labels.LabelSourceK8sKeyPrefix+key] = value
+	}
+	dnsLabels[ciliumNamespaceLabelKey] = opts.DNSNamespace
+
+	return &ciliumapi.Rule{
+		EndpointSelector: selector,
+		Description:      "Allow DNS lookups against the cluster DNS",
+		Egress: []ciliumapi.EgressRule{
+			{
+				EgressCommonRule: ciliumapi.EgressCommonRule{
+					ToEndpoints: []ciliumapi.EndpointSelector{ciliumapi.NewESFromMatchRequirements(dnsLabels, nil)},
+				},
+				ToPorts: ciliumapi.PortRules{
+					{
+						Ports: []ciliumapi.PortProtocol{
+							{Port: "53", Protocol: ciliumapi.ProtoUDP},
+							{Port: "53", Protocol: ciliumapi.ProtoTCP},
+						},
+						Rules: &ciliumapi.L7Rules{
+							DNS: []ciliumapi.PortRuleDNS{{MatchPattern: "*"}},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// dnsNetworkPolicyPorts returns the UDP and TCP DNS ports
+func dnsNetworkPolicyPorts() []networkingv1.NetworkPolicyPort {
+	dnsPort := intstr.FromInt(53)
+	udp := corev1.ProtocolUDP
+	tcp := corev1.ProtocolTCP
+
+	return []networkingv1.NetworkPolicyPort{
+		{Port: &dnsPort, Protocol: &udp},
+		{Port: &dnsPort, Protocol: &tcp},
+	}
+}
@@ -0,0 +1,146 @@
+package network
+
+import (
+	"testing"
+
+	ciliumv2 "github.com/cilium/cilium/pkg/k8s/apis/cilium.io/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xentra-ai/advisor/pkg/common"
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/yaml"
+)
+
+func TestStandardGenerateBaseline(t *testing.T) {
+	generator := NewStandardPolicyGenerator()
+	opts := DefaultBaselineOptions()
+
+	policies, err := generator.GenerateBaseline([]string{"prod", "dev"}, opts)
+	require.NoError(t, err)
+	require.Len(t, policies, 4)
+
+	denyAll, ok := policies[0].(*networkingv1.NetworkPolicy)
+	require.True(t, ok)
+	assert.Equal(t, BaselineDenyAllName, denyAll.Name)
+	assert.Equal(t, "prod", denyAll.Namespace)
+	assert.Empty(t, denyAll.Spec.PodSelector.MatchLabels)
+	assert.ElementsMatch(t, []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress}, denyAll.Spec.PolicyTypes)
+	assert.Empty(t, denyAll.Spec.Ingress)
+	assert.Empty(t, denyAll.Spec.Egress)
+
+	allowDNS, ok := policies[1].(*networkingv1.NetworkPolicy)
+	require.True(t, ok)
+	assert.Equal(t, BaselineAllowDNSName, allowDNS.Name)
+	require.Len(t, allowDNS.Spec.Egress, 1)
+	peer := allowDNS.Spec.Egress[0].To[0]
+	assert.Equal(t, map[string]string{"k8s-app": "kube-dns"}, peer.PodSelector.MatchLabels)
+	assert.Equal(t, map[string]string{"kubernetes.io/metadata.name": "kube-system"}, peer.NamespaceSelector.MatchLabels)
+	require.Len(t, allowDNS.Spec.Egress[0].Ports, 2)
+	assert.Equal(t, int32(53), allowDNS.Spec.Egress[0].Ports[0].Port.IntVal)
+
+	assert.Equal(t, "dev", policies[2].(*networkingv1.NetworkPolicy).Namespace)
+
+	// Same-namespace policy is opt-in
+	opts.AllowSameNamespace = true
+	policies, err = generator.GenerateBaseline([]string{"prod"}, opts)
+	require.NoError(t, err)
+	require.Len(t, policies, 3)
+	sameNamespace := policies[2].(*networkingv1.NetworkPolicy)
+	assert.Equal(t, BaselineSameNamespaceName, sameNamespace.Name)
+	require.Len(t, sameNamespace.Spec.Ingress, 1)
+	assert.NotNil(t, sameNamespace.Spec.Ingress[0].From[0].PodSelector)
+	assert.Nil(t, sameNamespace.Spec.Ingress[0].From[0].NamespaceSelector)
+}
+
+func TestCiliumGenerateBaseline(t *testing.T) {
+	generator := NewCiliumPolicyGenerator()
+	opts := DefaultBaselineOptions()
+
+	policies, err := generator.GenerateBaseline([]string{"prod"}, opts)
+	require.NoError(t, err)
+	require.Len(t, policies, 2)
+
+	denyAll, ok := policies[0].(*ciliumv2.CiliumNetworkPolicy)
+	require.True(t, ok)
+	assert.Equal(t, BaselineDenyAllName, denyAll.Name)
+	assert.Equal(t, "prod", denyAll.Namespace)
+	require.NotNil(t, denyAll.Spec.EnableDefaultDeny.Ingress)
+	assert.True(t, *denyAll.Spec.EnableDefaultDeny.Ingress)
+	assert.True(t, *denyAll.Spec.EnableDefaultDeny.Egress)
+
+	allowDNS, ok := policies[1].(*ciliumv2.CiliumNetworkPolicy)
+	require.True(t, ok)
+	require.Len(t, allowDNS.Spec.Egress, 1)
+	selector := allowDNS.Spec.Egress[0].ToEndpoints[0]
+	assert.Equal(t, "kube-system", selector.MatchLabels[ciliumNamespaceLabelKey])
+	assert.Equal(t, "kube-dns", selector.MatchLabels["k8s.k8s-app"])
+
+	// Selectors serialize with the k8s source prefix
+	policyYAML, err := yaml.Marshal(allowDNS)
+	require.NoError(t, err)
+	assert.Contains(t, string(policyYAML), "k8s:io.kubernetes.pod.namespace: kube-system")
+	assert.Contains(t, string(policyYAML), "k8s:k8s-app: kube-dns")
+	require.Len(t, allowDNS.Spec.Egress[0].ToPorts, 1)
+	assert.Len(t, allowDNS.Spec.Egress[0].ToPorts[0].Ports, 2)
+	assert.Equal(t, "*", allowDNS.Spec.Egress[0].ToPorts[0].Rules.DNS[0].MatchPattern)
+}
+
+func TestCiliumGenerateBaseline_Clusterwide(t *testing.T) {
+	generator := NewCiliumPolicyGenerator()
+	opts := DefaultBaselineOptions()
+	opts.Clusterwide = true
+	opts.AllowSameNamespace = true
+
+	policies, err := generator.GenerateBaseline([]string{"prod", "dev"}, opts)
+	require.NoError(t, err)
+	// Two clusterwide policies plus one same-namespace policy per namespace
+	require.Len(t, policies, 4)
+
+	denyAll, ok := policies[0].(*ciliumv2.CiliumClusterwideNetworkPolicy)
+	require.True(t, ok)
+	assert.Empty(t, denyAll.Namespace)
+	require.Len(t, denyAll.Spec.EndpointSelector.MatchExpressions, 1)
+	assert.Equal(t, ciliumNamespaceLabelKey, denyAll.Spec.EndpointSelector.MatchExpressions[0].Key)
+	assert.Equal(t, []string{"prod", "dev"}, denyAll.Spec.EndpointSelector.MatchExpressions[0].Values)
+
+	_, ok = policies[1].(*ciliumv2.CiliumClusterwideNetworkPolicy)
+	assert.True(t, ok)
+
+	sameNamespace, ok := policies[2].(*ciliumv2.CiliumNetworkPolicy)
+	require.True(t, ok)
+	assert.Equal(t, BaselineSameNamespaceName, sameNamespace.Name)
+	assert.Equal(t, "prod", sameNamespace.Namespace)
+}
+
+func TestGenerateAndHandleBaseline(t *testing.T) {
+	origCommonPrint := common.PrintDryRunMessageFunc
+	common.PrintDryRunMessageFunc = func(resourceType, name string, content []byte, outputDir string) {}
+	defer func() { common.PrintDryRunMessageFunc = origCommonPrint }()
+
+	origCommonSave := common.SaveToFileFunc
+	saved := map[string]string{}
+	common.SaveToFileFunc = func(outputDir, resourceType, namespace, name string, content []byte) (string, error) {
+		assert.Equal(t, "cilium-networkpolicy", resourceType)
+		saved[name] = namespace
+		return "out/" + name, nil
+	}
+	defer func() { common.SaveToFileFunc = origCommonSave }()
+
+	service := NewPolicyService(&mockConfigProvider{dryRun: true, outputDir: "out"}, CiliumPolicy)
+	service.RegisterGenerator(NewCiliumPolicyGenerator())
+
+	opts := DefaultBaselineOptions()
+	opts.Clusterwide = true
+	err := service.GenerateAndHandleBaseline([]string{"prod"}, CiliumPolicy, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		BaselineDenyAllName:  "clusterwide",
+		BaselineAllowDNSName: "clusterwide",
+	}, saved)
+
+	// Generators without baseline support are rejected
+	service.RegisterGenerator(&mockPolicyGenerator{policyType: StandardPolicy})
+	err = service.GenerateAndHandleBaseline([]string{"prod"}, StandardPolicy, opts)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "does not support baseline")
+}
@@ -0,0 +1,219 @@
+package network
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	log "github.com/rs/zerolog/log"
+	"github.com/xentra-ai/advisor/pkg/api"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	// calicoPolicyOrder places the per-workload allow policies before the baseline
+	calicoPolicyOrder = 1000
+	// calicoBaselineOrder places the baseline after the per-workload allow policies
+	calicoBaselineOrder = 2000
+	// calicoNamespaceLabel is the label Calico sets on namespaces with their name
+	calicoNamespaceLabel = "projectcalico.org/name"
+)
+
+// CalicoPolicyGenerator generates Calico NetworkPolicy and GlobalNetworkPolicy resources
+type CalicoPolicyGenerator struct {
+	standard *StandardPolicyGenerator
+}
+
+// NewCalicoPolicyGenerator creates a new generator for Calico NetworkPolicy resources
+func NewCalicoPolicyGenerator() *CalicoPolicyGenerator {
+	return &CalicoPolicyGenerator{standard: NewStandardPolicyGenerator()}
+}
+
+// GetType returns the policy type
+func (g *CalicoPolicyGenerator) GetType() PolicyType {
+	return CalicoPolicy
+}
+
+// Generate creates a projectcalico.org/v3 NetworkPolicy for the specified pod
+func (g *CalicoPolicyGenerator) Generate(podName string, podTraffic []api.PodTraffic, podDetail *api.PodDetail) (interface{}, error) {
+	log.Info().Msgf("Generating Calico network policy for pod %s", podName)
+
+	if podDetail == nil {
+		return nil, fmt.Errorf("pod detail is nil for pod %s", podName)
+	}
+
+	ingressRules, egressRules := g.standard.processTrafficRules(podTraffic, podDetail)
+
+	order := float64(calicoPolicyOrder)
+	policy := &CalicoNetworkPolicy{
+		TypeMeta: CreateTypeMeta("NetworkPolicy", calicoAPIVersion),
+		ObjectMeta: CreateObjectMeta(
+			GetPolicyName(podDetail.Name, "calico-policy"),
+			podDetail.Namespace,
+			CreateStandardLabels(podDetail.Name, "calico-policy"),
+		),
+		Spec: CalicoPolicySpec{
+			Order:    &order,
+			Selector: calicoSelector(podDetail.Pod.Labels),
+			Types:    []string{"Ingress", "Egress"},
+		},
+	}
+
+	for _, rule := range ingressRules {
+		source := g.calicoPeer(rule.PeerIP)
+		for _, protocolRule := range calicoProtocolRules(rule.Ports) {
+			protocolRule.Source = source
+			policy.Spec.Ingress = append(policy.Spec.Ingress, protocolRule)
+		}
+	}
+
+	for _, rule := range egressRules {
+		destination := g.calicoPeer(rule.PeerIP)
+		for _, protocolRule := range calicoProtocolRules(rule.Ports) {
+			ports := protocolRule.Destination.Ports
+			protocolRule.Destination = destination
+			protocolRule.Destination.Ports = ports
+			policy.Spec.Egress = append(policy.Spec.Egress, protocolRule)
+		}
+	}
+
+	if len(policy.Spec.Ingress) == 0 && len(policy.Spec.Egress) == 0 {
+		// A policy selecting the pod without any rules denies all of its traffic
+		log.Warn().Msgf("No valid ingress or egress rules generated for pod %s. Applying default-deny.", podName)
+		policy.Name = GetPolicyName(podDetail.Name, "calico-policy-deny-all")
+		policy.Labels = CreateStandardLabels(podDetail.Name, "calico-policy-deny-all")
+	}
+
+	return policy, nil
+}
+
+// GenerateBaseline creates a GlobalNetworkPolicy putting the given namespaces in default-deny
+// while allowing DNS, and optionally a NetworkPolicy per namespace allowing same-namespace traffic
+func (g *CalicoPolicyGenerator) GenerateBaseline(namespaces []string, opts BaselineOptions) ([]interface{}, error) {
+	log.Info().Msgf("Generating Calico baseline policies for namespaces %v", namespaces)
+
+	order := float64(calicoBaselineOrder)
+	dnsDestination := CalicoEntityRule{
+		Selector:          calicoSelector(opts.DNSLabels),
+		NamespaceSelector: calicoNamespaceSelector(opts.DNSNamespace),
+		Ports:             []intstr.IntOrString{intstr.FromInt(53)},
+	}
+
+	policies := []interface{}{
+		&CalicoGlobalNetworkPolicy{
+			TypeMeta:   CreateTypeMeta("GlobalNetworkPolicy", calicoAPIVersion),
+			ObjectMeta: CreateObjectMeta(BaselineDenyAllName, "", CreateStandardLabels("baseline", "calico-global-policy-deny-all")),
+			Spec: CalicoPolicySpec{
+				Order:             &order,
+				Selector:          "all()",
+				NamespaceSelector: calicoNamespaceSelector(namespaces...),
+				Types:             []string{"Ingress", "Egress"},
+				// Traffic not allowed by any policy is denied at the end of the tier
+				Egress: []CalicoRule{
+					{Action: CalicoActionAllow, Protocol: "UDP", Destination: dnsDestination},
+					{Action: CalicoActionAllow, Protocol: "TCP", Destination: dnsDestination},
+				},
+			},
+		},
+	}
+
+	if opts.AllowSameNamespace {
+		for _, namespace := range namespaces {
+			policies = append(policies, &CalicoNetworkPolicy{
+				TypeMeta:   CreateTypeMeta("NetworkPolicy", calicoAPIVersion),
+				ObjectMeta: CreateObjectMeta(BaselineSameNamespaceName, namespace, CreateStandardLabels("baseline", "calico-policy-same-namespace")),
+				Spec: CalicoPolicySpec{
+					Order:    &order,
+					Selector: "all()",
+					Types:    []string{"Ingress", "Egress"},
+					// Selectors without a namespaceSelector match the policy's own namespace
+					Ingress: []CalicoRule{{Action: CalicoActionAllow, Source: CalicoEntityRule{Selector: "all()"}}},
+					Egress:  []CalicoRule{{Action: CalicoActionAllow, Destination: CalicoEntityRule{Selector: "all()"}}},
+				},
+			})
+		}
+	}
+
+	return policies, nil
+}
+
+// calicoPeer resolves a peer IP to a namespaced selector or a single-address net
+func (g *CalicoPolicyGenerator) calicoPeer(peerIP string) CalicoEntityRule {
+	peer := ResolvePeer(peerIP)
+	if peer.Kind == PeerExternal {
+		return CalicoEntityRule{Nets: []string{hostCIDR(peerIP)}}
+	}
+
+	return CalicoEntityRule{
+		Selector:          calicoSelector(peer.Labels),
+		NamespaceSelector: calicoNamespaceSelector(peer.Namespace),
+	}
+}
+
+// calicoProtocolRules splits ports into one Allow rule per protocol, as Calico rules carry a
+// single protocol. The ports are set on the rule's destination.
+func calicoProtocolRules(ports []networkingv1.NetworkPolicyPort) []CalicoRule {
+	var protocols []corev1.Protocol
+	portsByProtocol := make(map[corev1.Protocol][]intstr.IntOrString)
+
+	for _, port := range ports {
+		if port.Port == nil {
+			continue
+		}
+		protocol := corev1.ProtocolTCP
+		if port.Protocol != nil {
+			protocol = *port.Protocol
+		}
+		if _, exists := portsByProtocol[protocol]; !exists {
+			protocols = append(protocols, protocol)
+		}
+		portsByProtocol[protocol] = append(portsByProtocol[protocol], *port.Port)
+	}
+
+	rules := make([]CalicoRule, 0, len(protocols))
+	for _, protocol := range protocols {
+		rules = append(rules, CalicoRule{
+			Action:      CalicoActionAllow,
+			Protocol:    string(protocol),
+			Destination: CalicoEntityRule{Ports: portsByProtocol[protocol]},
+		})
+	}
+
+	return rules
+}
+
+// calicoSelector converts labels into a Calico selector expression, e.g. app == 'api' && tier == 'web'
+func calicoSelector(selectorLabels map[string]string) string {
+	if len(selectorLabels) == 0 {
+		return "all()"
+	}
+
+	keys := make([]string, 0, len(selectorLabels))
+	for key := range selectorLabels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	terms := make([]string, 0, len(keys))
+	for _, key := range keys {
+		terms = append(terms, fmt.Sprintf("%s == '%s'", key, selectorLabels[key]))
+	}
+
+	return strings.Join(terms, " && ")
+}
+
+// calicoNamespaceSelector selects namespaces by name
+func calicoNamespaceSelector(namespaces ...string) string {
+	if len(namespaces) == 1 {
+		return fmt.Sprintf("%s == '%s'", calicoNamespaceLabel, namespaces[0])
+	}
+
+	quoted := make([]string, 0, len(namespaces))
+	for _, namespace := range namespaces {
+		quoted = append(quoted, fmt.Sprintf("'%s'", namespace))
+	}
+
+	return fmt.Sprintf("%s in {%s}", calicoNamespaceLabel, strings.Join(quoted, ", "))
+}
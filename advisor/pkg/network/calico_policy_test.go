@@ -0,0 +1,202 @@
+package network
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xentra-ai/advisor/pkg/api"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/yaml"
+)
+
+func TestCalicoPolicyGenerator_Generate_NoTraffic(t *testing.T) {
+	gen := NewCalicoPolicyGenerator()
+	podDetail := mockPodDetail("test-pod", "default", "192.168.1.10", map[string]string{"app": "test"})
+	var podTraffic []api.PodTraffic // Empty traffic
+
+	policyInterface, err := gen.Generate("test-pod", podTraffic, podDetail)
+	assert.NoError(t, err)
+
+	policy, ok := policyInterface.(*CalicoNetworkPolicy)
+	require.True(t, ok)
+	assert.Equal(t, GetPolicyName("test-pod", "calico-policy-deny-all"), policy.Name)
+	assert.Equal(t, podDetail.Namespace, policy.Namespace)
+	assert.Equal(t, "app == 'test'", policy.Spec.Selector)
+	assert.Equal(t, []string{"Ingress", "Egress"}, policy.Spec.Types)
+	assert.Empty(t, policy.Spec.Ingress)
+	assert.Empty(t, policy.Spec.Egress)
+}
+
+func TestCalicoPolicyGenerator_Generate_BasicIngressEgress(t *testing.T) {
+	// --- Setup Mocks ---
+	origGetPodSpecFunc := api.GetPodSpecFunc
+	origGetSvcSpecFunc := api.GetSvcSpecFunc
+	defer func() {
+		api.GetPodSpecFunc = origGetPodSpecFunc
+		api.GetSvcSpecFunc = origGetSvcSpecFunc
+	}()
+
+	api.GetPodSpecFunc = func(ip string) (*api.PodDetail, error) {
+		if ip == "10.0.0.1" {
+			return mockPodDetail("client-pod", "web", ip, map[string]string{"app": "client", "tier": "frontend"}), nil
+		}
+		return nil, nil // Not found
+	}
+	api.GetSvcSpecFunc = func(ip string) (*api.SvcDetail, error) {
+		if ip == "10.0.0.2" {
+			return mockSvcDetail("backend-svc", "default", ip, map[string]string{"app": "backend"}), nil
+		}
+		return nil, nil // Not found
+	}
+	// --- End Mocks ---
+
+	gen := NewCalicoPolicyGenerator()
+	podDetail := mockPodDetail("test-pod", "default", "192.168.1.10", map[string]string{"app": "test"})
+	podTraffic := []api.PodTraffic{
+		{
+			// INGRESS: client-pod (10.0.0.1) -> test-pod (192.168.1.10:80)
+			SrcPodName:  "test-pod",
+			SrcIP:       "192.168.1.10",
+			SrcPodPort:  "80",
+			DstIP:       "10.0.0.1",
+			Protocol:    corev1.ProtocolTCP,
+			TrafficType: "INGRESS",
+		},
+		{
+			// EGRESS: test-pod (192.168.1.10) -> backend-svc (10.0.0.2:443)
+			SrcPodName:  "test-pod",
+			SrcIP:       "192.168.1.10",
+			DstIP:       "10.0.0.2",
+			DstPort:     "443",
+			Protocol:    corev1.ProtocolTCP,
+			TrafficType: "EGRESS",
+		},
+	}
+
+	policyInterface, err := gen.Generate("test-pod", podTraffic, podDetail)
+	assert.NoError(t, err)
+	policy, ok := policyInterface.(*CalicoNetworkPolicy)
+	require.True(t, ok)
+
+	assert.Equal(t, "NetworkPolicy", policy.Kind)
+	assert.Equal(t, "projectcalico.org/v3", policy.APIVersion)
+	assert.Equal(t, GetPolicyName("test-pod", "calico-policy"), policy.Name)
+	require.NotNil(t, policy.Spec.Order)
+	assert.Equal(t, float64(1000), *policy.Spec.Order)
+
+	// Verify Ingress Rule
+	require.Len(t, policy.Spec.Ingress, 1)
+	ingressRule := policy.Spec.Ingress[0]
+	assert.Equal(t, CalicoActionAllow, ingressRule.Action)
+	assert.Equal(t, "TCP", ingressRule.Protocol)
+	assert.Equal(t, "app == 'client' && tier == 'frontend'", ingressRule.Source.Selector)
+	assert.Equal(t, "projectcalico.org/name == 'web'", ingressRule.Source.NamespaceSelector)
+	assert.Equal(t, []intstr.IntOrString{intstr.FromInt(80)}, ingressRule.Destination.Ports)
+
+	// Verify Egress Rule
+	require.Len(t, policy.Spec.Egress, 1)
+	egressRule := policy.Spec.Egress[0]
+	assert.Equal(t, CalicoActionAllow, egressRule.Action)
+	assert.Equal(t, "app == 'backend'", egressRule.Destination.Selector)
+	assert.Equal(t, "projectcalico.org/name == 'default'", egressRule.Destination.NamespaceSelector)
+	assert.Equal(t, []intstr.IntOrString{intstr.FromInt(443)}, egressRule.Destination.Ports)
+}
+
+func TestCalicoPolicyGenerator_Generate_NetsFallbackAndProtocols(t *testing.T) {
+	// --- Setup Mocks ---
+	origGetPodSpecFunc := api.GetPodSpecFunc
+	origGetSvcSpecFunc := api.GetSvcSpecFunc
+	defer func() {
+		api.GetPodSpecFunc = origGetPodSpecFunc
+		api.GetSvcSpecFunc = origGetSvcSpecFunc
+	}()
+
+	// Mock APIs to return nothing found
+	api.GetPodSpecFunc = func(ip string) (*api.PodDetail, error) { return nil, nil }
+	api.GetSvcSpecFunc = func(ip string) (*api.SvcDetail, error) { return nil, nil }
+	// --- End Mocks ---
+
+	gen := NewCalicoPolicyGenerator()
+	podDetail := mockPodDetail("test-pod", "default", "192.168.1.10", map[string]string{"app": "test"})
+	podTraffic := []api.PodTraffic{
+		{SrcIP: "192.168.1.10", DstIP: "8.8.8.8", DstPort: "53", Protocol: corev1.ProtocolUDP, TrafficType: "EGRESS"},
+		{SrcIP: "192.168.1.10", DstIP: "8.8.8.8", DstPort: "53", Protocol: corev1.ProtocolTCP, TrafficType: "EGRESS"},
+	}
+
+	policyInterface, err := gen.Generate("test-pod", podTraffic, podDetail)
+	assert.NoError(t, err)
+	policy := policyInterface.(*CalicoNetworkPolicy)
+
+	// Calico rules carry a single protocol, so the peer is split into one rule per protocol
+	require.Len(t, policy.Spec.Egress, 2)
+	assert.Equal(t, "UDP", policy.Spec.Egress[0].Protocol)
+	assert.Equal(t, "TCP", policy.Spec.Egress[1].Protocol)
+	for _, rule := range policy.Spec.Egress {
+		assert.Equal(t, []string{"8.8.8.8/32"}, rule.Destination.Nets)
+		assert.Empty(t, rule.Destination.Selector)
+		assert.Equal(t, []intstr.IntOrString{intstr.FromInt(53)}, rule.Destination.Ports)
+	}
+
+	policyYAML, err := yaml.Marshal(policy)
+	require.NoError(t, err)
+	assert.Contains(t, string(policyYAML), "nets:\n      - 8.8.8.8/32")
+	assert.NotContains(t, string(policyYAML), "source:")
+}
+
+func TestCalicoPolicyGenerator_Generate_IPv6Nets(t *testing.T) {
+	origGetPodSpecFunc := api.GetPodSpecFunc
+	origGetSvcSpecFunc := api.GetSvcSpecFunc
+	defer func() {
+		api.GetPodSpecFunc = origGetPodSpecFunc
+		api.GetSvcSpecFunc = origGetSvcSpecFunc
+	}()
+	api.GetPodSpecFunc = func(ip string) (*api.PodDetail, error) { return nil, nil }
+	api.GetSvcSpecFunc = func(ip string) (*api.SvcDetail, error) { return nil, nil }
+
+	gen := NewCalicoPolicyGenerator()
+	podDetail := mockPodDetail("test-pod", "default", "fd00::10", map[string]string{"app": "test"})
+	podTraffic := []api.PodTraffic{
+		{SrcIP: "fd00::10", DstIP: "2001:db8::1", DstPort: "443", Protocol: corev1.ProtocolTCP, TrafficType: "EGRESS"},
+	}
+
+	policyInterface, err := gen.Generate("test-pod", podTraffic, podDetail)
+	require.NoError(t, err)
+	policy := policyInterface.(*CalicoNetworkPolicy)
+
+	require.Len(t, policy.Spec.Egress, 1)
+	assert.Equal(t, []string{"2001:db8::1/128"}, policy.Spec.Egress[0].Destination.Nets)
+}
+
+func TestCalicoPolicyGenerator_GenerateBaseline(t *testing.T) {
+	gen := NewCalicoPolicyGenerator()
+	opts := DefaultBaselineOptions()
+	opts.AllowSameNamespace = true
+
+	policies, err := gen.GenerateBaseline([]string{"prod", "dev"}, opts)
+	require.NoError(t, err)
+	require.Len(t, policies, 3)
+
+	global, ok := policies[0].(*CalicoGlobalNetworkPolicy)
+	require.True(t, ok)
+	assert.Equal(t, "GlobalNetworkPolicy", global.Kind)
+	assert.Empty(t, global.Namespace)
+	assert.Equal(t, "all()", global.Spec.Selector)
+	assert.Equal(t, "projectcalico.org/name in {'prod', 'dev'}", global.Spec.NamespaceSelector)
+	assert.Empty(t, global.Spec.Ingress)
+	require.Len(t, global.Spec.Egress, 2)
+	assert.Equal(t, "k8s-app == 'kube-dns'", global.Spec.Egress[0].Destination.Selector)
+	assert.Equal(t, "projectcalico.org/name == 'kube-system'", global.Spec.Egress[0].Destination.NamespaceSelector)
+
+	sameNamespace, ok := policies[1].(*CalicoNetworkPolicy)
+	require.True(t, ok)
+	assert.Equal(t, BaselineSameNamespaceName, sameNamespace.Name)
+	assert.Equal(t, "prod", sameNamespace.Namespace)
+	assert.Equal(t, "all()", sameNamespace.Spec.Ingress[0].Source.Selector)
+}
+
+func TestCalicoPolicyGenerator_GetType(t *testing.T) {
+	gen := NewCalicoPolicyGenerator()
+	assert.Equal(t, CalicoPolicy, gen.GetType())
+}
@@ -0,0 +1,65 @@
+package network
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// The projectcalico.org/v3 policy resources, limited to the fields the generator emits.
+// They are declared locally to avoid depending on the Calico API module.
+
+// calicoAPIVersion is the API version of the Calico policy resources
+const calicoAPIVersion = "projectcalico.org/v3"
+
+// CalicoAction is the action of a Calico rule
+type CalicoAction string
+
+const (
+	// CalicoActionAllow allows the matching traffic
+	CalicoActionAllow CalicoAction = "Allow"
+	// CalicoActionDeny denies the matching traffic
+	CalicoActionDeny CalicoAction = "Deny"
+)
+
+// CalicoNetworkPolicy is a namespaced projectcalico.org/v3 NetworkPolicy
+type CalicoNetworkPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              CalicoPolicySpec `json:"spec"`
+}
+
+// CalicoGlobalNetworkPolicy is a cluster scoped projectcalico.org/v3 GlobalNetworkPolicy
+type CalicoGlobalNetworkPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              CalicoPolicySpec `json:"spec"`
+}
+
+// CalicoPolicySpec is the spec shared by Calico NetworkPolicy and GlobalNetworkPolicy
+type CalicoPolicySpec struct {
+	// Order controls precedence, policies with a lower order are evaluated first
+	Order *float64 `json:"order,omitempty"`
+	// Selector is a Calico selector expression selecting the endpoints the policy applies to
+	Selector string `json:"selector"`
+	// NamespaceSelector restricts a GlobalNetworkPolicy to the matching namespaces
+	NamespaceSelector string       `json:"namespaceSelector,omitempty"`
+	Types             []string     `json:"types,omitempty"`
+	Ingress           []CalicoRule `json:"ingress,omitempty"`
+	Egress            []CalicoRule `json:"egress,omitempty"`
+}
+
+// CalicoRule is a single Calico policy rule
+type CalicoRule struct {
+	Action      CalicoAction     `json:"action"`
+	Protocol    string           `json:"protocol,omitempty"`
+	Source      CalicoEntityRule `json:"source,omitzero"`
+	Destination CalicoEntityRule `json:"destination,omitzero"`
+}
+
+// CalicoEntityRule matches the source or destination of a rule
+type CalicoEntityRule struct {
+	Nets              []string             `json:"nets,omitempty"`
+	Selector          string               `json:"selector,omitempty"`
+	NamespaceSelector string               `json:"namespaceSelector,omitempty"`
+	Ports             []intstr.IntOrString `json:"ports,omitempty"`
+}
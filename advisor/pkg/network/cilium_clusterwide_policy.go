@@ -0,0 +1,155 @@
+package network
+
+import (
+	"fmt"
+
+	ciliumio "github.com/cilium/cilium/pkg/k8s/apis/cilium.io"
+	ciliumv2 "github.com/cilium/cilium/pkg/k8s/apis/cilium.io/v2"
+	"github.com/cilium/cilium/pkg/labels"
+	ciliumapi "github.com/cilium/cilium/pkg/policy/api"
+	log "github.com/rs/zerolog/log"
+	"github.com/xentra-ai/advisor/pkg/api"
+)
+
+// CiliumClusterwidePolicyGenerator generates CiliumClusterwideNetworkPolicy resources
+//
+// Unlike namespaced CiliumNetworkPolicies, every selector is pinned to a namespace through the
+// k8s:io.kubernetes.pod.namespace label, so rules can express cross-namespace peers. Pods running
+// in the host network namespace are secured with a host policy using a nodeSelector instead.
+type CiliumClusterwidePolicyGenerator struct {
+	cilium *CiliumPolicyGenerator
+}
+
+// NewCiliumClusterwidePolicyGenerator creates a new generator for CiliumClusterwideNetworkPolicy resources
+func NewCiliumClusterwidePolicyGenerator() *CiliumClusterwidePolicyGenerator {
+	return &CiliumClusterwidePolicyGenerator{cilium: NewCiliumPolicyGenerator()}
+}
+
+// NewCiliumClusterwidePolicyGeneratorWithOptions creates a new generator for CiliumClusterwideNetworkPolicy resources
+func NewCiliumClusterwidePolicyGeneratorWithOptions(options CiliumPolicyOptions) *CiliumClusterwidePolicyGenerator {
+	return &CiliumClusterwidePolicyGenerator{cilium: NewCiliumPolicyGeneratorWithOptions(options)}
+}
+
+// GetType returns the policy type
+func (g *CiliumClusterwidePolicyGenerator) GetType() PolicyType {
+	return CiliumClusterwidePolicy
+}
+
+// Generate creates a CiliumClusterwideNetworkPolicy for the specified pod
+func (g *CiliumClusterwidePolicyGenerator) Generate(podName string, podTraffic []api.PodTraffic, podDetail *api.PodDetail) (interface{}, error) {
+	log.Info().Msgf("Generating Cilium clusterwide network policy for pod %s", podName)
+
+	if podDetail == nil {
+		return nil, fmt.Errorf("pod detail is nil for pod %s", podName)
+	}
+
+	policy := &ciliumv2.CiliumClusterwideNetworkPolicy{
+		TypeMeta: CreateTypeMeta("CiliumClusterwideNetworkPolicy", "cilium.io/v2"),
+		// Clusterwide policies share one name scope, so the namespace is part of the name
+		ObjectMeta: CreateObjectMeta(
+			GetPolicyName(fmt.Sprintf("%s-%s", podDetail.Namespace, podDetail.Name), "cilium-clusterwide-policy"),
+			"",
+			CreateStandardLabels(podDetail.Name, "cilium-clusterwide-policy"),
+		),
+		Spec: &ciliumapi.Rule{
+			Description: fmt.Sprintf("Cilium clusterwide network policy for pod %s/%s generated by xentra-advisor", podDetail.Namespace, podDetail.Name),
+		},
+	}
+
+	if podDetail.Pod.Spec.HostNetwork {
+		log.Info().Msgf("Pod %s/%s uses the host network, generating a host policy", podDetail.Namespace, podDetail.Name)
+		policy.Spec.NodeSelector = g.createNodeSelector(podDetail.Pod.Spec.NodeSelector)
+	} else {
+		policy.Spec.EndpointSelector = g.createNamespacedSelector(podDetail.Pod.Labels, podDetail.Namespace)
+	}
+
+	ingressRules, egressRules := g.cilium.processTrafficRules(podTraffic, podDetail)
+
+	for _, rule := range ingressRules {
+		selectors, cidrs, entities := g.resolvePeer(rule.PeerIP)
+		ingressRule := ciliumapi.IngressRule{ToPorts: g.cilium.convertPortsToCiliumPortRules(rule.Ports)}
+		ingressRule.FromEndpoints = selectors
+		ingressRule.FromCIDR = cidrs
+		ingressRule.FromEntities = entities
+		policy.Spec.Ingress = append(policy.Spec.Ingress, ingressRule)
+	}
+
+	for _, rule := range egressRules {
+		if serviceRule := g.cilium.createCiliumServiceEgressRule(rule.PeerIP, rule.Ports); serviceRule != nil {
+			policy.Spec.Egress = append(policy.Spec.Egress, *serviceRule)
+			continue
+		}
+
+		selectors, cidrs, entities := g.resolvePeer(rule.PeerIP)
+		egressRule := ciliumapi.EgressRule{ToPorts: g.cilium.convertPortsToCiliumPortRules(rule.Ports)}
+		egressRule.ToEndpoints = selectors
+		egressRule.ToCIDR = cidrs
+		egressRule.ToEntities = entities
+		policy.Spec.Egress = append(policy.Spec.Egress, egressRule)
+	}
+
+	// Without any allowed traffic the policy is a default-deny for the selected pods. A host policy
+	// would instead cut off every selected node, including all nodes for a wildcard node selector,
+	// so none is generated.
+	if len(policy.Spec.Ingress) == 0 && len(policy.Spec.Egress) == 0 {
+		if podDetail.Pod.Spec.HostNetwork {
+			log.Warn().Msgf("No valid ingress or egress rules generated for host network pod %s, skipping the host policy", podName)
+			return nil, fmt.Errorf("no traffic recorded for host network pod %s/%s, refusing to default-deny its nodes", podDetail.Namespace, podDetail.Name)
+		}
+		log.Warn().Msgf("No valid ingress or egress rules generated for pod %s. Applying default-deny.", podName)
+		truePtr := true
+		policy.Name = GetPolicyName(fmt.Sprintf("%s-%s", podDetail.Namespace, podDetail.Name), "cilium-clusterwide-policy-deny-all")
+		policy.Labels = CreateStandardLabels(podDetail.Name, "cilium-clusterwide-policy-deny-all")
+		policy.Spec.EnableDefaultDeny = ciliumapi.DefaultDenyConfig{
+			Ingress: &truePtr,
+			Egress:  &truePtr,
+		}
+	}
+
+	log.Debug().Msgf("Added %d ingress and %d egress rules to Cilium clusterwide policy", len(policy.Spec.Ingress), len(policy.Spec.Egress))
+
+	return policy, nil
+}
+
+// resolvePeer resolves a peer IP to entities, a namespace-pinned endpoint selector or a host CIDR
+func (g *CiliumClusterwidePolicyGenerator) resolvePeer(peerIP string) ([]ciliumapi.EndpointSelector, ciliumapi.CIDRSlice, ciliumapi.EntitySlice) {
+	if entities := g.cilium.clusterEntities(peerIP); len(entities) > 0 {
+		return nil, nil, entities
+	}
+
+	peer := ResolvePeer(peerIP)
+	if peer.Kind == PeerExternal {
+		if entities := g.cilium.worldEntities(peerIP); len(entities) > 0 {
+			return nil, nil, entities
+		}
+		return nil, ciliumapi.CIDRSlice{ciliumapi.CIDR(hostCIDR(peerIP))}, nil
+	}
+
+	return []ciliumapi.EndpointSelector{g.createNamespacedSelector(peer.Labels, peer.Namespace)}, nil, nil
+}
+
+// createNamespacedSelector creates an EndpointSelector matching the labels within a single namespace
+func (g *CiliumClusterwidePolicyGenerator) createNamespacedSelector(podLabels map[string]string, namespace string) ciliumapi.EndpointSelector {
+	labelArray := make(labels.LabelArray, 0, len(podLabels)+1)
+	for key, value := range podLabels {
+		labelArray = append(labelArray, labels.NewLabel(key, value, labels.LabelSourceK8s))
+	}
+	labelArray = append(labelArray, labels.NewLabel(ciliumio.PodNamespaceLabel, namespace, labels.LabelSourceK8s))
+
+	return ciliumapi.NewESFromLabels(labelArray...)
+}
+
+// createNodeSelector creates a host policy node selector from the pod's nodeSelector.
+// Pods without a nodeSelector, such as most DaemonSets, run on every node.
+func (g *CiliumClusterwidePolicyGenerator) createNodeSelector(nodeLabels map[string]string) ciliumapi.EndpointSelector {
+	if len(nodeLabels) == 0 {
+		return ciliumapi.WildcardEndpointSelector
+	}
+
+	labelArray := make(labels.LabelArray, 0, len(nodeLabels))
+	for key, value := range nodeLabels {
+		labelArray = append(labelArray, labels.NewLabel(key, value, labels.LabelSourceAny))
+	}
+
+	return ciliumapi.NewESFromLabels(labelArray...)
+}
@@ -0,0 +1,158 @@
+package network
+
+import (
+	"testing"
+
+	ciliumv2 "github.com/cilium/cilium/pkg/k8s/apis/cilium.io/v2"
+	ciliumapi "github.com/cilium/cilium/pkg/policy/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xentra-ai/advisor/pkg/api"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+func TestCiliumClusterwidePolicyGenerator_Generate_CrossNamespace(t *testing.T) {
+	// --- Setup Mocks ---
+	origGetPodSpecFunc := api.GetPodSpecFunc
+	origGetSvcSpecFunc := api.GetSvcSpecFunc
+	defer func() {
+		api.GetPodSpecFunc = origGetPodSpecFunc
+		api.GetSvcSpecFunc = origGetSvcSpecFunc
+	}()
+
+	api.GetPodSpecFunc = func(ip string) (*api.PodDetail, error) {
+		if ip == "10.0.0.1" {
+			return mockPodDetail("prometheus-0", "monitoring", ip, map[string]string{"app": "prometheus"}), nil
+		}
+		return nil, nil
+	}
+	api.GetSvcSpecFunc = func(ip string) (*api.SvcDetail, error) {
+		if ip == "10.0.0.2" {
+			return mockSvcDetail("postgres", "data", ip, map[string]string{"app": "postgres"}), nil
+		}
+		return nil, nil
+	}
+	// --- End Mocks ---
+
+	gen := NewCiliumClusterwidePolicyGenerator()
+	podDetail := mockPodDetail("api-pod", "prod", "192.168.1.10", map[string]string{"app": "api"})
+	podTraffic := []api.PodTraffic{
+		{SrcIP: "192.168.1.10", SrcPodPort: "9090", DstIP: "10.0.0.1", Protocol: corev1.ProtocolTCP, TrafficType: "INGRESS"},
+		{SrcIP: "192.168.1.10", DstIP: "10.0.0.2", DstPort: "5432", Protocol: corev1.ProtocolTCP, TrafficType: "EGRESS"},
+		{SrcIP: "192.168.1.10", DstIP: "8.8.8.8", DstPort: "53", Protocol: corev1.ProtocolUDP, TrafficType: "EGRESS"},
+	}
+
+	policyInterface, err := gen.Generate("api-pod", podTraffic, podDetail)
+	require.NoError(t, err)
+	policy, ok := policyInterface.(*ciliumv2.CiliumClusterwideNetworkPolicy)
+	require.True(t, ok)
+
+	assert.Equal(t, "prod-api-pod-cilium-clusterwide-policy", policy.Name)
+	assert.Empty(t, policy.Namespace)
+	assert.Nil(t, policy.Spec.NodeSelector.LabelSelector)
+	assert.Equal(t, "prod", policy.Spec.EndpointSelector.MatchLabels[ciliumNamespaceLabelKey])
+	assert.Equal(t, "api", policy.Spec.EndpointSelector.MatchLabels["k8s.app"])
+
+	require.Len(t, policy.Spec.Ingress, 1)
+	fromEndpoint := policy.Spec.Ingress[0].FromEndpoints[0]
+	assert.Equal(t, "monitoring", fromEndpoint.MatchLabels[ciliumNamespaceLabelKey])
+	assert.Equal(t, "prometheus", fromEndpoint.MatchLabels["k8s.app"])
+	assert.Equal(t, "9090", policy.Spec.Ingress[0].ToPorts[0].Ports[0].Port)
+
+	require.Len(t, policy.Spec.Egress, 2)
+	toEndpoint := policy.Spec.Egress[0].ToEndpoints[0]
+	assert.Equal(t, "data", toEndpoint.MatchLabels[ciliumNamespaceLabelKey])
+	assert.Equal(t, "postgres", toEndpoint.MatchLabels["k8s.app"])
+	assert.Equal(t, ciliumapi.CIDRSlice{"8.8.8.8/32"}, policy.Spec.Egress[1].ToCIDR)
+
+	policyYAML, err := yaml.Marshal(policy)
+	require.NoError(t, err)
+	assert.NotContains(t, string(policyYAML), "nodeSelector")
+	assert.Contains(t, string(policyYAML), "kind: CiliumClusterwideNetworkPolicy")
+}
+
+func TestCiliumClusterwidePolicyGenerator_Generate_HostNetwork(t *testing.T) {
+	origGetPodSpecFunc := api.GetPodSpecFunc
+	origGetSvcSpecFunc := api.GetSvcSpecFunc
+	defer func() {
+		api.GetPodSpecFunc = origGetPodSpecFunc
+		api.GetSvcSpecFunc = origGetSvcSpecFunc
+	}()
+	api.GetPodSpecFunc = func(ip string) (*api.PodDetail, error) { return nil, nil }
+	api.GetSvcSpecFunc = func(ip string) (*api.SvcDetail, error) { return nil, nil }
+
+	gen := NewCiliumClusterwidePolicyGenerator()
+	podTraffic := []api.PodTraffic{
+		{SrcIP: "172.18.0.2", SrcPodPort: "9100", DstIP: "10.0.0.1", Protocol: corev1.ProtocolTCP, TrafficType: "INGRESS"},
+	}
+
+	// A DaemonSet without a nodeSelector runs on, and is secured on, every node
+	podDetail := mockPodDetail("node-exporter-abc", "monitoring", "172.18.0.2", map[string]string{"app": "node-exporter"})
+	podDetail.Pod.Spec.HostNetwork = true
+
+	policyInterface, err := gen.Generate("node-exporter-abc", podTraffic, podDetail)
+	require.NoError(t, err)
+	policy := policyInterface.(*ciliumv2.CiliumClusterwideNetworkPolicy)
+	require.NotNil(t, policy.Spec.NodeSelector.LabelSelector)
+	assert.Empty(t, policy.Spec.NodeSelector.MatchLabels)
+	assert.Nil(t, policy.Spec.EndpointSelector.LabelSelector)
+	require.Len(t, policy.Spec.Ingress, 1)
+	assert.Equal(t, ciliumapi.CIDRSlice{"10.0.0.1/32"}, policy.Spec.Ingress[0].FromCIDR)
+
+	// The pod's nodeSelector narrows the host policy to the matching nodes
+	podDetail.Pod.Spec.NodeSelector = map[string]string{"node-role.kubernetes.io/worker": ""}
+	policyInterface, err = gen.Generate("node-exporter-abc", podTraffic, podDetail)
+	require.NoError(t, err)
+	policy = policyInterface.(*ciliumv2.CiliumClusterwideNetworkPolicy)
+	assert.Contains(t, policy.Spec.NodeSelector.MatchLabels, "any.node-role.kubernetes.io/worker")
+}
+
+func TestCiliumClusterwidePolicyGenerator_Generate_DefaultDeny(t *testing.T) {
+	gen := NewCiliumClusterwidePolicyGenerator()
+	podDetail := mockPodDetail("test-pod", "default", "192.168.1.10", map[string]string{"app": "test"})
+
+	policyInterface, err := gen.Generate("test-pod", nil, podDetail)
+	require.NoError(t, err)
+	policy := policyInterface.(*ciliumv2.CiliumClusterwideNetworkPolicy)
+	assert.Equal(t, "default-test-pod-cilium-clusterwide-policy-deny-all", policy.Name)
+	require.NotNil(t, policy.Spec.EnableDefaultDeny.Ingress)
+	assert.True(t, *policy.Spec.EnableDefaultDeny.Ingress)
+	assert.True(t, *policy.Spec.EnableDefaultDeny.Egress)
+
+	// A host policy without rules would default-deny every node, so none is generated
+	podDetail.Pod.Spec.HostNetwork = true
+	policyInterface, err = gen.Generate("test-pod", nil, podDetail)
+	assert.Error(t, err)
+	assert.Nil(t, policyInterface)
+}
+
+func TestCiliumClusterwidePolicyGenerator_Generate_IPv6Peer(t *testing.T) {
+	origGetPodSpecFunc := api.GetPodSpecFunc
+	origGetSvcSpecFunc := api.GetSvcSpecFunc
+	defer func() {
+		api.GetPodSpecFunc = origGetPodSpecFunc
+		api.GetSvcSpecFunc = origGetSvcSpecFunc
+	}()
+	api.GetPodSpecFunc = func(ip string) (*api.PodDetail, error) { return nil, nil }
+	api.GetSvcSpecFunc = func(ip string) (*api.SvcDetail, error) { return nil, nil }
+
+	gen := NewCiliumClusterwidePolicyGenerator()
+	podDetail := mockPodDetail("api-pod", "prod", "fd00::10", map[string]string{"app": "api"})
+	podTraffic := []api.PodTraffic{
+		{SrcIP: "fd00::10", SrcPodPort: "8080", DstIP: "2001:db8::1", Protocol: corev1.ProtocolTCP, TrafficType: "INGRESS"},
+		{SrcIP: "fd00::10", DstIP: "2001:db8::2", DstPort: "443", Protocol: corev1.ProtocolTCP, TrafficType: "EGRESS"},
+	}
+
+	policyInterface, err := gen.Generate("api-pod", podTraffic, podDetail)
+	require.NoError(t, err)
+	policy := policyInterface.(*ciliumv2.CiliumClusterwideNetworkPolicy)
+	require.Len(t, policy.Spec.Ingress, 1)
+	assert.Equal(t, ciliumapi.CIDRSlice{"2001:db8::1/128"}, policy.Spec.Ingress[0].FromCIDR)
+	require.Len(t, policy.Spec.Egress, 1)
+	assert.Equal(t, ciliumapi.CIDRSlice{"2001:db8::2/128"}, policy.Spec.Egress[0].ToCIDR)
+}
+
+func TestCiliumClusterwidePolicyGenerator_GetType(t *testing.T) {
+	assert.Equal(t, CiliumClusterwidePolicy, NewCiliumClusterwidePolicyGenerator().GetType())
+}
@@ -12,12 +12,34 @@ import (
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
+// CiliumPolicyOptions configures the Cilium policy generators
+type CiliumPolicyOptions struct {
+	// PortRanges configures the merging of adjacent ports into endPort ranges
+	PortRanges PortRangeOptions
+	// Cluster holds the API server and node addresses matched with entities
+	Cluster ClusterInfo
+	// AllowWorld matches public peers with the world entity instead of their /32 CIDR
+	AllowWorld bool
+	// Endpoints resolves the endpoints of Services without a selector, nil keeps their ClusterIP
+	Endpoints EndpointResolver
+	// ExternalNames holds the ExternalName Services keyed by the addresses their names resolve to,
+	// peers at these addresses are matched by the name instead of their CIDR
+	ExternalNames map[string]ExternalNameService
+}
+
 // CiliumPolicyGenerator generates Cilium NetworkPolicy resources
-type CiliumPolicyGenerator struct{}
+type CiliumPolicyGenerator struct {
+	options CiliumPolicyOptions
+}
 
 // NewCiliumPolicyGenerator creates a new generator for Cilium NetworkPolicy resources
 func NewCiliumPolicyGenerator() *CiliumPolicyGenerator {
-	return &CiliumPolicyGenerator{}
+	return NewCiliumPolicyGeneratorWithOptions(CiliumPolicyOptions{PortRanges: DefaultPortRangeOptions()})
+}
+
+// NewCiliumPolicyGeneratorWithOptions creates a new generator for Cilium NetworkPolicy resources
+func NewCiliumPolicyGeneratorWithOptions(options CiliumPolicyOptions) *CiliumPolicyGenerator {
+	return &CiliumPolicyGenerator{options: options}
 }
 
 // GetType returns the policy type
@@ -140,10 +162,11 @@ func (g *CiliumPolicyGenerator) processTrafficRules(podTraffic []api.PodTraffic,
 				log.Warn().Err(err).Msgf("Skipping ingress traffic record due to invalid pod port: %s", traffic.SrcPodPort)
 				continue
 			}
-			port = intstr.FromInt(portInt)
 			protocolStr = string(traffic.Protocol)
+			// Prefer the container port name declared by our pod
+			port = ingressPort(podDetail, portInt, protocolStr)
 
-			log.Debug().Msgf("Processing CILIUM INGRESS: allowing peer %s to reach our pod port %d (%s)", peer, portInt, protocolStr)
+			log.Debug().Msgf("Processing CILIUM INGRESS: allowing peer %s to reach our pod port %s (%s)", peer, port.String(), protocolStr)
 			ingressRules = g.addOrUpdateRule(ingressRules, peer, port, protocolStr)
 
 		} else if IsEgressTraffic(traffic, podDetail) {
@@ -167,10 +190,11 @@ func (g *CiliumPolicyGenerator) processTrafficRules(podTraffic []api.PodTraffic,
 				log.Warn().Err(err).Msgf("Skipping egress traffic record due to invalid destination port: %s", traffic.DstPort)
 				continue
 			}
-			port = intstr.FromInt(portInt)
 			protocolStr = string(traffic.Protocol)
+			// Services are resolved before policies are enforced, so match their target port
+			port = egressPort(lookupSelectorService(peer), portInt, protocolStr)
 
-			log.Debug().Msgf("Processing CILIUM EGRESS: allowing our pod to reach peer %s on port %d (%s)", peer, portInt, protocolStr)
+			log.Debug().Msgf("Processing CILIUM EGRESS: allowing our pod to reach peer %s on port %s (%s)", peer, port.String(), protocolStr)
 			egressRules = g.addOrUpdateRule(egressRules, peer, port, protocolStr)
 		} else {
 			log.Debug().Msgf("Skipping traffic record with unknown type: %s", traffic.TrafficType)
@@ -270,6 +294,11 @@ func (g *CiliumPolicyGenerator) transformToCiliumEgressRules(rules []NetworkPoli
 
 	// Create egress rules
 	for peerIP, ports := range peerRules {
+		if serviceRule := g.createCiliumServiceEgressRule(peerIP, ports); serviceRule != nil {
+			egressRules = append(egressRules, *serviceRule)
+			continue
+		}
+
 		egressRule := g.createCiliumEgressRuleForPeer(peerIP, ports)
 		if egressRule != nil {
 			egressRules = append(egressRules, *egressRule)
@@ -284,12 +313,15 @@ func (g *CiliumPolicyGenerator) createCiliumIngressRuleForPeer(peerIP string, po
 	log.Debug().Msgf("Creating Cilium ingress rule for peer IP: %s", peerIP)
 
 	// Try to resolve peer information
-	fromEndpoints, fromCIDR := g.resolvePeerForCilium(peerIP)
+	fromEndpoints, fromCIDR, fromEntities := g.resolvePeerForCilium(peerIP)
 
 	var ingressRule ciliumapi.IngressRule
 
 	// Set the peer selector
-	if len(fromEndpoints) > 0 {
+	if len(fromEntities) > 0 {
+		ingressRule.FromEntities = fromEntities
+		log.Debug().Msgf("Using FromEntities %v for peer %s", fromEntities, peerIP)
+	} else if len(fromEndpoints) > 0 {
 		ingressRule.FromEndpoints = fromEndpoints
 		log.Debug().Msgf("Using FromEndpoints for peer %s", peerIP)
 	} else if len(fromCIDR) > 0 {
@@ -311,12 +343,15 @@ func (g *CiliumPolicyGenerator) createCiliumEgressRuleForPeer(peerIP string, por
 	log.Debug().Msgf("Creating Cilium egress rule for peer IP: %s", peerIP)
 
 	// Try to resolve peer information
-	toEndpoints, toCIDR := g.resolvePeerForCilium(peerIP)
+	toEndpoints, toCIDR, toEntities := g.resolvePeerForCilium(peerIP)
 
 	var egressRule ciliumapi.EgressRule
 
 	// Set the peer selector
-	if len(toEndpoints) > 0 {
+	if len(toEntities) > 0 {
+		egressRule.ToEntities = toEntities
+		log.Debug().Msgf("Using ToEntities %v for peer %s", toEntities, peerIP)
+	} else if len(toEndpoints) > 0 {
 		egressRule.ToEndpoints = toEndpoints
 		log.Debug().Msgf("Using ToEndpoints for peer %s", peerIP)
 	} else if len(toCIDR) > 0 {
@@ -333,17 +368,54 @@ func (g *CiliumPolicyGenerator) createCiliumEgressRuleForPeer(peerIP string, por
 	return &egressRule
 }
 
-// resolvePeerForCilium resolves peer IP to either EndpointSelector or CIDR
-func (g *CiliumPolicyGenerator) resolvePeerForCilium(peerIP string) ([]ciliumapi.EndpointSelector, ciliumapi.CIDRSlice) {
+// createCiliumServiceEgressRule creates an egress rule for a Service that cannot be matched by a
+// selector. ExternalName Services become toFQDNs rules for the external name, Services without a
+// selector are expanded into the CIDRs of their endpoints on their target ports.
+func (g *CiliumPolicyGenerator) createCiliumServiceEgressRule(peerIP string, ports []networkingv1.NetworkPolicyPort) *ciliumapi.EgressRule {
+	// The kubernetes Service is matched by the kube-apiserver entity
+	if len(g.clusterEntities(peerIP)) > 0 {
+		return nil
+	}
+
+	if svc, ok := g.options.ExternalNames[peerIP]; ok {
+		log.Debug().Msgf("IP %s is an address of %s, the external name of service %s/%s, using ToFQDNs",
+			peerIP, svc.ExternalName, svc.Namespace, svc.Name)
+		return &ciliumapi.EgressRule{
+			ToFQDNs: ciliumapi.FQDNSelectorSlice{{MatchName: svc.ExternalName}},
+			ToPorts: g.convertPortsToCiliumPortRules(ports),
+		}
+	}
+
+	svcDetail, endpoints := selectorlessServiceEndpoints(g.options.Endpoints, peerIP)
+	if endpoints == nil {
+		return nil
+	}
+	egressRule := &ciliumapi.EgressRule{
+		ToPorts: g.convertPortsToCiliumPortRules(endpointTargetPorts(svcDetail, endpoints, ports)),
+	}
+	for _, address := range endpoints.Addresses {
+		egressRule.ToCIDR = append(egressRule.ToCIDR, ciliumapi.CIDR(hostCIDR(address)))
+	}
+	return egressRule
+}
+
+// resolvePeerForCilium resolves peer IP to either entities, EndpointSelector or CIDR
+func (g *CiliumPolicyGenerator) resolvePeerForCilium(peerIP string) ([]ciliumapi.EndpointSelector, ciliumapi.CIDRSlice, ciliumapi.EntitySlice) {
+	// The API server and nodes are not endpoints, even when host network pods share their IP
+	if entities := g.clusterEntities(peerIP); len(entities) > 0 {
+		return nil, nil, entities
+	}
+
 	// Try to get Service info first
+	// Headless Services are resolved by the endpoint the client connected to
 	svcSpec, err := api.GetSvcSpec(peerIP)
-	if err == nil && svcSpec != nil && len(svcSpec.Service.Spec.Selector) > 0 {
+	if err == nil && svcSpec != nil && len(svcSpec.Service.Spec.Selector) > 0 && !isHeadlessService(svcSpec) {
 		log.Debug().Msgf("Found service %s/%s with selector %v for IP %s",
 			svcSpec.SvcNamespace, svcSpec.SvcName, svcSpec.Service.Spec.Selector, peerIP)
 
 		// Create EndpointSelector from service labels
 		selector := g.createEndpointSelector(svcSpec.Service.Spec.Selector)
-		return []ciliumapi.EndpointSelector{selector}, nil
+		return []ciliumapi.EndpointSelector{selector}, nil, nil
 	}
 
 	// Try to get Pod info
@@ -354,17 +426,46 @@ func (g *CiliumPolicyGenerator) resolvePeerForCilium(peerIP string) ([]ciliumapi
 
 		// Create EndpointSelector from pod labels
 		selector := g.createEndpointSelector(podSpec.Pod.Labels)
-		return []ciliumapi.EndpointSelector{selector}, nil
+		return []ciliumapi.EndpointSelector{selector}, nil, nil
+	}
+
+	if entities := g.worldEntities(peerIP); len(entities) > 0 {
+		return nil, nil, entities
 	}
 
 	// Fall back to CIDR for external IPs or unresolvable cluster IPs
 	log.Debug().Msgf("Using CIDR for peer %s", peerIP)
 	cidr := ciliumapi.CIDR(fmt.Sprintf("%s/32", peerIP))
-	return nil, ciliumapi.CIDRSlice{cidr}
+	return nil, ciliumapi.CIDRSlice{cidr}, nil
+}
+
+// clusterEntities returns the entities of the API server and node addresses
+func (g *CiliumPolicyGenerator) clusterEntities(peerIP string) ciliumapi.EntitySlice {
+	switch {
+	case g.options.Cluster.IsAPIServer(peerIP):
+		log.Debug().Msgf("Peer %s is the Kubernetes API server", peerIP)
+		return ciliumapi.EntitySlice{ciliumapi.EntityKubeAPIServer}
+	case g.options.Cluster.IsNode(peerIP):
+		// Pods move between nodes, so the rule covers the local host as well as the other nodes
+		log.Debug().Msgf("Peer %s is a cluster node", peerIP)
+		return ciliumapi.EntitySlice{ciliumapi.EntityHost, ciliumapi.EntityRemoteNode}
+	}
+	return nil
 }
 
-// convertPortsToCiliumPortRules converts standard ports to Cilium PortRules
+// worldEntities returns the world entity for public peers when broad world access is allowed
+func (g *CiliumPolicyGenerator) worldEntities(peerIP string) ciliumapi.EntitySlice {
+	if !g.options.AllowWorld || !isPublicIP(peerIP) {
+		return nil
+	}
+	log.Debug().Msgf("Peer %s is on the internet, using the world entity", peerIP)
+	return ciliumapi.EntitySlice{ciliumapi.EntityWorld}
+}
+
+// convertPortsToCiliumPortRules converts standard ports to Cilium PortRules, merging adjacent
+// ports into endPort ranges when enabled
 func (g *CiliumPolicyGenerator) convertPortsToCiliumPortRules(ports []networkingv1.NetworkPolicyPort) ciliumapi.PortRules {
+	ports = compactPortRanges(ports, g.options.PortRanges)
 	portRules := make(ciliumapi.PortRules, 0, len(ports))
 
 	for _, port := range ports {
@@ -373,13 +474,16 @@ func (g *CiliumPolicyGenerator) convertPortsToCiliumPortRules(ports []networking
 			continue
 		}
 
+		portProtocol := ciliumapi.PortProtocol{
+			Port:     port.Port.String(),
+			Protocol: ciliumapi.L4Proto(string(*port.Protocol)),
+		}
+		if port.EndPort != nil {
+			portProtocol.EndPort = *port.EndPort
+		}
+
 		portRule := ciliumapi.PortRule{
-			Ports: []ciliumapi.PortProtocol{
-				{
-					Port:     port.Port.String(),
-					Protocol: ciliumapi.L4Proto(string(*port.Protocol)),
-				},
-			},
+			Ports: []ciliumapi.PortProtocol{portProtocol},
 		}
 		portRules = append(portRules, portRule)
 	}
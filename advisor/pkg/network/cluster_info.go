@@ -0,0 +1,36 @@
+package network
+
+import (
+	"net"
+	"slices"
+)
+
+// ClusterInfo holds the addresses of cluster components that policies address as entities
+// instead of pods or CIDRs
+type ClusterInfo struct {
+	// APIServerIPs are the ClusterIP of the kubernetes Service and the addresses of its endpoints
+	APIServerIPs []string
+	// NodeIPs are the internal and external addresses of the cluster's nodes
+	NodeIPs []string
+}
+
+// IsAPIServer reports whether the IP belongs to the Kubernetes API server
+func (c ClusterInfo) IsAPIServer(ip string) bool {
+	return slices.Contains(c.APIServerIPs, ip)
+}
+
+// IsNode reports whether the IP belongs to a cluster node
+func (c ClusterInfo) IsNode(ip string) bool {
+	return slices.Contains(c.NodeIPs, ip)
+}
+
+// isPublicIP reports whether the IP is routable on the internet, i.e. not private, loopback,
+// link-local or unspecified
+func isPublicIP(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	return !parsed.IsPrivate() && !parsed.IsLoopback() && !parsed.IsLinkLocalUnicast() &&
+		!parsed.IsUnspecified() && !parsed.IsMulticast()
+}
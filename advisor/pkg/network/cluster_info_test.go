@@ -0,0 +1,97 @@
+package network
+
+import (
+	"testing"
+
+	ciliumv2 "github.com/cilium/cilium/pkg/k8s/apis/cilium.io/v2"
+	ciliumapi "github.com/cilium/cilium/pkg/policy/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xentra-ai/advisor/pkg/api"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+func TestIsPublicIP(t *testing.T) {
+	assert.True(t, isPublicIP("8.8.8.8"))
+	assert.True(t, isPublicIP("2606:4700::1111"))
+	assert.False(t, isPublicIP("10.0.0.1"))
+	assert.False(t, isPublicIP("192.168.1.1"))
+	assert.False(t, isPublicIP("169.254.169.254"))
+	assert.False(t, isPublicIP("127.0.0.1"))
+	assert.False(t, isPublicIP("not-an-ip"))
+}
+
+func TestCiliumPolicyGenerator_Generate_Entities(t *testing.T) {
+	// --- Setup Mocks ---
+	origGetPodSpecFunc := api.GetPodSpecFunc
+	origGetSvcSpecFunc := api.GetSvcSpecFunc
+	defer func() {
+		api.GetPodSpecFunc = origGetPodSpecFunc
+		api.GetSvcSpecFunc = origGetSvcSpecFunc
+	}()
+
+	api.GetPodSpecFunc = func(ip string) (*api.PodDetail, error) {
+		if ip == "172.18.0.4" {
+			// Host network pods share the node IP, they must not become endpoint selectors
+			return mockPodDetail("node-exporter-abc", "monitoring", ip, map[string]string{"app": "node-exporter"}), nil
+		}
+		return nil, nil
+	}
+	api.GetSvcSpecFunc = func(ip string) (*api.SvcDetail, error) { return nil, nil }
+	// --- End Mocks ---
+
+	cluster := ClusterInfo{APIServerIPs: []string{"10.96.0.1", "172.18.0.2"}, NodeIPs: []string{"172.18.0.2", "172.18.0.4"}}
+	podDetail := mockPodDetail("api-pod", "prod", "192.168.1.10", map[string]string{"app": "api"})
+	podTraffic := []api.PodTraffic{
+		{SrcIP: "192.168.1.10", SrcPodPort: "8443", DstIP: "172.18.0.2", Protocol: corev1.ProtocolTCP, TrafficType: "INGRESS"},
+		{SrcIP: "192.168.1.10", DstIP: "10.96.0.1", DstPort: "443", Protocol: corev1.ProtocolTCP, TrafficType: "EGRESS"},
+		{SrcIP: "192.168.1.10", DstIP: "172.18.0.4", DstPort: "9100", Protocol: corev1.ProtocolTCP, TrafficType: "EGRESS"},
+		{SrcIP: "192.168.1.10", DstIP: "93.184.215.14", DstPort: "443", Protocol: corev1.ProtocolTCP, TrafficType: "EGRESS"},
+		{SrcIP: "192.168.1.10", DstIP: "10.20.0.5", DstPort: "443", Protocol: corev1.ProtocolTCP, TrafficType: "EGRESS"},
+	}
+
+	t.Run("namespaced", func(t *testing.T) {
+		gen := NewCiliumPolicyGeneratorWithOptions(CiliumPolicyOptions{Cluster: cluster, AllowWorld: true})
+		policyInterface, err := gen.Generate("api-pod", podTraffic, podDetail)
+		require.NoError(t, err)
+		policy := policyInterface.(*ciliumv2.CiliumNetworkPolicy)
+
+		require.Len(t, policy.Spec.Ingress, 1)
+		// API server addresses take precedence over the node they run on
+		assert.Equal(t, ciliumapi.EntitySlice{ciliumapi.EntityKubeAPIServer}, policy.Spec.Ingress[0].FromEntities)
+
+		entities := map[string]ciliumapi.EntitySlice{}
+		cidrs := map[string]ciliumapi.CIDRSlice{}
+		for _, rule := range policy.Spec.Egress {
+			port := rule.ToPorts[0].Ports[0].Port
+			if len(rule.ToEntities) > 0 {
+				entities[port] = append(entities[port], rule.ToEntities...)
+			}
+			cidrs[port] = append(cidrs[port], rule.ToCIDR...)
+			assert.Empty(t, rule.ToEndpoints)
+		}
+		assert.ElementsMatch(t, ciliumapi.EntitySlice{ciliumapi.EntityKubeAPIServer, ciliumapi.EntityWorld}, entities["443"])
+		assert.Equal(t, ciliumapi.EntitySlice{ciliumapi.EntityHost, ciliumapi.EntityRemoteNode}, entities["9100"])
+		// Private addresses outside the cluster keep their CIDR
+		assert.Equal(t, ciliumapi.CIDRSlice{"10.20.0.5/32"}, cidrs["443"])
+
+		policyYAML, err := yaml.Marshal(policy)
+		require.NoError(t, err)
+		assert.Contains(t, string(policyYAML), "- kube-apiserver")
+		assert.Contains(t, string(policyYAML), "- remote-node")
+	})
+
+	t.Run("world requires opt-in", func(t *testing.T) {
+		gen := NewCiliumClusterwidePolicyGeneratorWithOptions(CiliumPolicyOptions{Cluster: cluster})
+		policyInterface, err := gen.Generate("api-pod", podTraffic, podDetail)
+		require.NoError(t, err)
+		policy := policyInterface.(*ciliumv2.CiliumClusterwideNetworkPolicy)
+
+		require.Len(t, policy.Spec.Egress, 4)
+		assert.Equal(t, ciliumapi.EntitySlice{ciliumapi.EntityKubeAPIServer}, policy.Spec.Egress[0].ToEntities)
+		assert.Equal(t, ciliumapi.EntitySlice{ciliumapi.EntityHost, ciliumapi.EntityRemoteNode}, policy.Spec.Egress[1].ToEntities)
+		assert.Empty(t, policy.Spec.Egress[2].ToEntities)
+		assert.Equal(t, ciliumapi.CIDRSlice{"93.184.215.14/32"}, policy.Spec.Egress[2].ToCIDR)
+	})
+}
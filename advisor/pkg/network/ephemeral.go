@@ -0,0 +1,182 @@
+package network
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	log "github.com/rs/zerolog/log"
+	"github.com/xentra-ai/advisor/pkg/api"
+	"github.com/xentra-ai/advisor/pkg/common"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// DefaultEphemeralPortMin is the lower bound of the Linux net.ipv4.ip_local_port_range default
+	DefaultEphemeralPortMin = 32768
+	// DefaultEphemeralPortMax is the upper bound of the Linux net.ipv4.ip_local_port_range default
+	DefaultEphemeralPortMax = 60999
+)
+
+// EphemeralPortOptions configures the filtering of flows on ephemeral client ports
+type EphemeralPortOptions struct {
+	// Enabled drops flows classified as ephemeral before the policies are generated
+	Enabled bool
+	// Min and Max bound the range the kernel picks client ports from
+	Min int
+	Max int
+}
+
+// DefaultEphemeralPortOptions returns the default ephemeral port options, using the kernel's
+// default local port range. Filtering is opt-in, as it drops observed flows.
+func DefaultEphemeralPortOptions() EphemeralPortOptions {
+	return EphemeralPortOptions{
+		Enabled: false,
+		Min:     DefaultEphemeralPortMin,
+		Max:     DefaultEphemeralPortMax,
+	}
+}
+
+// ParseEphemeralPortRange parses a port range in the min-max form of ip_local_port_range
+func ParseEphemeralPortRange(value string) (int, int, error) {
+	bounds := strings.Split(value, "-")
+	if len(bounds) != 2 {
+		return 0, 0, fmt.Errorf("invalid port range '%s', expected <min>-<max>", value)
+	}
+	minPort, err := parsePort(strings.TrimSpace(bounds[0]))
+	if err != nil {
+		return 0, 0, err
+	}
+	maxPort, err := parsePort(strings.TrimSpace(bounds[1]))
+	if err != nil {
+		return 0, 0, err
+	}
+	if minPort > maxPort {
+		return 0, 0, fmt.Errorf("invalid port range '%s', min is greater than max", value)
+	}
+	return minPort, maxPort, nil
+}
+
+// DroppedFlow is a traffic record left out of the generated policy, with the reason why
+type DroppedFlow struct {
+	Direction string `json:"direction"`
+	PeerIP    string `json:"peerIP"`
+	PodPort   string `json:"podPort,omitempty"`
+	PeerPort  string `json:"peerPort,omitempty"`
+	Protocol  string `json:"protocol"`
+	Reason    string `json:"reason"`
+}
+
+// DroppedFlowsReport lists the flows dropped while generating the policy of a pod
+type DroppedFlowsReport struct {
+	Pod          string        `json:"pod"`
+	Namespace    string        `json:"namespace"`
+	DroppedFlows []DroppedFlow `json:"droppedFlows"`
+}
+
+// FilterEphemeralFlows removes the flows that only describe ephemeral client ports, and returns
+// the kept traffic and the dropped flows
+func FilterEphemeralFlows(podTraffic []api.PodTraffic, podDetail *api.PodDetail, opts EphemeralPortOptions) ([]api.PodTraffic, []DroppedFlow) {
+	if !opts.Enabled || podDetail == nil {
+		return podTraffic, nil
+	}
+
+	kept := make([]api.PodTraffic, 0, len(podTraffic))
+	var dropped []DroppedFlow
+	for _, traffic := range podTraffic {
+		reason, ephemeral := classifyEphemeralFlow(traffic, podDetail, opts)
+		if !ephemeral {
+			kept = append(kept, traffic)
+			continue
+		}
+		log.Debug().Msgf("Dropping %s flow with peer %s: %s", traffic.TrafficType, traffic.DstIP, reason)
+		dropped = append(dropped, DroppedFlow{
+			Direction: traffic.TrafficType,
+			PeerIP:    traffic.DstIP,
+			PodPort:   traffic.SrcPodPort,
+			PeerPort:  traffic.DstPort,
+			Protocol:  string(traffic.Protocol),
+			Reason:    reason,
+		})
+	}
+
+	return kept, dropped
+}
+
+// classifyEphemeralFlow decides whether the port a rule would be built from is an ephemeral
+// client port, using the pod's declared container ports and the ports on both sides of the flow
+func classifyEphemeralFlow(traffic api.PodTraffic, podDetail *api.PodDetail, opts EphemeralPortOptions) (string, bool) {
+	podPort, podPortErr := strconv.Atoi(traffic.SrcPodPort)
+	peerPort, peerPortErr := strconv.Atoi(traffic.DstPort)
+
+	switch {
+	case IsIngressTraffic(traffic, podDetail):
+		// Ingress rules are built from our pod's port
+		if podPortErr != nil || !opts.isEphemeral(podPort) || declaresContainerPort(podDetail, podPort) {
+			return "", false
+		}
+		if peerPortErr == nil && peerPort > 0 && !opts.isEphemeral(peerPort) {
+			return fmt.Sprintf("pod port %d is ephemeral and peer port %d looks like a server port, the pod is the client", podPort, peerPort), true
+		}
+		return fmt.Sprintf("pod port %d is in the ephemeral range %d-%d and not declared as a container port", podPort, opts.Min, opts.Max), true
+
+	case IsEgressTraffic(traffic, podDetail):
+		// Egress rules are built from the peer's port, which is ephemeral when our pod is replying
+		if peerPortErr != nil || !opts.isEphemeral(peerPort) {
+			return "", false
+		}
+		if podPortErr == nil && podPort > 0 && (declaresContainerPort(podDetail, podPort) || !opts.isEphemeral(podPort)) {
+			return fmt.Sprintf("peer port %d is ephemeral and pod port %d is a server port, the flow is a reply", peerPort, podPort), true
+		}
+	}
+
+	return "", false
+}
+
+// isEphemeral reports whether a port lies in the ephemeral port range
+func (o EphemeralPortOptions) isEphemeral(port int) bool {
+	return port >= o.Min && port <= o.Max
+}
+
+// declaresContainerPort reports whether any container of the pod declares the port, named or not
+func declaresContainerPort(podDetail *api.PodDetail, port int) bool {
+	for _, container := range podDetail.Pod.Spec.Containers {
+		for _, containerPort := range container.Ports {
+			if int(containerPort.ContainerPort) == port {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// handleDroppedFlows reports the flows dropped for a policy, saving the report next to the policy
+func (s *PolicyService) handleDroppedFlows(output *PolicyOutput) error {
+	if len(output.DroppedFlows) == 0 {
+		return nil
+	}
+
+	log.Warn().Msgf("Dropped %d flows on ephemeral ports for pod %s", len(output.DroppedFlows), output.PodName)
+	for _, flow := range output.DroppedFlows {
+		log.Info().Msgf("  %s %s (%s): %s", flow.Direction, flow.PeerIP, flow.Protocol, flow.Reason)
+	}
+
+	if s.config.GetOutputDir() == "" {
+		return nil
+	}
+
+	reportYAML, err := yaml.Marshal(DroppedFlowsReport{
+		Pod:          output.PodName,
+		Namespace:    output.Namespace,
+		DroppedFlows: output.DroppedFlows,
+	})
+	if err != nil {
+		return err
+	}
+	filename, err := common.SaveToFile(s.config.GetOutputDir(), "networkpolicy-report", output.Namespace, output.PodName, reportYAML)
+	if err != nil {
+		return err
+	}
+	log.Info().Msgf("Dropped flows report for pod %s saved to %s", output.PodName, filename)
+	return nil
+}
@@ -0,0 +1,135 @@
+package network
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xentra-ai/advisor/pkg/api"
+	"github.com/xentra-ai/advisor/pkg/common"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+func TestFilterEphemeralFlows(t *testing.T) {
+	podDetail := mockPodDetail("api-pod", "prod", "192.168.1.10", map[string]string{"app": "api"})
+	podDetail.Pod.Spec.Containers = []corev1.Container{{
+		Name:  "api",
+		Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: 8080}, {ContainerPort: 40000}},
+	}}
+
+	podTraffic := []api.PodTraffic{
+		// Regular ingress to a declared port
+		{SrcPodPort: "8080", DstIP: "10.0.0.1", DstPort: "51234", Protocol: corev1.ProtocolTCP, TrafficType: "INGRESS"},
+		// Declared ports in the ephemeral range are kept
+		{SrcPodPort: "40000", DstIP: "10.0.0.1", DstPort: "51234", Protocol: corev1.ProtocolTCP, TrafficType: "INGRESS"},
+		// Client side of an outgoing connection recorded as ingress
+		{SrcPodPort: "45678", DstIP: "10.0.0.2", DstPort: "5432", Protocol: corev1.ProtocolTCP, TrafficType: "INGRESS"},
+		// Undeclared ephemeral pod port without a peer port
+		{SrcPodPort: "50000", DstIP: "10.0.0.3", Protocol: corev1.ProtocolUDP, TrafficType: "INGRESS"},
+		// Regular egress from an ephemeral client port
+		{SrcPodPort: "45679", DstIP: "10.0.0.2", DstPort: "5432", Protocol: corev1.ProtocolTCP, TrafficType: "EGRESS"},
+		// Reply from our server port misclassified as egress
+		{SrcPodPort: "8080", DstIP: "10.0.0.1", DstPort: "51234", Protocol: corev1.ProtocolTCP, TrafficType: "EGRESS"},
+		// Egress to a high port without a local port is kept
+		{DstIP: "10.0.0.4", DstPort: "33000", Protocol: corev1.ProtocolTCP, TrafficType: "EGRESS"},
+	}
+
+	options := DefaultEphemeralPortOptions()
+	options.Enabled = true
+	kept, dropped := FilterEphemeralFlows(podTraffic, podDetail, options)
+	require.Len(t, kept, 4)
+	assert.Equal(t, "8080", kept[0].SrcPodPort)
+	assert.Equal(t, "40000", kept[1].SrcPodPort)
+	assert.Equal(t, "45679", kept[2].SrcPodPort)
+	assert.Equal(t, "33000", kept[3].DstPort)
+
+	require.Len(t, dropped, 3)
+	assert.Equal(t, "10.0.0.2", dropped[0].PeerIP)
+	assert.Contains(t, dropped[0].Reason, "the pod is the client")
+	assert.Contains(t, dropped[1].Reason, "not declared as a container port")
+	assert.Equal(t, "EGRESS", dropped[2].Direction)
+	assert.Contains(t, dropped[2].Reason, "the flow is a reply")
+
+	// Disabled keeps every flow
+	kept, dropped = FilterEphemeralFlows(podTraffic, podDetail, EphemeralPortOptions{})
+	assert.Len(t, kept, len(podTraffic))
+	assert.Empty(t, dropped)
+
+	// A narrower range keeps the client flows outside of it
+	kept, dropped = FilterEphemeralFlows(podTraffic, podDetail, EphemeralPortOptions{Enabled: true, Min: 49152, Max: 65535})
+	assert.Len(t, kept, 5)
+	assert.Len(t, dropped, 2)
+}
+
+func TestParseEphemeralPortRange(t *testing.T) {
+	minPort, maxPort, err := ParseEphemeralPortRange("32768-60999")
+	require.NoError(t, err)
+	assert.Equal(t, 32768, minPort)
+	assert.Equal(t, 60999, maxPort)
+
+	for _, value := range []string{"32768", "60999-32768", "a-b", "0-100"} {
+		_, _, err := ParseEphemeralPortRange(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestGeneratePolicy_DropsEphemeralFlows(t *testing.T) {
+	// --- Setup Mocks ---
+	origGetPodTrafficFunc := api.GetPodTrafficFunc
+	origGetPodSpecFunc := api.GetPodSpecFunc
+	origCommonSave := common.SaveToFileFunc
+	defer func() {
+		api.GetPodTrafficFunc = origGetPodTrafficFunc
+		api.GetPodSpecFunc = origGetPodSpecFunc
+		common.SaveToFileFunc = origCommonSave
+	}()
+
+	api.GetPodTrafficFunc = func(podName string) ([]api.PodTraffic, error) {
+		return []api.PodTraffic{
+			{SrcIP: "192.168.1.10", SrcPodPort: "8080", DstIP: "10.0.0.1", DstPort: "51234", Protocol: corev1.ProtocolTCP, TrafficType: "INGRESS"},
+			{SrcIP: "192.168.1.10", SrcPodPort: "45678", DstIP: "10.0.0.2", DstPort: "5432", Protocol: corev1.ProtocolTCP, TrafficType: "INGRESS"},
+		}, nil
+	}
+	api.GetPodSpecFunc = func(ip string) (*api.PodDetail, error) {
+		return mockPodDetail("api-pod", "prod", "192.168.1.10", map[string]string{"app": "api"}), nil
+	}
+
+	var savedReport []byte
+	common.SaveToFileFunc = func(outputDir, resourceType, namespace, name string, content []byte) (string, error) {
+		if resourceType == "networkpolicy-report" {
+			savedReport = content
+		}
+		return outputDir + "/" + resourceType, nil
+	}
+
+	var trafficSeen []api.PodTraffic
+	service := NewPolicyService(&mockConfigProvider{dryRun: true, outputDir: "out"}, StandardPolicy)
+	service.SetEphemeralPortOptions(EphemeralPortOptions{Enabled: true, Min: DefaultEphemeralPortMin, Max: DefaultEphemeralPortMax})
+	service.RegisterGenerator(&recordingPolicyGenerator{mockPolicyGenerator: mockPolicyGenerator{policyType: StandardPolicy}, seen: &trafficSeen})
+	// --- End Mocks ---
+
+	output, err := service.GeneratePolicy("api-pod", StandardPolicy)
+	require.NoError(t, err)
+	require.Len(t, trafficSeen, 1)
+	assert.Equal(t, "8080", trafficSeen[0].SrcPodPort)
+	require.Len(t, output.DroppedFlows, 1)
+
+	require.NoError(t, service.HandlePolicyOutput(output))
+	var report DroppedFlowsReport
+	require.NoError(t, yaml.Unmarshal(savedReport, &report))
+	assert.Equal(t, "api-pod", report.Pod)
+	require.Len(t, report.DroppedFlows, 1)
+	assert.Equal(t, "45678", report.DroppedFlows[0].PodPort)
+}
+
+// recordingPolicyGenerator records the traffic it is asked to generate a policy from
+type recordingPolicyGenerator struct {
+	mockPolicyGenerator
+	seen *[]api.PodTraffic
+}
+
+func (r *recordingPolicyGenerator) Generate(podName string, podTraffic []api.PodTraffic, podDetail *api.PodDetail) (interface{}, error) {
+	*r.seen = podTraffic
+	return r.mockPolicyGenerator.Generate(podName, podTraffic, podDetail)
+}
@@ -11,20 +11,32 @@ import (
 
 // PolicyService handles network policy generation and management
 type PolicyService struct {
-	config      ConfigProvider
-	generators  map[PolicyType]PolicyGenerator
-	defaultType PolicyType
+	config         ConfigProvider
+	generators     map[PolicyType]PolicyGenerator
+	defaultType    PolicyType
+	ephemeralPorts EphemeralPortOptions
 }
 
 // NewPolicyService creates a new PolicyService
 func NewPolicyService(config ConfigProvider, defaultType PolicyType) *PolicyService {
 	return &PolicyService{
-		config:      config,
-		generators:  make(map[PolicyType]PolicyGenerator),
-		defaultType: defaultType,
+		config:         config,
+		generators:     make(map[PolicyType]PolicyGenerator),
+		defaultType:    defaultType,
+		ephemeralPorts: DefaultEphemeralPortOptions(),
 	}
 }
 
+// SetEphemeralPortOptions configures the filtering of flows on ephemeral client ports
+func (s *PolicyService) SetEphemeralPortOptions(options EphemeralPortOptions) {
+	s.ephemeralPorts = options
+}
+
+// EphemeralPortOptions returns the configured filtering of flows on ephemeral client ports
+func (s *PolicyService) EphemeralPortOptions() EphemeralPortOptions {
+	return s.ephemeralPorts
+}
+
 // RegisterGenerator registers a policy generator for a specific policy type
 func (s *PolicyService) RegisterGenerator(generator PolicyGenerator) {
 	s.generators[generator.GetType()] = generator
@@ -68,6 +80,9 @@ func (s *PolicyService) GeneratePolicy(podName string, policyType PolicyType) (*
 		return nil, fmt.Errorf("pod details not found using IP %s for pod %s", lookupIP, podName)
 	}
 
+	// Drop flows on ephemeral client ports, which would otherwise become rules for random high ports
+	podTraffic, droppedFlows := FilterEphemeralFlows(podTraffic, podDetail, s.ephemeralPorts)
+
 	// Select the appropriate generator
 	generator, exists := s.generators[policyType]
 	if !exists {
@@ -94,11 +109,12 @@ func (s *PolicyService) GeneratePolicy(podName string, policyType PolicyType) (*
 	}
 
 	return &PolicyOutput{
-		Policy:    policy,
-		YAML:      policyYAML,
-		PodName:   podDetail.Name,
-		Namespace: podDetail.Namespace,
-		Type:      generator.GetType(),
+		Policy:       policy,
+		YAML:         policyYAML,
+		PodName:      podDetail.Name,
+		Namespace:    podDetail.Namespace,
+		Type:         generator.GetType(),
+		DroppedFlows: droppedFlows,
 	}, nil
 }
 
@@ -134,6 +150,10 @@ func (s *PolicyService) HandlePolicyOutput(output *PolicyOutput) error {
 		log.Warn().Msg("Applying network policies is not yet implemented - only saving to files")
 	}
 
+	if err := s.handleDroppedFlows(output); err != nil {
+		log.Warn().Err(err).Msgf("Error saving the dropped flows report for pod %s", output.PodName)
+	}
+
 	return nil
 }
 
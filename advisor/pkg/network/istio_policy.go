@@ -0,0 +1,189 @@
+package network
+
+import (
+	"fmt"
+	"strconv"
+
+	log "github.com/rs/zerolog/log"
+	"github.com/xentra-ai/advisor/pkg/api"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// IstioPolicyOptions configures the Istio AuthorizationPolicy generator
+type IstioPolicyOptions struct {
+	// TrustDomain of the mesh, used to build the SPIFFE principals of peers
+	TrustDomain string
+}
+
+// DefaultIstioPolicyOptions returns the default Istio AuthorizationPolicy options
+func DefaultIstioPolicyOptions() IstioPolicyOptions {
+	return IstioPolicyOptions{TrustDomain: "cluster.local"}
+}
+
+// IstioPolicyGenerator generates Istio AuthorizationPolicy resources
+//
+// AuthorizationPolicies are enforced by the receiving workload's proxy, so only ingress traffic
+// is turned into rules. Mesh peers are identified by their SPIFFE principal, derived from the
+// peer pod's service account.
+type IstioPolicyGenerator struct {
+	standard *StandardPolicyGenerator
+	options  IstioPolicyOptions
+}
+
+// NewIstioPolicyGenerator creates a new generator for Istio AuthorizationPolicy resources
+func NewIstioPolicyGenerator() *IstioPolicyGenerator {
+	return NewIstioPolicyGeneratorWithOptions(DefaultIstioPolicyOptions())
+}
+
+// NewIstioPolicyGeneratorWithOptions creates a new generator for Istio AuthorizationPolicy resources
+func NewIstioPolicyGeneratorWithOptions(options IstioPolicyOptions) *IstioPolicyGenerator {
+	return &IstioPolicyGenerator{
+		standard: NewStandardPolicyGenerator(),
+		options:  options,
+	}
+}
+
+// GetType returns the policy type
+func (g *IstioPolicyGenerator) GetType() PolicyType {
+	return IstioPolicy
+}
+
+// Generate creates an AuthorizationPolicy allowing the observed ingress traffic of the specified pod
+func (g *IstioPolicyGenerator) Generate(podName string, podTraffic []api.PodTraffic, podDetail *api.PodDetail) (interface{}, error) {
+	log.Info().Msgf("Generating Istio authorization policy for pod %s", podName)
+
+	if podDetail == nil {
+		return nil, fmt.Errorf("pod detail is nil for pod %s", podName)
+	}
+
+	// Ingress rules carry the observed SrcPodPort of our pod
+	ingressRules, egressRules := g.standard.processTrafficRules(podTraffic, podDetail)
+	if len(egressRules) > 0 {
+		log.Debug().Msgf("Ignoring %d egress rules of pod %s, AuthorizationPolicies only apply to ingress", len(egressRules), podName)
+	}
+
+	policy := &IstioAuthorizationPolicy{
+		TypeMeta: CreateTypeMeta("AuthorizationPolicy", istioAPIVersion),
+		ObjectMeta: CreateObjectMeta(
+			GetPolicyName(podDetail.Name, "istio-policy"),
+			podDetail.Namespace,
+			CreateStandardLabels(podDetail.Name, "istio-policy"),
+		),
+		Spec: IstioAuthorizationPolicySpec{
+			Selector: &IstioWorkloadSelector{MatchLabels: podDetail.Pod.Labels},
+			Action:   IstioActionAllow,
+		},
+	}
+
+	for _, rule := range ingressRules {
+		ports := istioPorts(rule.Ports, &podDetail.Pod)
+		if len(ports) == 0 {
+			// An operation without ports would match every port of the workload
+			log.Debug().Msgf("Skipping ingress from peer %s without TCP ports", rule.PeerIP)
+			continue
+		}
+		policy.Spec.Rules = append(policy.Spec.Rules, IstioRule{
+			From: []IstioRuleFrom{{Source: g.istioSource(rule.PeerIP)}},
+			To:   []IstioRuleTo{{Operation: IstioOperation{Ports: ports}}},
+		})
+	}
+
+	if len(policy.Spec.Rules) == 0 {
+		// An ALLOW policy without rules denies all requests to the workload
+		log.Warn().Msgf("No valid ingress rules generated for pod %s. Applying default-deny.", podName)
+		policy.Name = GetPolicyName(podDetail.Name, "istio-policy-deny-all")
+		policy.Labels = CreateStandardLabels(podDetail.Name, "istio-policy-deny-all")
+	}
+
+	return policy, nil
+}
+
+// GenerateBaseline creates a namespace-wide allow-nothing AuthorizationPolicy per namespace, and
+// optionally one allowing requests from the same namespace. DNS is not affected by
+// AuthorizationPolicies and needs no exception.
+func (g *IstioPolicyGenerator) GenerateBaseline(namespaces []string, opts BaselineOptions) ([]interface{}, error) {
+	if opts.Clusterwide {
+		log.Warn().Msg("Istio AuthorizationPolicies are generated per namespace, ignoring the clusterwide baseline option")
+	}
+
+	var policies []interface{}
+	for _, namespace := range namespaces {
+		log.Info().Msgf("Generating Istio baseline policies for namespace %s", namespace)
+
+		// Without a selector the policy applies to every workload of the namespace
+		policies = append(policies, &IstioAuthorizationPolicy{
+			TypeMeta:   CreateTypeMeta("AuthorizationPolicy", istioAPIVersion),
+			ObjectMeta: CreateObjectMeta(BaselineDenyAllName, namespace, CreateStandardLabels("baseline", "istio-policy-deny-all")),
+			Spec:       IstioAuthorizationPolicySpec{},
+		})
+
+		if opts.AllowSameNamespace {
+			policies = append(policies, &IstioAuthorizationPolicy{
+				TypeMeta:   CreateTypeMeta("AuthorizationPolicy", istioAPIVersion),
+				ObjectMeta: CreateObjectMeta(BaselineSameNamespaceName, namespace, CreateStandardLabels("baseline", "istio-policy-same-namespace")),
+				Spec: IstioAuthorizationPolicySpec{
+					Action: IstioActionAllow,
+					Rules: []IstioRule{
+						{From: []IstioRuleFrom{{Source: IstioSource{Namespaces: []string{namespace}}}}},
+					},
+				},
+			})
+		}
+	}
+
+	return policies, nil
+}
+
+// istioSource maps a peer IP to the SPIFFE principal of its service account. Peers that are not
+// pods are matched by namespace (Services) or IP block (external peers).
+func (g *IstioPolicyGenerator) istioSource(peerIP string) IstioSource {
+	peer := ResolvePeer(peerIP)
+
+	switch peer.Kind {
+	case PeerPod:
+		serviceAccount := peer.ServiceAccount
+		if serviceAccount == "" {
+			serviceAccount = "default"
+		}
+		return IstioSource{Principals: []string{g.principal(peer.Namespace, serviceAccount)}}
+	case PeerService:
+		log.Debug().Msgf("Peer %s is a service, matching its namespace %s instead of a principal", peerIP, peer.Namespace)
+		return IstioSource{Namespaces: []string{peer.Namespace}}
+	default:
+		return IstioSource{IPBlocks: []string{hostCIDR(peerIP)}}
+	}
+}
+
+// principal builds the SPIFFE identity of a service account, without the spiffe:// scheme
+func (g *IstioPolicyGenerator) principal(namespace, serviceAccount string) string {
+	return fmt.Sprintf("%s/ns/%s/sa/%s", g.options.TrustDomain, namespace, serviceAccount)
+}
+
+// istioPorts converts NetworkPolicy ports to AuthorizationPolicy operation ports.
+// Operation ports only apply to TCP, which is the only protocol the mesh authorizes, and must be
+// numeric, so named ports are resolved through the pod's container ports.
+func istioPorts(ports []networkingv1.NetworkPolicyPort, pod *corev1.Pod) []string {
+	istioPorts := make([]string, 0, len(ports))
+	for _, port := range ports {
+		if port.Port == nil {
+			continue
+		}
+		if port.Protocol != nil && *port.Protocol != "TCP" {
+			log.Debug().Msgf("Skipping %s port %s, Istio only authorizes TCP traffic", *port.Protocol, port.Port.String())
+			continue
+		}
+		if port.Port.Type == intstr.String {
+			number, ok := containerPortNumber(pod, port.Port.StrVal)
+			if !ok {
+				log.Debug().Msgf("Skipping named port %s not declared by pod %s", port.Port.StrVal, pod.Name)
+				continue
+			}
+			istioPorts = append(istioPorts, strconv.Itoa(int(number)))
+			continue
+		}
+		istioPorts = append(istioPorts, port.Port.String())
+	}
+	return istioPorts
+}
@@ -0,0 +1,126 @@
+package network
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xentra-ai/advisor/pkg/api"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+func TestIstioPolicyGenerator_Generate(t *testing.T) {
+	// --- Setup Mocks ---
+	origGetPodSpecFunc := api.GetPodSpecFunc
+	origGetSvcSpecFunc := api.GetSvcSpecFunc
+	defer func() {
+		api.GetPodSpecFunc = origGetPodSpecFunc
+		api.GetSvcSpecFunc = origGetSvcSpecFunc
+	}()
+
+	api.GetPodSpecFunc = func(ip string) (*api.PodDetail, error) {
+		switch ip {
+		case "10.0.0.1":
+			client := mockPodDetail("frontend-0", "web", ip, map[string]string{"app": "frontend"})
+			client.Pod.Spec.ServiceAccountName = "frontend"
+			return client, nil
+		case "10.0.0.3":
+			// Pods without an explicit service account run as "default"
+			return mockPodDetail("job-0", "batch", ip, map[string]string{"app": "job"}), nil
+		}
+		return nil, nil
+	}
+	api.GetSvcSpecFunc = func(ip string) (*api.SvcDetail, error) { return nil, nil }
+	// --- End Mocks ---
+
+	gen := NewIstioPolicyGenerator()
+	podDetail := mockPodDetail("api-pod", "prod", "192.168.1.10", map[string]string{"app": "api"})
+	podTraffic := []api.PodTraffic{
+		{SrcIP: "192.168.1.10", SrcPodPort: "8080", DstIP: "10.0.0.1", DstPort: "51234", Protocol: corev1.ProtocolTCP, TrafficType: "INGRESS"},
+		{SrcIP: "192.168.1.10", SrcPodPort: "9090", DstIP: "10.0.0.1", DstPort: "51235", Protocol: corev1.ProtocolTCP, TrafficType: "INGRESS"},
+		{SrcIP: "192.168.1.10", SrcPodPort: "8080", DstIP: "10.0.0.3", Protocol: corev1.ProtocolTCP, TrafficType: "INGRESS"},
+		{SrcIP: "192.168.1.10", SrcPodPort: "8080", DstIP: "203.0.113.7", Protocol: corev1.ProtocolTCP, TrafficType: "INGRESS"},
+		{SrcIP: "192.168.1.10", SrcPodPort: "8080", DstIP: "2001:db8::7", Protocol: corev1.ProtocolTCP, TrafficType: "INGRESS"},
+		// UDP cannot be authorized by the mesh
+		{SrcIP: "192.168.1.10", SrcPodPort: "5353", DstIP: "10.0.0.9", Protocol: corev1.ProtocolUDP, TrafficType: "INGRESS"},
+		// Egress is not part of an AuthorizationPolicy
+		{SrcIP: "192.168.1.10", DstIP: "10.0.0.2", DstPort: "5432", Protocol: corev1.ProtocolTCP, TrafficType: "EGRESS"},
+	}
+
+	policyInterface, err := gen.Generate("api-pod", podTraffic, podDetail)
+	require.NoError(t, err)
+	policy, ok := policyInterface.(*IstioAuthorizationPolicy)
+	require.True(t, ok)
+
+	assert.Equal(t, "AuthorizationPolicy", policy.Kind)
+	assert.Equal(t, "security.istio.io/v1", policy.APIVersion)
+	assert.Equal(t, GetPolicyName("api-pod", "istio-policy"), policy.Name)
+	assert.Equal(t, "prod", policy.Namespace)
+	assert.Equal(t, IstioActionAllow, policy.Spec.Action)
+	assert.Equal(t, map[string]string{"app": "api"}, policy.Spec.Selector.MatchLabels)
+
+	require.Len(t, policy.Spec.Rules, 4)
+	assert.Equal(t, []string{"cluster.local/ns/web/sa/frontend"}, policy.Spec.Rules[0].From[0].Source.Principals)
+	// Ports are the observed ports of our pod, not the peer's ephemeral ports
+	assert.Equal(t, []string{"8080", "9090"}, policy.Spec.Rules[0].To[0].Operation.Ports)
+	assert.Equal(t, []string{"cluster.local/ns/batch/sa/default"}, policy.Spec.Rules[1].From[0].Source.Principals)
+	assert.Equal(t, []string{"203.0.113.7/32"}, policy.Spec.Rules[2].From[0].Source.IPBlocks)
+	assert.Equal(t, []string{"2001:db8::7/128"}, policy.Spec.Rules[3].From[0].Source.IPBlocks)
+}
+
+func TestIstioPolicyGenerator_Generate_TrustDomainAndDefaultDeny(t *testing.T) {
+	origGetPodSpecFunc := api.GetPodSpecFunc
+	origGetSvcSpecFunc := api.GetSvcSpecFunc
+	defer func() {
+		api.GetPodSpecFunc = origGetPodSpecFunc
+		api.GetSvcSpecFunc = origGetSvcSpecFunc
+	}()
+	api.GetPodSpecFunc = func(ip string) (*api.PodDetail, error) {
+		client := mockPodDetail("client", "web", ip, map[string]string{"app": "client"})
+		client.Pod.Spec.ServiceAccountName = "client"
+		return client, nil
+	}
+	api.GetSvcSpecFunc = func(ip string) (*api.SvcDetail, error) { return nil, nil }
+
+	gen := NewIstioPolicyGeneratorWithOptions(IstioPolicyOptions{TrustDomain: "prod.example.com"})
+	podDetail := mockPodDetail("api-pod", "prod", "192.168.1.10", map[string]string{"app": "api"})
+
+	policyInterface, err := gen.Generate("api-pod", []api.PodTraffic{
+		{SrcIP: "192.168.1.10", SrcPodPort: "8080", DstIP: "10.0.0.1", Protocol: corev1.ProtocolTCP, TrafficType: "INGRESS"},
+	}, podDetail)
+	require.NoError(t, err)
+	policy := policyInterface.(*IstioAuthorizationPolicy)
+	assert.Equal(t, []string{"prod.example.com/ns/web/sa/client"}, policy.Spec.Rules[0].From[0].Source.Principals)
+
+	// Without ingress rules the ALLOW policy has no rules and denies everything
+	policyInterface, err = gen.Generate("api-pod", nil, podDetail)
+	require.NoError(t, err)
+	policy = policyInterface.(*IstioAuthorizationPolicy)
+	assert.Equal(t, GetPolicyName("api-pod", "istio-policy-deny-all"), policy.Name)
+	assert.Empty(t, policy.Spec.Rules)
+}
+
+func TestIstioPolicyGenerator_GenerateBaseline(t *testing.T) {
+	gen := NewIstioPolicyGenerator()
+	opts := DefaultBaselineOptions()
+	opts.AllowSameNamespace = true
+
+	policies, err := gen.GenerateBaseline([]string{"prod"}, opts)
+	require.NoError(t, err)
+	require.Len(t, policies, 2)
+
+	denyAll := policies[0].(*IstioAuthorizationPolicy)
+	assert.Equal(t, BaselineDenyAllName, denyAll.Name)
+	assert.Nil(t, denyAll.Spec.Selector)
+	policyYAML, err := yaml.Marshal(denyAll)
+	require.NoError(t, err)
+	assert.Contains(t, string(policyYAML), "spec: {}")
+
+	sameNamespace := policies[1].(*IstioAuthorizationPolicy)
+	assert.Equal(t, []string{"prod"}, sameNamespace.Spec.Rules[0].From[0].Source.Namespaces)
+}
+
+func TestIstioPolicyGenerator_GetType(t *testing.T) {
+	assert.Equal(t, IstioPolicy, NewIstioPolicyGenerator().GetType())
+}
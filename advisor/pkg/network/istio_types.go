@@ -0,0 +1,67 @@
+package network
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// The security.istio.io/v1 AuthorizationPolicy resource, limited to the fields the generator emits.
+// It is declared locally to avoid depending on the Istio API modules.
+
+// istioAPIVersion is the API version of the Istio security resources
+const istioAPIVersion = "security.istio.io/v1"
+
+// IstioAuthorizationAction is the action of an AuthorizationPolicy
+type IstioAuthorizationAction string
+
+const (
+	// IstioActionAllow allows requests matching any of the rules
+	IstioActionAllow IstioAuthorizationAction = "ALLOW"
+)
+
+// IstioAuthorizationPolicy is a security.istio.io/v1 AuthorizationPolicy
+type IstioAuthorizationPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              IstioAuthorizationPolicySpec `json:"spec"`
+}
+
+// IstioAuthorizationPolicySpec selects the workloads and lists the rules of an AuthorizationPolicy.
+// An ALLOW policy without rules denies all requests to the selected workloads.
+type IstioAuthorizationPolicySpec struct {
+	Selector *IstioWorkloadSelector   `json:"selector,omitempty"`
+	Action   IstioAuthorizationAction `json:"action,omitempty"`
+	Rules    []IstioRule              `json:"rules,omitempty"`
+}
+
+// IstioWorkloadSelector selects the workloads a policy applies to
+type IstioWorkloadSelector struct {
+	MatchLabels map[string]string `json:"matchLabels,omitempty"`
+}
+
+// IstioRule matches requests from the listed sources to the listed operations
+type IstioRule struct {
+	From []IstioRuleFrom `json:"from,omitempty"`
+	To   []IstioRuleTo   `json:"to,omitempty"`
+}
+
+// IstioRuleFrom wraps the source of a rule
+type IstioRuleFrom struct {
+	Source IstioSource `json:"source"`
+}
+
+// IstioSource matches the peer identity, namespace or IP of a request
+type IstioSource struct {
+	Principals []string `json:"principals,omitempty"`
+	Namespaces []string `json:"namespaces,omitempty"`
+	IPBlocks   []string `json:"ipBlocks,omitempty"`
+}
+
+// IstioRuleTo wraps the operation of a rule
+type IstioRuleTo struct {
+	Operation IstioOperation `json:"operation"`
+}
+
+// IstioOperation matches the destination port of a request
+type IstioOperation struct {
+	Ports []string `json:"ports,omitempty"`
+}
@@ -0,0 +1,66 @@
+package network
+
+import (
+	log "github.com/rs/zerolog/log"
+	"github.com/xentra-ai/advisor/pkg/api"
+)
+
+// PeerKind describes what a traffic peer IP resolved to
+type PeerKind string
+
+const (
+	// PeerService is a Service with a pod selector
+	PeerService PeerKind = "service"
+	// PeerPod is a labelled pod known to the broker
+	PeerPod PeerKind = "pod"
+	// PeerExternal is an IP that could not be resolved to a workload
+	PeerExternal PeerKind = "external"
+)
+
+// Peer is a traffic peer resolved through the broker, including the namespace and identity
+// details needed to express cross-namespace rules
+type Peer struct {
+	IP   string
+	Kind PeerKind
+	// Namespace of the Service or pod, empty for external peers
+	Namespace string
+	// Labels select the peer pods, i.e. the Service selector or the pod labels
+	Labels map[string]string
+	// ServiceAccount of the peer pod, only known for pod peers
+	ServiceAccount string
+	Service        *api.SvcDetail
+	Pod            *api.PodDetail
+}
+
+// ResolvePeer resolves a peer IP to a Service, then to a pod, and falls back to an external peer.
+// Headless Services are skipped as their clients connect to the pod IPs.
+func ResolvePeer(peerIP string) Peer {
+	svcSpec, err := api.GetSvcSpec(peerIP)
+	// Headless Services are resolved by the endpoint the client connected to
+	if err == nil && svcSpec != nil && len(svcSpec.Service.Spec.Selector) > 0 && !isHeadlessService(svcSpec) {
+		log.Debug().Msgf("Resolved peer %s to service %s/%s", peerIP, svcSpec.SvcNamespace, svcSpec.SvcName)
+		return Peer{
+			IP:        peerIP,
+			Kind:      PeerService,
+			Namespace: svcSpec.SvcNamespace,
+			Labels:    svcSpec.Service.Spec.Selector,
+			Service:   svcSpec,
+		}
+	}
+
+	podSpec, err := api.GetPodSpec(peerIP)
+	if err == nil && podSpec != nil && len(podSpec.Pod.Labels) > 0 {
+		log.Debug().Msgf("Resolved peer %s to pod %s/%s", peerIP, podSpec.Namespace, podSpec.Name)
+		return Peer{
+			IP:             peerIP,
+			Kind:           PeerPod,
+			Namespace:      podSpec.Namespace,
+			Labels:         podSpec.Pod.Labels,
+			ServiceAccount: podSpec.Pod.Spec.ServiceAccountName,
+			Pod:            podSpec,
+		}
+	}
+
+	log.Debug().Msgf("Peer %s did not resolve to a service or pod, treating it as external", peerIP)
+	return Peer{IP: peerIP, Kind: PeerExternal}
+}
@@ -0,0 +1,84 @@
+package network
+
+import (
+	"sort"
+
+	log "github.com/rs/zerolog/log"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// PortRangeOptions configures the compaction of adjacent ports into endPort ranges
+type PortRangeOptions struct {
+	// Enabled merges ports into ranges, it requires a cluster that supports endPort
+	Enabled bool
+	// GapTolerance is the number of unobserved ports allowed between two merged ports. Any value
+	// above zero also allows the unobserved ports in between.
+	GapTolerance int
+}
+
+// DefaultPortRangeOptions returns the default port range options, which keep every port separate
+// until endPort support has been detected
+func DefaultPortRangeOptions() PortRangeOptions {
+	return PortRangeOptions{Enabled: false, GapTolerance: 0}
+}
+
+// compactPortRanges merges numeric ports of the same protocol that are at most GapTolerance
+// ports apart into port/endPort ranges. Named ports and existing ranges are kept as they are.
+func compactPortRanges(ports []networkingv1.NetworkPolicyPort, opts PortRangeOptions) []networkingv1.NetworkPolicyPort {
+	if !opts.Enabled || len(ports) < 2 {
+		return ports
+	}
+	gap := int32(max(opts.GapTolerance, 0))
+
+	var protocols []corev1.Protocol
+	numericPorts := make(map[corev1.Protocol][]int32)
+	var otherPorts []networkingv1.NetworkPolicyPort
+	for _, port := range ports {
+		if port.Port == nil || port.Port.Type != intstr.Int || port.EndPort != nil {
+			otherPorts = append(otherPorts, port)
+			continue
+		}
+		protocol := corev1.ProtocolTCP
+		if port.Protocol != nil {
+			protocol = *port.Protocol
+		}
+		if _, seen := numericPorts[protocol]; !seen {
+			protocols = append(protocols, protocol)
+		}
+		numericPorts[protocol] = append(numericPorts[protocol], port.Port.IntVal)
+	}
+
+	var result []networkingv1.NetworkPolicyPort
+	for _, protocol := range protocols {
+		numbers := numericPorts[protocol]
+		sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+
+		start, end := numbers[0], numbers[0]
+		for _, number := range numbers[1:] {
+			if number <= end+gap+1 {
+				end = max(end, number)
+				continue
+			}
+			result = append(result, portRange(protocol, start, end))
+			start, end = number, number
+		}
+		result = append(result, portRange(protocol, start, end))
+	}
+
+	if len(result) < len(ports)-len(otherPorts) {
+		log.Debug().Msgf("Compacted %d ports into %d port ranges", len(ports)-len(otherPorts), len(result))
+	}
+	return append(result, otherPorts...)
+}
+
+// portRange creates a NetworkPolicyPort covering start to end, without endPort for single ports
+func portRange(protocol corev1.Protocol, start, end int32) networkingv1.NetworkPolicyPort {
+	port := intstr.FromInt32(start)
+	policyPort := networkingv1.NetworkPolicyPort{Port: &port, Protocol: &protocol}
+	if end > start {
+		policyPort.EndPort = &end
+	}
+	return policyPort
+}
@@ -0,0 +1,70 @@
+package network
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// Helper to create NetworkPolicyPorts from port numbers
+func policyPorts(protocol string, numbers ...int) []networkingv1.NetworkPolicyPort {
+	ports := make([]networkingv1.NetworkPolicyPort, 0, len(numbers))
+	for _, number := range numbers {
+		port := intstr.FromInt(number)
+		ports = append(ports, networkingv1.NetworkPolicyPort{Port: &port, Protocol: protocolPtr(protocol)})
+	}
+	return ports
+}
+
+func TestCompactPortRanges(t *testing.T) {
+	ports := append(policyPorts("TCP", 9302, 9300, 9301, 21, 30000, 30001, 30003), policyPorts("UDP", 9303)...)
+	named := intstr.FromString("http")
+	ports = append(ports, networkingv1.NetworkPolicyPort{Port: &named, Protocol: protocolPtr("TCP")})
+
+	// Disabled keeps the ports untouched
+	assert.Equal(t, ports, compactPortRanges(ports, DefaultPortRangeOptions()))
+
+	compacted := compactPortRanges(ports, PortRangeOptions{Enabled: true})
+	require.Len(t, compacted, 6)
+	assert.Equal(t, int32(21), compacted[0].Port.IntVal)
+	assert.Nil(t, compacted[0].EndPort)
+	assert.Equal(t, int32(9300), compacted[1].Port.IntVal)
+	assert.Equal(t, int32(9302), *compacted[1].EndPort)
+	assert.Equal(t, int32(30000), compacted[2].Port.IntVal)
+	assert.Equal(t, int32(30001), *compacted[2].EndPort)
+	assert.Equal(t, int32(30003), compacted[3].Port.IntVal)
+	// Protocols are never merged together
+	assert.Equal(t, corev1.ProtocolUDP, *compacted[4].Protocol)
+	assert.Nil(t, compacted[4].EndPort)
+	assert.Equal(t, "http", compacted[5].Port.StrVal)
+
+	// A gap tolerance of one bridges 30001 and 30003
+	compacted = compactPortRanges(ports, PortRangeOptions{Enabled: true, GapTolerance: 1})
+	require.Len(t, compacted, 5)
+	assert.Equal(t, int32(30000), compacted[2].Port.IntVal)
+	assert.Equal(t, int32(30003), *compacted[2].EndPort)
+}
+
+func TestDeduplicatePorts_PortRanges(t *testing.T) {
+	ports := policyPorts("TCP", 50000, 50001, 50001, 50002)
+
+	deduplicated := deduplicatePorts(ports, PortRangeOptions{Enabled: true})
+	require.Len(t, deduplicated, 1)
+	assert.Equal(t, int32(50000), deduplicated[0].Port.IntVal)
+	assert.Equal(t, int32(50002), *deduplicated[0].EndPort)
+}
+
+func TestCiliumConvertPortsToCiliumPortRules_PortRanges(t *testing.T) {
+	gen := NewCiliumPolicyGeneratorWithOptions(CiliumPolicyOptions{PortRanges: PortRangeOptions{Enabled: true}})
+
+	portRules := gen.convertPortsToCiliumPortRules(policyPorts("UDP", 10000, 10001, 10002, 10005))
+	require.Len(t, portRules, 2)
+	assert.Equal(t, "10000", portRules[0].Ports[0].Port)
+	assert.Equal(t, int32(10002), portRules[0].Ports[0].EndPort)
+	assert.Equal(t, "10005", portRules[1].Ports[0].Port)
+	assert.Zero(t, portRules[1].Ports[0].EndPort)
+}
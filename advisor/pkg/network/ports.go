@@ -0,0 +1,90 @@
+package network
+
+import (
+	log "github.com/rs/zerolog/log"
+	"github.com/xentra-ai/advisor/pkg/api"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// ingressPort returns the container port name the pod declares for an observed ingress port, so
+// the policy keeps matching when the port number changes. It falls back to the numeric port.
+func ingressPort(podDetail *api.PodDetail, port int, protocolStr string) intstr.IntOrString {
+	// Host network pods are matched by host policies, which do not resolve named ports
+	if podDetail.Pod.Spec.HostNetwork {
+		return intstr.FromInt(port)
+	}
+
+	if name, ok := containerPortName(&podDetail.Pod, port, protocolStr); ok {
+		log.Debug().Msgf("Using named port %s for port %d of pod %s", name, port, podDetail.Name)
+		return intstr.FromString(name)
+	}
+	return intstr.FromInt(port)
+}
+
+// egressPort translates an observed Service port to the targetPort of the Service's pods, as
+// NetworkPolicies match the pod port after the Service has been resolved. Ports of other peers
+// are returned unchanged.
+func egressPort(svcDetail *api.SvcDetail, port int, protocolStr string) intstr.IntOrString {
+	if svcDetail == nil {
+		return intstr.FromInt(port)
+	}
+
+	for _, servicePort := range svcDetail.Service.Spec.Ports {
+		if int(servicePort.Port) != port || !protocolMatches(servicePort.Protocol, protocolStr) {
+			continue
+		}
+		targetPort := servicePort.TargetPort
+		// An unset targetPort defaults to the Service port
+		if targetPort.Type == intstr.Int && targetPort.IntVal == 0 {
+			return intstr.FromInt(port)
+		}
+		log.Debug().Msgf("Translating port %d of service %s/%s to target port %s",
+			port, svcDetail.SvcNamespace, svcDetail.SvcName, targetPort.String())
+		return targetPort
+	}
+
+	return intstr.FromInt(port)
+}
+
+// lookupSelectorService returns the Service with a pod selector behind a peer IP, if any
+func lookupSelectorService(peerIP string) *api.SvcDetail {
+	svcDetail, err := api.GetSvcSpec(peerIP)
+	if err != nil || svcDetail == nil || len(svcDetail.Service.Spec.Selector) == 0 {
+		return nil
+	}
+	return svcDetail
+}
+
+// containerPortName returns the name of the container port matching a port number and protocol
+func containerPortName(pod *corev1.Pod, port int, protocolStr string) (string, bool) {
+	for _, container := range pod.Spec.Containers {
+		for _, containerPort := range container.Ports {
+			if containerPort.Name != "" && int(containerPort.ContainerPort) == port &&
+				protocolMatches(containerPort.Protocol, protocolStr) {
+				return containerPort.Name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// containerPortNumber resolves a named container port of a pod to its number
+func containerPortNumber(pod *corev1.Pod, name string) (int32, bool) {
+	for _, container := range pod.Spec.Containers {
+		for _, containerPort := range container.Ports {
+			if containerPort.Name == name {
+				return containerPort.ContainerPort, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// protocolMatches compares a Kubernetes protocol, which defaults to TCP when empty, to an observed protocol
+func protocolMatches(protocol corev1.Protocol, protocolStr string) bool {
+	if protocol == "" {
+		protocol = corev1.ProtocolTCP
+	}
+	return string(protocol) == protocolStr
+}
@@ -0,0 +1,114 @@
+package network
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xentra-ai/advisor/pkg/api"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestIngressPort(t *testing.T) {
+	podDetail := mockPodDetail("api-pod", "prod", "192.168.1.10", map[string]string{"app": "api"})
+	podDetail.Pod.Spec.Containers = []corev1.Container{{
+		Name: "api",
+		Ports: []corev1.ContainerPort{
+			{Name: "http", ContainerPort: 8080},
+			{Name: "dns", ContainerPort: 5353, Protocol: corev1.ProtocolUDP},
+			{ContainerPort: 9090},
+		},
+	}}
+
+	assert.Equal(t, intstr.FromString("http"), ingressPort(podDetail, 8080, "TCP"))
+	assert.Equal(t, intstr.FromString("dns"), ingressPort(podDetail, 5353, "UDP"))
+	// The protocol must match the declared port
+	assert.Equal(t, intstr.FromInt(5353), ingressPort(podDetail, 5353, "TCP"))
+	// Unnamed and undeclared ports stay numeric
+	assert.Equal(t, intstr.FromInt(9090), ingressPort(podDetail, 9090, "TCP"))
+	assert.Equal(t, intstr.FromInt(7000), ingressPort(podDetail, 7000, "TCP"))
+
+	podDetail.Pod.Spec.HostNetwork = true
+	assert.Equal(t, intstr.FromInt(8080), ingressPort(podDetail, 8080, "TCP"))
+}
+
+func TestEgressPort(t *testing.T) {
+	svc := mockSvcDetail("db-svc", "data", "10.96.0.20", map[string]string{"app": "db"})
+	svc.Service.Spec.Ports = []corev1.ServicePort{
+		{Port: 5432, TargetPort: intstr.FromInt(15432)},
+		{Port: 80, TargetPort: intstr.FromString("http")},
+		{Port: 53, Protocol: corev1.ProtocolUDP},
+	}
+
+	assert.Equal(t, intstr.FromInt(15432), egressPort(svc, 5432, "TCP"))
+	assert.Equal(t, intstr.FromString("http"), egressPort(svc, 80, "TCP"))
+	// An unset targetPort defaults to the Service port
+	assert.Equal(t, intstr.FromInt(53), egressPort(svc, 53, "UDP"))
+	assert.Equal(t, intstr.FromInt(443), egressPort(svc, 443, "TCP"))
+	assert.Equal(t, intstr.FromInt(5432), egressPort(nil, 5432, "TCP"))
+}
+
+func TestStandardPolicyGenerator_Generate_NamedAndTargetPorts(t *testing.T) {
+	// --- Setup Mocks ---
+	origGetPodSpecFunc := api.GetPodSpecFunc
+	origGetSvcSpecFunc := api.GetSvcSpecFunc
+	defer func() {
+		api.GetPodSpecFunc = origGetPodSpecFunc
+		api.GetSvcSpecFunc = origGetSvcSpecFunc
+	}()
+
+	api.GetPodSpecFunc = func(ip string) (*api.PodDetail, error) {
+		if ip == "10.0.0.1" {
+			return mockPodDetail("frontend-0", "prod", ip, map[string]string{"app": "frontend"}), nil
+		}
+		return nil, nil
+	}
+	api.GetSvcSpecFunc = func(ip string) (*api.SvcDetail, error) {
+		if ip == "10.96.0.20" {
+			svc := mockSvcDetail("db-svc", "data", ip, map[string]string{"app": "db"})
+			svc.Service.Spec.Ports = []corev1.ServicePort{{Port: 5432, TargetPort: intstr.FromInt(15432)}}
+			return svc, nil
+		}
+		return nil, nil
+	}
+	// --- End Mocks ---
+
+	gen := NewStandardPolicyGenerator()
+	podDetail := mockPodDetail("api-pod", "prod", "192.168.1.10", map[string]string{"app": "api"})
+	podDetail.Pod.Spec.Containers = []corev1.Container{{
+		Name:  "api",
+		Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: 8080, Protocol: corev1.ProtocolTCP}},
+	}}
+	podTraffic := []api.PodTraffic{
+		{SrcIP: "192.168.1.10", SrcPodPort: "8080", DstIP: "10.0.0.1", DstPort: "51234", Protocol: corev1.ProtocolTCP, TrafficType: "INGRESS"},
+		{SrcIP: "192.168.1.10", DstIP: "10.96.0.20", DstPort: "5432", Protocol: corev1.ProtocolTCP, TrafficType: "EGRESS"},
+	}
+
+	policyInterface, err := gen.Generate("api-pod", podTraffic, podDetail)
+	require.NoError(t, err)
+	policy, ok := policyInterface.(*networkingv1.NetworkPolicy)
+	require.True(t, ok)
+
+	require.Len(t, policy.Spec.Ingress, 1)
+	assert.Equal(t, intstr.FromString("http"), *policy.Spec.Ingress[0].Ports[0].Port)
+	require.Len(t, policy.Spec.Egress, 1)
+	assert.Equal(t, intstr.FromInt(15432), *policy.Spec.Egress[0].Ports[0].Port)
+}
+
+func TestIstioPorts_ResolvesNamedPorts(t *testing.T) {
+	pod := &corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{{
+		Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: 8080}},
+	}}}}
+	named := intstr.FromString("http")
+	unknown := intstr.FromString("grpc")
+	numeric := intstr.FromInt(9090)
+
+	ports := istioPorts([]networkingv1.NetworkPolicyPort{
+		{Port: &named, Protocol: protocolPtr("TCP")},
+		{Port: &unknown, Protocol: protocolPtr("TCP")},
+		{Port: &numeric, Protocol: protocolPtr("TCP")},
+	}, pod)
+	assert.Equal(t, []string{"8080", "9090"}, ports)
+}
@@ -0,0 +1,143 @@
+package network
+
+import (
+	"fmt"
+	"net"
+
+	log "github.com/rs/zerolog/log"
+	"github.com/xentra-ai/advisor/pkg/api"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// ServiceEndpoints are the ready endpoint addresses and ports of a Service
+type ServiceEndpoints struct {
+	Addresses []string
+	Ports     []EndpointPort
+}
+
+// EndpointPort is a named port of a Service's endpoints, the name matches the Service port name
+type EndpointPort struct {
+	Name     string
+	Port     int32
+	Protocol corev1.Protocol
+}
+
+// EndpointResolver looks up the endpoints behind a Service
+type EndpointResolver interface {
+	// GetServiceEndpoints returns the endpoints of the Service, or nil if it has none
+	GetServiceEndpoints(namespace, name string) (*ServiceEndpoints, error)
+}
+
+// ExternalNameService is a Service aliasing an external DNS name. ExternalName Services have no
+// ClusterIP, so peers are matched with them by the addresses their name resolves to.
+type ExternalNameService struct {
+	Namespace    string
+	Name         string
+	ExternalName string
+}
+
+// selectorlessServiceEndpoints returns the Service behind a peer IP and its endpoints, when the
+// Service has no selector and its pods cannot be matched by labels, e.g. default/kubernetes
+func selectorlessServiceEndpoints(resolver EndpointResolver, peerIP string) (*api.SvcDetail, *ServiceEndpoints) {
+	if resolver == nil {
+		return nil, nil
+	}
+
+	svcDetail, err := api.GetSvcSpec(peerIP)
+	if err != nil || svcDetail == nil || len(svcDetail.Service.Spec.Selector) > 0 {
+		return nil, nil
+	}
+	// Clients of headless Services already connect to an endpoint
+	if isHeadlessService(svcDetail) {
+		return nil, nil
+	}
+
+	endpoints, err := resolver.GetServiceEndpoints(svcDetail.SvcNamespace, svcDetail.SvcName)
+	if err != nil {
+		log.Warn().Err(err).Msgf("Unable to look up the endpoints of service %s/%s, keeping its ClusterIP %s",
+			svcDetail.SvcNamespace, svcDetail.SvcName, peerIP)
+		return nil, nil
+	}
+	if endpoints == nil || len(endpoints.Addresses) == 0 {
+		log.Debug().Msgf("Service %s/%s has no ready endpoints, keeping its ClusterIP %s", svcDetail.SvcNamespace, svcDetail.SvcName, peerIP)
+		return nil, nil
+	}
+
+	log.Debug().Msgf("Service %s/%s behind %s has no selector, using its endpoints %v",
+		svcDetail.SvcNamespace, svcDetail.SvcName, peerIP, endpoints.Addresses)
+	return svcDetail, endpoints
+}
+
+// isHeadlessService reports whether the Service has no ClusterIP, its clients connect to the
+// endpoint addresses directly
+func isHeadlessService(svcDetail *api.SvcDetail) bool {
+	return svcDetail.Service.Spec.ClusterIP == corev1.ClusterIPNone || svcDetail.SvcIp == corev1.ClusterIPNone
+}
+
+// endpointTargetPorts translates observed Service ports to the ports of the Service's endpoints.
+// Endpoint ports carry the name of the Service port they implement.
+func endpointTargetPorts(svcDetail *api.SvcDetail, endpoints *ServiceEndpoints, ports []networkingv1.NetworkPolicyPort) []networkingv1.NetworkPolicyPort {
+	targetPorts := make([]networkingv1.NetworkPolicyPort, 0, len(ports))
+	for _, port := range ports {
+		if port.Port == nil || port.Port.Type != intstr.Int {
+			targetPorts = append(targetPorts, port)
+			continue
+		}
+		protocol := corev1.ProtocolTCP
+		if port.Protocol != nil {
+			protocol = *port.Protocol
+		}
+
+		targetPort := *port.Port
+		if number, ok := endpointPortNumber(svcDetail, endpoints, port.Port.IntVal, protocol); ok {
+			targetPort = intstr.FromInt32(number)
+		}
+		targetPorts = append(targetPorts, networkingv1.NetworkPolicyPort{Port: &targetPort, Protocol: port.Protocol})
+	}
+	return targetPorts
+}
+
+// endpointPortNumber finds the endpoint port implementing the Service port with the given number
+func endpointPortNumber(svcDetail *api.SvcDetail, endpoints *ServiceEndpoints, port int32, protocol corev1.Protocol) (int32, bool) {
+	for _, servicePort := range svcDetail.Service.Spec.Ports {
+		if servicePort.Port != port || !protocolMatches(servicePort.Protocol, string(protocol)) {
+			continue
+		}
+		for _, endpointPort := range endpoints.Ports {
+			if endpointPort.Name == servicePort.Name && protocolMatches(endpointPort.Protocol, string(protocol)) {
+				return endpointPort.Port, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// hostCIDR returns the single-address CIDR of an IPv4 or IPv6 address
+func hostCIDR(ip string) string {
+	if parsed := net.ParseIP(ip); parsed != nil && parsed.To4() == nil {
+		return fmt.Sprintf("%s/128", ip)
+	}
+	return fmt.Sprintf("%s/32", ip)
+}
+
+// serviceEndpointsEgressRule rewrites egress to the ClusterIP of a Service without a selector into
+// ipBlocks of its endpoints on their target ports. NetworkPolicies are evaluated after the
+// ClusterIP has been translated, so a rule for the ClusterIP itself never matches.
+func (g *StandardPolicyGenerator) serviceEndpointsEgressRule(peerIP string, ports []networkingv1.NetworkPolicyPort) *networkingv1.NetworkPolicyEgressRule {
+	svcDetail, endpoints := selectorlessServiceEndpoints(g.options.Endpoints, peerIP)
+	if endpoints == nil {
+		return nil
+	}
+
+	rule := &networkingv1.NetworkPolicyEgressRule{
+		Ports: deduplicatePorts(endpointTargetPorts(svcDetail, endpoints, ports), g.options.PortRanges),
+	}
+	for _, address := range endpoints.Addresses {
+		rule.To = append(rule.To, networkingv1.NetworkPolicyPeer{
+			IPBlock: &networkingv1.IPBlock{CIDR: hostCIDR(address)},
+		})
+	}
+	return rule
+}
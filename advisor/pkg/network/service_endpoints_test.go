@@ -0,0 +1,269 @@
+package network
+
+import (
+	"fmt"
+	"testing"
+
+	ciliumv2 "github.com/cilium/cilium/pkg/k8s/apis/cilium.io/v2"
+	ciliumapi "github.com/cilium/cilium/pkg/policy/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xentra-ai/advisor/pkg/api"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// mockEndpointResolver returns endpoints keyed by namespace/name
+type mockEndpointResolver struct {
+	endpoints map[string]*ServiceEndpoints
+	err       error
+}
+
+func (m *mockEndpointResolver) GetServiceEndpoints(namespace, name string) (*ServiceEndpoints, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.endpoints[namespace+"/"+name], nil
+}
+
+func TestStandardPolicyGenerator_Generate_SelectorlessServiceEndpoints(t *testing.T) {
+	// --- Setup Mocks ---
+	origGetPodSpecFunc := api.GetPodSpecFunc
+	origGetSvcSpecFunc := api.GetSvcSpecFunc
+	defer func() {
+		api.GetPodSpecFunc = origGetPodSpecFunc
+		api.GetSvcSpecFunc = origGetSvcSpecFunc
+	}()
+
+	api.GetPodSpecFunc = func(ip string) (*api.PodDetail, error) { return nil, nil }
+	api.GetSvcSpecFunc = func(ip string) (*api.SvcDetail, error) {
+		switch ip {
+		case "10.96.0.1":
+			return &api.SvcDetail{SvcIp: ip, SvcName: "kubernetes", SvcNamespace: "default", Service: corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "kubernetes", Namespace: "default"},
+				Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{
+					{Name: "https", Port: 443, Protocol: corev1.ProtocolTCP, TargetPort: intstr.FromInt32(6443)},
+				}},
+			}}, nil
+		case "10.96.10.20":
+			return &api.SvcDetail{SvcIp: ip, SvcName: "legacy-db", SvcNamespace: "data", Service: corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "legacy-db", Namespace: "data"},
+				Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{
+					{Port: 5432, Protocol: corev1.ProtocolTCP},
+				}},
+			}}, nil
+		}
+		return nil, nil
+	}
+	// --- End Mocks ---
+
+	resolver := &mockEndpointResolver{endpoints: map[string]*ServiceEndpoints{
+		"default/kubernetes": {
+			Addresses: []string{"172.18.0.2", "172.18.0.3"},
+			Ports:     []EndpointPort{{Name: "https", Port: 6443, Protocol: corev1.ProtocolTCP}},
+		},
+		"data/legacy-db": {
+			Addresses: []string{"192.0.2.15"},
+			Ports:     []EndpointPort{{Port: 15432, Protocol: corev1.ProtocolTCP}},
+		},
+	}}
+	podDetail := mockPodDetail("operator", "ops", "192.168.1.10", map[string]string{"app": "operator"})
+	podTraffic := []api.PodTraffic{
+		{SrcIP: "192.168.1.10", DstIP: "10.96.0.1", DstPort: "443", Protocol: corev1.ProtocolTCP, TrafficType: "EGRESS"},
+		{SrcIP: "192.168.1.10", DstIP: "10.96.10.20", DstPort: "5432", Protocol: corev1.ProtocolTCP, TrafficType: "EGRESS"},
+	}
+
+	gen := NewStandardPolicyGeneratorWithOptions(StandardPolicyOptions{
+		PortRanges: DefaultPortRangeOptions(),
+		Endpoints:  resolver,
+	})
+	policyInterface, err := gen.Generate("operator", podTraffic, podDetail)
+	require.NoError(t, err)
+	policy := policyInterface.(*networkingv1.NetworkPolicy)
+
+	rules := map[string]networkingv1.NetworkPolicyEgressRule{}
+	for _, rule := range policy.Spec.Egress {
+		require.NotEmpty(t, rule.To)
+		rules[rule.To[0].IPBlock.CIDR] = rule
+	}
+	require.Len(t, rules, 2)
+
+	apiServer := rules["172.18.0.2/32"]
+	assert.Equal(t, []networkingv1.NetworkPolicyPeer{
+		{IPBlock: &networkingv1.IPBlock{CIDR: "172.18.0.2/32"}},
+		{IPBlock: &networkingv1.IPBlock{CIDR: "172.18.0.3/32"}},
+	}, apiServer.To)
+	require.Len(t, apiServer.Ports, 1)
+	assert.Equal(t, intstr.FromInt32(6443), *apiServer.Ports[0].Port)
+
+	database := rules["192.0.2.15/32"]
+	require.Len(t, database.Ports, 1)
+	assert.Equal(t, intstr.FromInt32(15432), *database.Ports[0].Port)
+}
+
+func TestStandardPolicyGenerator_Generate_SelectorlessServiceWithoutEndpoints(t *testing.T) {
+	// --- Setup Mocks ---
+	origGetPodSpecFunc := api.GetPodSpecFunc
+	origGetSvcSpecFunc := api.GetSvcSpecFunc
+	defer func() {
+		api.GetPodSpecFunc = origGetPodSpecFunc
+		api.GetSvcSpecFunc = origGetSvcSpecFunc
+	}()
+
+	api.GetPodSpecFunc = func(ip string) (*api.PodDetail, error) { return nil, nil }
+	api.GetSvcSpecFunc = func(ip string) (*api.SvcDetail, error) {
+		return &api.SvcDetail{SvcIp: ip, SvcName: "kubernetes", SvcNamespace: "default"}, nil
+	}
+	// --- End Mocks ---
+
+	podDetail := mockPodDetail("operator", "ops", "192.168.1.10", map[string]string{"app": "operator"})
+	podTraffic := []api.PodTraffic{
+		{SrcIP: "192.168.1.10", DstIP: "10.96.0.1", DstPort: "443", Protocol: corev1.ProtocolTCP, TrafficType: "EGRESS"},
+	}
+
+	for name, resolver := range map[string]EndpointResolver{
+		"no resolver":  nil,
+		"lookup error": &mockEndpointResolver{err: fmt.Errorf("forbidden")},
+		"no endpoints": &mockEndpointResolver{},
+	} {
+		t.Run(name, func(t *testing.T) {
+			gen := NewStandardPolicyGeneratorWithOptions(StandardPolicyOptions{Endpoints: resolver})
+			policyInterface, err := gen.Generate("operator", podTraffic, podDetail)
+			require.NoError(t, err)
+			policy := policyInterface.(*networkingv1.NetworkPolicy)
+
+			// The ClusterIP is kept when the endpoints are unknown
+			require.Len(t, policy.Spec.Egress, 1)
+			assert.Equal(t, "10.96.0.1/32", policy.Spec.Egress[0].To[0].IPBlock.CIDR)
+			assert.Equal(t, intstr.FromInt32(443), *policy.Spec.Egress[0].Ports[0].Port)
+		})
+	}
+}
+
+// mockServiceKinds mocks a headless Service resolving to a pod and a Service backed by a manual
+// EndpointSlice. ExternalName Services have no ClusterIP, the controller does not record them.
+func mockServiceKinds() func() {
+	origGetPodSpecFunc := api.GetPodSpecFunc
+	origGetSvcSpecFunc := api.GetSvcSpecFunc
+
+	api.GetPodSpecFunc = func(ip string) (*api.PodDetail, error) {
+		if ip == "192.168.2.20" {
+			return mockPodDetail("kafka-0", "streaming", ip, map[string]string{"app": "kafka"}), nil
+		}
+		return nil, nil
+	}
+	api.GetSvcSpecFunc = func(ip string) (*api.SvcDetail, error) {
+		switch ip {
+		case "192.168.2.20":
+			return &api.SvcDetail{SvcIp: ip, SvcName: "kafka-headless", SvcNamespace: "streaming", Service: corev1.Service{
+				Spec: corev1.ServiceSpec{ClusterIP: corev1.ClusterIPNone, Selector: map[string]string{"app.kubernetes.io/name": "kafka"}},
+			}}, nil
+		case "10.96.10.20":
+			return &api.SvcDetail{SvcIp: ip, SvcName: "legacy-db", SvcNamespace: "data", Service: corev1.Service{
+				Spec: corev1.ServiceSpec{ClusterIP: ip, Ports: []corev1.ServicePort{{Name: "postgres", Port: 5432, Protocol: corev1.ProtocolTCP}}},
+			}}, nil
+		}
+		return nil, nil
+	}
+
+	return func() {
+		api.GetPodSpecFunc = origGetPodSpecFunc
+		api.GetSvcSpecFunc = origGetSvcSpecFunc
+	}
+}
+
+var serviceKindsTraffic = []api.PodTraffic{
+	{SrcIP: "192.168.1.10", DstIP: "192.168.2.20", DstPort: "9092", Protocol: corev1.ProtocolTCP, TrafficType: "EGRESS"},
+	{SrcIP: "192.168.1.10", DstIP: "203.0.113.50", DstPort: "443", Protocol: corev1.ProtocolTCP, TrafficType: "EGRESS"},
+	{SrcIP: "192.168.1.10", DstIP: "10.96.10.20", DstPort: "5432", Protocol: corev1.ProtocolTCP, TrafficType: "EGRESS"},
+}
+
+var serviceKindsEndpoints = &mockEndpointResolver{endpoints: map[string]*ServiceEndpoints{
+	"data/legacy-db": {
+		Addresses: []string{"192.0.2.15", "192.0.2.16"},
+		Ports:     []EndpointPort{{Name: "postgres", Port: 5432, Protocol: corev1.ProtocolTCP}},
+	},
+}}
+
+func TestResolvePeer_HeadlessService(t *testing.T) {
+	defer mockServiceKinds()()
+
+	peer := ResolvePeer("192.168.2.20")
+	assert.Equal(t, PeerPod, peer.Kind)
+	assert.Equal(t, map[string]string{"app": "kafka"}, peer.Labels)
+
+	assert.Equal(t, PeerExternal, ResolvePeer("203.0.113.50").Kind)
+}
+
+func TestStandardPolicyGenerator_Generate_ServiceKinds(t *testing.T) {
+	defer mockServiceKinds()()
+
+	gen := NewStandardPolicyGeneratorWithOptions(StandardPolicyOptions{Endpoints: serviceKindsEndpoints})
+	podDetail := mockPodDetail("shop-api", "shop", "192.168.1.10", map[string]string{"app": "shop-api"})
+	policyInterface, err := gen.Generate("shop-api", serviceKindsTraffic, podDetail)
+	require.NoError(t, err)
+	policy := policyInterface.(*networkingv1.NetworkPolicy)
+
+	peers := map[int32][]networkingv1.NetworkPolicyPeer{}
+	for _, rule := range policy.Spec.Egress {
+		peers[rule.Ports[0].Port.IntVal] = rule.To
+	}
+	require.Len(t, peers, 3)
+
+	// Headless: the pod behind the endpoint, not the Service selector
+	require.Len(t, peers[9092], 1)
+	require.NotNil(t, peers[9092][0].PodSelector)
+	assert.Equal(t, map[string]string{"app": "kafka"}, peers[9092][0].PodSelector.MatchLabels)
+	// External peer: NetworkPolicies cannot match names, the resolved IP is kept
+	assert.Equal(t, []networkingv1.NetworkPolicyPeer{{IPBlock: &networkingv1.IPBlock{CIDR: "203.0.113.50/32"}}}, peers[443])
+	// Manual EndpointSlice: every endpoint becomes an ipBlock
+	assert.Equal(t, []networkingv1.NetworkPolicyPeer{
+		{IPBlock: &networkingv1.IPBlock{CIDR: "192.0.2.15/32"}},
+		{IPBlock: &networkingv1.IPBlock{CIDR: "192.0.2.16/32"}},
+	}, peers[5432])
+}
+
+func TestCiliumPolicyGenerator_Generate_ServiceKinds(t *testing.T) {
+	defer mockServiceKinds()()
+
+	podDetail := mockPodDetail("shop-api", "shop", "192.168.1.10", map[string]string{"app": "shop-api"})
+	options := CiliumPolicyOptions{
+		Endpoints: serviceKindsEndpoints,
+		ExternalNames: map[string]ExternalNameService{
+			"203.0.113.50": {Namespace: "shop", Name: "payments-api", ExternalName: "api.payments.example.com"},
+		},
+	}
+
+	assertEgress := func(t *testing.T, egress []ciliumapi.EgressRule) {
+		rules := map[string]ciliumapi.EgressRule{}
+		for _, rule := range egress {
+			rules[rule.ToPorts[0].Ports[0].Port] = rule
+		}
+		require.Len(t, rules, 3)
+
+		require.Len(t, rules["9092"].ToEndpoints, 1)
+		assert.Empty(t, rules["9092"].ToCIDR)
+		assert.Equal(t, ciliumapi.FQDNSelectorSlice{{MatchName: "api.payments.example.com"}}, rules["443"].ToFQDNs)
+		assert.Empty(t, rules["443"].ToCIDR)
+		assert.Equal(t, ciliumapi.CIDRSlice{"192.0.2.15/32", "192.0.2.16/32"}, rules["5432"].ToCIDR)
+	}
+
+	t.Run("namespaced", func(t *testing.T) {
+		policyInterface, err := NewCiliumPolicyGeneratorWithOptions(options).Generate("shop-api", serviceKindsTraffic, podDetail)
+		require.NoError(t, err)
+		assertEgress(t, policyInterface.(*ciliumv2.CiliumNetworkPolicy).Spec.Egress)
+	})
+
+	t.Run("clusterwide", func(t *testing.T) {
+		policyInterface, err := NewCiliumClusterwidePolicyGeneratorWithOptions(options).Generate("shop-api", serviceKindsTraffic, podDetail)
+		require.NoError(t, err)
+		assertEgress(t, policyInterface.(*ciliumv2.CiliumClusterwideNetworkPolicy).Spec.Egress)
+	})
+}
+
+func TestHostCIDR(t *testing.T) {
+	assert.Equal(t, "10.0.0.1/32", hostCIDR("10.0.0.1"))
+	assert.Equal(t, "fd00::1/128", hostCIDR("fd00::1"))
+}
@@ -11,12 +11,27 @@ import (
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
+// StandardPolicyOptions configures the standard NetworkPolicy generator
+type StandardPolicyOptions struct {
+	// PortRanges configures the merging of adjacent ports into endPort ranges
+	PortRanges PortRangeOptions
+	// Endpoints resolves the endpoints of Services without a selector, nil keeps their ClusterIP
+	Endpoints EndpointResolver
+}
+
 // StandardPolicyGenerator generates standard Kubernetes NetworkPolicy resources
-type StandardPolicyGenerator struct{}
+type StandardPolicyGenerator struct {
+	options StandardPolicyOptions
+}
 
 // NewStandardPolicyGenerator creates a new generator for standard NetworkPolicy resources
 func NewStandardPolicyGenerator() *StandardPolicyGenerator {
-	return &StandardPolicyGenerator{}
+	return NewStandardPolicyGeneratorWithOptions(StandardPolicyOptions{PortRanges: DefaultPortRangeOptions()})
+}
+
+// NewStandardPolicyGeneratorWithOptions creates a new generator for standard NetworkPolicy resources
+func NewStandardPolicyGeneratorWithOptions(options StandardPolicyOptions) *StandardPolicyGenerator {
+	return &StandardPolicyGenerator{options: options}
 }
 
 // GetType returns the policy type
@@ -152,10 +167,11 @@ func (g *StandardPolicyGenerator) processTrafficRules(podTraffic []api.PodTraffi
 				log.Warn().Err(err).Msgf("Skipping ingress traffic record due to invalid pod port: %s", traffic.SrcPodPort)
 				continue
 			}
-			port = intstr.FromInt(portInt)
 			protocolStr = string(traffic.Protocol)
+			// Prefer the container port name declared by our pod
+			port = ingressPort(podDetail, portInt, protocolStr)
 
-			log.Debug().Msgf("Processing INGRESS: allowing peer %s to reach our pod port %d (%s)", peer, portInt, protocolStr)
+			log.Debug().Msgf("Processing INGRESS: allowing peer %s to reach our pod port %s (%s)", peer, port.String(), protocolStr)
 			ingressRules = g.addOrUpdateRule(ingressRules, peer, port, protocolStr)
 
 		} else if IsEgressTraffic(traffic, podDetail) {
@@ -179,10 +195,11 @@ func (g *StandardPolicyGenerator) processTrafficRules(podTraffic []api.PodTraffi
 				log.Warn().Err(err).Msgf("Skipping egress traffic record due to invalid destination port: %s", traffic.DstPort)
 				continue
 			}
-			port = intstr.FromInt(portInt)
 			protocolStr = string(traffic.Protocol)
+			// Services are resolved before policies are enforced, so match their target port
+			port = egressPort(lookupSelectorService(peer), portInt, protocolStr)
 
-			log.Debug().Msgf("Processing EGRESS: allowing our pod to reach peer %s on port %d (%s)", peer, portInt, protocolStr)
+			log.Debug().Msgf("Processing EGRESS: allowing our pod to reach peer %s on port %s (%s)", peer, port.String(), protocolStr)
 			egressRules = g.addOrUpdateRule(egressRules, peer, port, protocolStr)
 		} else {
 			log.Debug().Msgf("Skipping traffic record with unknown type: %s", traffic.TrafficType)
@@ -252,7 +269,7 @@ func (g *StandardPolicyGenerator) transformToNetworkPolicyIngressRules(rules []N
 		}
 		ingressRules = append(ingressRules, networkingv1.NetworkPolicyIngressRule{
 			From:  []networkingv1.NetworkPolicyPeer{*peerPolicy},
-			Ports: deduplicatePorts(ports),
+			Ports: deduplicatePorts(ports, g.options.PortRanges),
 		})
 	}
 
@@ -271,6 +288,11 @@ func (g *StandardPolicyGenerator) transformToNetworkPolicyEgressRules(rules []Ne
 
 	// Create egress rules
 	for peerIP, ports := range peerRules {
+		if endpointsRule := g.serviceEndpointsEgressRule(peerIP, ports); endpointsRule != nil {
+			egressRules = append(egressRules, *endpointsRule)
+			continue
+		}
+
 		peerPolicy := g.createNetworkPolicyPeer(peerIP)
 		if peerPolicy == nil { // Skip if peer could not be determined
 			continue
@@ -278,7 +300,7 @@ func (g *StandardPolicyGenerator) transformToNetworkPolicyEgressRules(rules []Ne
 
 		egressRules = append(egressRules, networkingv1.NetworkPolicyEgressRule{
 			To:    []networkingv1.NetworkPolicyPeer{*peerPolicy},
-			Ports: deduplicatePorts(ports),
+			Ports: deduplicatePorts(ports, g.options.PortRanges),
 		})
 	}
 
@@ -293,8 +315,12 @@ func (g *StandardPolicyGenerator) createNetworkPolicyPeer(peerIP string) *networ
 	// Try to get Service info first
 	svcSpec, err := api.GetSvcSpec(peerIP)
 	if err == nil && svcSpec != nil {
-		// Validate service has selectors before using it
-		if len(svcSpec.Service.Spec.Selector) > 0 {
+		switch {
+		case isHeadlessService(svcSpec):
+			log.Debug().Msgf("Service %s/%s found for IP %s is headless, resolving the endpoint",
+				svcSpec.SvcNamespace, svcSpec.SvcName, peerIP)
+		case len(svcSpec.Service.Spec.Selector) > 0:
+			// Validate service has selectors before using it
 			log.Debug().Msgf("Found service %s/%s with selector %v for IP %s",
 				svcSpec.SvcNamespace, svcSpec.SvcName, svcSpec.Service.Spec.Selector, peerIP)
 
@@ -308,8 +334,8 @@ func (g *StandardPolicyGenerator) createNetworkPolicyPeer(peerIP string) *networ
 					},
 				},
 			}
-		} else {
-			log.Debug().Msgf("Service %s/%s found for IP %s but has no selector, trying pod lookup",
+		default:
+			log.Debug().Msgf("Service %s/%s found for IP %s but has no selector or known endpoints, trying pod lookup",
 				svcSpec.SvcNamespace, svcSpec.SvcName, peerIP)
 		}
 	} else if err != nil {
@@ -387,8 +413,9 @@ func protocolPtr(protocol string) *corev1.Protocol {
 	return &p
 }
 
-// deduplicatePorts removes duplicate ports from a slice.
-func deduplicatePorts(ports []networkingv1.NetworkPolicyPort) []networkingv1.NetworkPolicyPort {
+// deduplicatePorts removes duplicate ports from a slice and, when enabled, merges adjacent ports
+// into endPort ranges.
+func deduplicatePorts(ports []networkingv1.NetworkPolicyPort, portRanges PortRangeOptions) []networkingv1.NetworkPolicyPort {
 	uniquePorts := make(map[string]networkingv1.NetworkPolicyPort)
 	var result []networkingv1.NetworkPolicyPort
 
@@ -404,5 +431,5 @@ func deduplicatePorts(ports []networkingv1.NetworkPolicyPort) []networkingv1.Net
 		}
 	}
 
-	return result
+	return compactPortRanges(result, portRanges)
 }
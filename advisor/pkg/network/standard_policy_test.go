@@ -368,7 +368,7 @@ func TestDeduplicatePorts(t *testing.T) {
 		{Port: &p80, Protocol: nil}, // Invalid (nil protocol)
 	}
 
-	deduplicated := deduplicatePorts(ports)
+	deduplicated := deduplicatePorts(ports, DefaultPortRangeOptions())
 	assert.Len(t, deduplicated, 3)
 	assert.ElementsMatch(t, []networkingv1.NetworkPolicyPort{
 		{Port: &p80, Protocol: &tcp},
@@ -16,6 +16,18 @@ const (
 	StandardPolicy PolicyType = "standard"
 	// CiliumPolicy is the Cilium NetworkPolicy
 	CiliumPolicy PolicyType = "cilium"
+	// CiliumClusterwidePolicy is the Cilium CiliumClusterwideNetworkPolicy
+	CiliumClusterwidePolicy PolicyType = "cilium-clusterwide"
+	// AdminPolicy is the Kubernetes AdminNetworkPolicy and BaselineAdminNetworkPolicy
+	AdminPolicy PolicyType = "admin"
+	// CalicoPolicy is the Calico NetworkPolicy and GlobalNetworkPolicy
+	CalicoPolicy PolicyType = "calico"
+	// IstioPolicy is the Istio AuthorizationPolicy
+	IstioPolicy PolicyType = "istio"
+	// AntreaPolicy is the Antrea NetworkPolicy
+	AntreaPolicy PolicyType = "antrea"
+	// AntreaClusterPolicy is the Antrea ClusterNetworkPolicy
+	AntreaClusterPolicy PolicyType = "antrea-clusterwide"
 )
 
 // NetworkPolicyRule represents a network policy rule
@@ -39,6 +51,8 @@ type PolicyOutput struct {
 	PodName   string
 	Namespace string
 	Type      PolicyType
+	// DroppedFlows lists the traffic records left out of the policy
+	DroppedFlows []DroppedFlow
 }
 
 // ConfigProvider provides configuration for policy generation
@@ -0,0 +1,5 @@
+-- This file should undo anything in `up.sql`
+DELETE FROM pod_syscalls WHERE container_name <> '';
+ALTER TABLE pod_syscalls DROP CONSTRAINT pod_syscalls_pkey;
+ALTER TABLE pod_syscalls ADD PRIMARY KEY (pod_name);
+ALTER TABLE pod_syscalls DROP COLUMN container_name;
@@ -0,0 +1,5 @@
+-- Record the syscalls of every container of a pod. Rows written before are kept for the whole pod
+-- with an empty container name.
+ALTER TABLE pod_syscalls ADD COLUMN container_name VARCHAR NOT NULL DEFAULT '';
+ALTER TABLE pod_syscalls DROP CONSTRAINT pod_syscalls_pkey;
+ALTER TABLE pod_syscalls ADD PRIMARY KEY (pod_name, container_name);
@@ -159,13 +159,14 @@ impl PodInputSyscalls {
         use schema::pod_syscalls::dsl::*;
 
         debug!(
-            "pod_name: {:?}, pod_namespace: {:?}, syscalls: {:?}, arch: {:?}",
-            &self.pod_name, &self.pod_namespace, &self.syscalls, &self.arch
+            "pod_name: {:?}, pod_namespace: {:?}, container_name: {:?}, syscalls: {:?}, arch: {:?}",
+            &self.pod_name, &self.pod_namespace, &self.container_name, &self.syscalls, &self.arch
         );
 
         let row = pod_syscalls
             .filter(pod_name.eq(&self.pod_name))
             .filter(pod_namespace.eq(&self.pod_namespace))
+            .filter(container_name.eq(&self.container_name))
             .filter(arch.eq(&self.arch))
             .first::<PodSyscalls>(conn)
             .optional()?;
@@ -208,15 +209,20 @@ pub fn create_pod_syscalls(
         if let Some(mut row) = existing_row {
             row.syscalls = new_syscall_number;
 
-            diesel::update(pod_syscalls.filter(pod_name.eq(&row.pod_name)))
-                .set(syscalls.eq(row.syscalls.clone()))
-                .execute(conn)
-                .expect("Error updating pod_syscalls");
+            diesel::update(
+                pod_syscalls
+                    .filter(pod_name.eq(&row.pod_name))
+                    .filter(container_name.eq(&row.container_name)),
+            )
+            .set(syscalls.eq(row.syscalls.clone()))
+            .execute(conn)
+            .expect("Error updating pod_syscalls");
         } else {
             let new_pod_syscall = PodSyscalls {
                 syscalls: new_syscall_number,
                 pod_name: pod_syscall.pod_name.clone(),
                 pod_namespace: pod_syscall.pod_namespace.clone(),
+                container_name: pod_syscall.container_name.clone(),
                 arch: pod_syscall.arch.clone(),
                 time_stamp: pod_syscall.time_stamp,
             };
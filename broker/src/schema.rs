@@ -26,9 +26,10 @@ diesel::table! {
 }
 
 diesel::table! {
-    pod_syscalls (pod_name) {
+    pod_syscalls (pod_name, container_name) {
         pod_name -> Varchar,
         pod_namespace -> Varchar,
+        container_name -> Varchar,
         syscalls -> Varchar,
         arch -> Varchar,
         time_stamp -> Timestamp,
@@ -83,10 +83,11 @@ pub struct SvcDetail {
     Selectable,
 )]
 #[diesel(table_name = pod_syscalls)]
-#[diesel(primary_key(pod_name))]
+#[diesel(primary_key(pod_name, container_name))]
 pub struct PodSyscalls {
     pub pod_name: String,
     pub pod_namespace: String,
+    pub container_name: String,
     pub syscalls: String,
     pub arch: String,
     pub time_stamp: NaiveDateTime,
@@ -97,6 +98,9 @@ pub struct PodSyscalls {
 pub struct PodInputSyscalls {
     pub pod_name: String,
     pub pod_namespace: String,
+    // Empty for controllers that record the syscalls of the whole pod
+    #[serde(default)]
+    pub container_name: String,
     pub syscalls: Vec<String>,
     pub arch: String,
     pub time_stamp: NaiveDateTime,
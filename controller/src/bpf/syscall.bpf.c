@@ -15,6 +15,8 @@ struct data_t
 {
     __u64 inum;
     __u64 sysnbr;
+    // The containers of a pod share its network namespace but each has its own mount namespace
+    __u64 mntns;
 };
 
 struct
@@ -40,6 +42,7 @@ int trace_execve(struct trace_event_raw_sys_enter *ctx)
     {
         data.sysnbr = ctx->id;
         data.inum = net_ns;
+        data.mntns = BPF_CORE_READ(task, nsproxy, mnt_ns, ns.inum);
         // For perf event array:
         bpf_perf_event_output(ctx, &syscall_events, BPF_F_CURRENT_CPU, &data, sizeof(data));
     }
@@ -25,7 +25,7 @@ impl PodInspect {
                 self.set_container_id(container_id)
                     .get_pid(channel)
                     .await
-                    .get_net_namespace_id(),
+                    .get_namespace_ids(),
             )
         } else {
             None
@@ -62,13 +62,16 @@ impl PodInspect {
         self
     }
 
-    fn get_net_namespace_id(mut self) -> Self {
+    fn get_namespace_ids(mut self) -> Self {
         if self.pid.is_some() {
             if let Ok(process) = Process::new(self.pid.unwrap() as i32) {
                 if let Ok(ns) = process.namespaces() {
                     if let Some(netns) = ns.0.get(&OsString::from("net")) {
                         self.inode_num = Some(netns.identifier);
                     }
+                    if let Some(mntns) = ns.0.get(&OsString::from("mnt")) {
+                        self.mnt_inode_num = Some(mntns.identifier);
+                    }
                 }
             }
         }
@@ -1,6 +1,7 @@
 use chrono::NaiveDateTime;
 use serde::Serialize;
 use serde_derive::Deserialize;
+use std::collections::BTreeMap;
 
 #[derive(Debug, Default, Deserialize, Clone)]
 pub struct PodInspect {
@@ -11,6 +12,9 @@ pub struct PodInspect {
     pub namespace_pid: Option<u32>,
     pub pid: Option<u32>,
     pub inode_num: Option<u64>,
+    pub mnt_inode_num: Option<u64>,
+    // Container names of the pod keyed by the inode of their mount namespace
+    pub containers: BTreeMap<u64, String>,
 }
 
 #[derive(Debug, Default, Deserialize, Clone)]
@@ -73,6 +77,7 @@ pub struct PodDetail {
 pub struct SyscallData {
     pub pod_name: String,
     pub pod_namespace: String,
+    pub container_name: String,
     pub syscalls: Vec<String>,
     pub arch: String,
     pub time_stamp: NaiveDateTime,
@@ -62,7 +62,7 @@ async fn process_pod(
     sender_ip: mpsc::Sender<String>,
     ignore_daemonset_traffic: bool,
 ) -> Option<u64> {
-    if let Some(con_ids) = pod_unready(pod) {
+    if let Some(containers) = pod_unready(pod) {
         let pod_ip = update_pods_details(pod).await;
         if let Ok(Some(pod_ip)) = pod_ip {
             if ignore_daemonset_traffic && is_backed_by_daemonset(pod) {
@@ -73,7 +73,7 @@ async fn process_pod(
                 }
             }
             if should_process_pod(&pod.metadata.namespace, excluded_namespaces) {
-                return process_container_ids(&con_ids, pod, &pod_ip, container_map).await;
+                return process_container_ids(&containers, pod, &pod_ip, container_map).await;
             }
         }
     }
@@ -87,7 +87,8 @@ fn should_process_pod(namespace: &Option<String>, excluded_namespaces: &[String]
         .map_or(false, |ns| excluded_namespaces.contains(ns))
 }
 
-fn pod_unready(p: &Pod) -> Option<Vec<String>> {
+// pod_unready returns the names and ids of the pod's containers, including init containers
+fn pod_unready(p: &Pod) -> Option<Vec<(String, String)>> {
     let status = p.status.as_ref().unwrap();
     if let Some(conds) = &status.conditions {
         let failed = conds
@@ -103,13 +104,14 @@ fn pod_unready(p: &Pod) -> Option<Vec<String>> {
     }
 
     if let Some(con_status) = &status.container_statuses {
-        let mut container_ids: Vec<String> = vec![];
-        for container in con_status {
+        let mut containers: Vec<(String, String)> = vec![];
+        let init_statuses = status.init_container_statuses.iter().flatten();
+        for container in con_status.iter().chain(init_statuses) {
             if let Some(container_id) = container.container_id.to_owned() {
-                container_ids.push(container_id)
+                containers.push((container.name.clone(), container_id))
             }
         }
-        return Some(container_ids);
+        return Some(containers);
     }
 
     None
@@ -140,31 +142,45 @@ async fn update_pods_details(pod: &Pod) -> Result<Option<String>, Error> {
 }
 
 async fn process_container_ids(
-    con_ids: &[String],
+    containers: &[(String, String)],
     pod: &Pod,
     pod_ip: &String,
     container_map: Arc<Mutex<BTreeMap<u64, PodInspect>>>,
 ) -> Option<u64> {
-    for con_id in con_ids {
+    // The containers share the network namespace of the pod, their mount namespaces tell the
+    // syscalls of each container apart
+    let mut pod_inspect: Option<PodInspect> = None;
+    for (container_name, con_id) in containers {
         let pod_info = create_pod_info(pod, pod_ip);
-        let pod_inspect = PodInspect {
+        let container_inspect = PodInspect {
             status: pod_info,
             ..Default::default()
         };
-        info!("pod name {}", pod.name_any());
-        if let Some(pod_inspect) = pod_inspect.get_pod_inspect(con_id).await {
-            let mut cm = container_map.lock().await;
-            if let Some(inode_num) = pod_inspect.inode_num {
-                info!(
-                    "inode_num of pod {} is {}",
-                    pod_inspect.status.pod_name, inode_num
-                );
-                cm.insert(inode_num, pod_inspect.clone());
-                return Some(inode_num);
-            }
+        info!("pod name {}, container {}", pod.name_any(), container_name);
+        let Some(container_inspect) = container_inspect.get_pod_inspect(con_id).await else {
+            continue;
+        };
+        if container_inspect.inode_num.is_none() {
+            continue;
+        }
+        let mnt_inode_num = container_inspect.mnt_inode_num;
+        let pod_inspect = pod_inspect.get_or_insert(container_inspect);
+        if let Some(mnt_inode_num) = mnt_inode_num {
+            pod_inspect
+                .containers
+                .insert(mnt_inode_num, container_name.to_string());
         }
     }
-    None
+
+    let pod_inspect = pod_inspect?;
+    let inode_num = pod_inspect.inode_num?;
+    info!(
+        "inode_num of pod {} is {}, containers {:?}",
+        pod_inspect.status.pod_name, inode_num, pod_inspect.containers
+    );
+    let mut cm = container_map.lock().await;
+    cm.insert(inode_num, pod_inspect);
+    Some(inode_num)
 }
 
 fn create_pod_info(pod: &Pod, pod_ip: &str) -> PodInfo {
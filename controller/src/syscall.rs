@@ -16,7 +16,9 @@ pub mod sycallprobe {
     ));
 }
 
-type SyscallCache = Cache<String, Arc<Mutex<HashSet<String>>>>;
+// Syscalls are cached per pod and container name. Processes outside the known containers of a
+// pod, such as the pause container, are recorded with an empty container name.
+type SyscallCache = Cache<(String, String), Arc<Mutex<HashSet<String>>>>;
 
 lazy_static::lazy_static! {
     static ref SYSCALL_CACHE: SyscallCache = Cache::new(10_000);
@@ -28,6 +30,8 @@ lazy_static::lazy_static! {
 pub struct SyscallEventData {
     pub inum: u64,
     pub sysnbr: u32,
+    // Aligned to the same offset as the u64 syscall number of the BPF event
+    pub mntns: u64,
 }
 
 pub async fn handle_syscall_events(
@@ -49,12 +53,17 @@ pub async fn process_syscall_event(
     pod_data: &PodInspect,
 ) -> Result<(), Error> {
     let pod_name = pod_data.status.pod_name.to_string();
+    let container_name = pod_data
+        .containers
+        .get(&data.mntns)
+        .cloned()
+        .unwrap_or_default();
     let syscall_number = data.sysnbr;
     let syscall_name = get_syscall_name(syscall_number.try_into().unwrap())
         .unwrap_or_else(|| format!("{}", syscall_number));
 
     let syscalls = SYSCALL_CACHE
-        .get_with(pod_name.clone(), async {
+        .get_with((pod_name.clone(), container_name.clone()), async {
             Arc::new(Mutex::new(HashSet::new()))
         })
         .await;
@@ -63,8 +72,8 @@ pub async fn process_syscall_event(
 
     if syscalls_lock.contains(&syscall_name) {
         debug!(
-            "Skipping duplicate syscall: {} for pod: {}",
-            syscall_name, pod_name
+            "Skipping duplicate syscall: {} for pod: {}, container: {}",
+            syscall_name, pod_name, container_name
         );
     } else {
         syscalls_lock.insert(syscall_name.clone());
@@ -78,10 +87,11 @@ pub async fn send_syscall_cache_periodically() -> Result<(), Error> {
     for _ in 0.. {
         let mut batch = Vec::new();
 
-        for (pod_name, syscalls) in SYSCALL_CACHE.iter() {
+        for (key, syscalls) in SYSCALL_CACHE.iter() {
+            let (pod_name, container_name) = key.as_ref();
             let syscalls_lock = syscalls.lock().await;
             let last_sent = LAST_SENT_CACHE
-                .get_with(pod_name.to_string(), async {
+                .get_with((pod_name.to_string(), container_name.to_string()), async {
                     Arc::new(Mutex::new(HashSet::new()))
                 })
                 .await;
@@ -92,6 +102,7 @@ pub async fn send_syscall_cache_periodically() -> Result<(), Error> {
                 let z = json!(SyscallData {
                     pod_name: pod_name.to_string(),
                     pod_namespace: "".to_string(), // We will not store the namespace and rather read it from the pod_details table
+                    container_name: container_name.to_string(),
                     syscalls: syscall_names,
                     arch: std::env::consts::ARCH.to_string(),
                     time_stamp: Utc::now().naive_utc()